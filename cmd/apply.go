@@ -0,0 +1,878 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xanzy/go-gitlab"
+	"gopkg.in/yaml.v2"
+)
+
+// manifestFile and prune hold the flags of `golab plan` and `golab apply`
+var manifestFile string
+var prune bool
+
+// planOutput receives the plan printed by `golab plan` and `golab apply`
+var planOutput io.Writer = os.Stdout
+
+// manifest is the desired state of a Gitlab instance, as read by `golab plan` and `golab apply`
+type manifest struct {
+	Groups   []*groupManifest   `yaml:"groups"`
+	Projects []*projectManifest `yaml:"projects"`
+}
+
+// groupManifest is the desired state of a group, Path is the full path, e.g. `parent/child`
+type groupManifest struct {
+	Path     string                 `yaml:"path"`
+	Name     string                 `yaml:"name"`
+	Settings map[string]interface{} `yaml:"settings"`
+	Members  []*memberManifest      `yaml:"members"`
+}
+
+// projectManifest is the desired state of a project, Path is `namespace/project`
+type projectManifest struct {
+	Path              string                     `yaml:"path"`
+	Name              string                     `yaml:"name"`
+	Settings          map[string]interface{}     `yaml:"settings"`
+	Members           []*memberManifest          `yaml:"members"`
+	Hooks             []map[string]interface{}   `yaml:"hooks"`
+	ProtectedBranches []*protectedBranchManifest `yaml:"protected_branches"`
+}
+
+type memberManifest struct {
+	Username    string `yaml:"username"`
+	AccessLevel int    `yaml:"access_level"`
+	ExpiresAt   string `yaml:"expires_at"`
+}
+
+type protectedBranchManifest struct {
+	Name               string `yaml:"name"`
+	DevelopersCanPush  bool   `yaml:"developers_can_push"`
+	DevelopersCanMerge bool   `yaml:"developers_can_merge"`
+}
+
+// a change is a difference between the manifest and the Gitlab instance, apply converges it
+type change struct {
+	action   string
+	resource string
+	details  []string
+	apply    func() error
+}
+
+// isoDate is the format of dates like `expires_at`
+const isoDate = "2006-01-02"
+
+var planSymbols = map[string]string{"create": "+", "update": "~", "delete": "-"}
+
+const manifestHelp = `
+The manifest lists groups and projects together with their settings, members, hooks and protected branches:
+
+    groups:
+      - path: platform
+        name: Platform
+        settings:
+          description: Platform team
+          visibility: internal
+        members:
+          - username: alice
+            access_level: 40
+          - username: bob
+            access_level: 30
+            expires_at: 2030-12-31
+    projects:
+      - path: platform/api
+        settings:
+          description: Public API
+          only_allow_merge_if_pipeline_succeeds: true
+        members:
+          - username: carol
+            access_level: 30
+        hooks:
+          - url: https://ci.example.com/hook
+            push_events: true
+            merge_requests_events: true
+        protected_branches:
+          - name: master
+            developers_can_merge: true
+
+Settings take the parameter names of the Gitlab API for creating groups and projects, hook attributes the ones
+for adding project hooks. Members and hooks are only managed for resources that list them. Settings that
+Gitlab does not return (e.g. hook tokens) are only set when a resource is created. Branches are only protected
+once they exist: Gitlab creates projects with an empty repository, so the branches of a new project are protected
+by the first apply after they were pushed.
+
+With --prune, members and hooks that are not listed in the manifest are removed - including the members
+Gitlab adds when a group or project is created.`
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the changes apply would make",
+	Long: `Compares a manifest with the Gitlab instance and prints the changes that "golab apply" would make.
+Nothing is changed on the Gitlab instance.
+` + manifestHelp,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		changes, err := planManifest()
+		if err != nil {
+			return err
+		}
+		printPlan(planOutput, changes)
+		return nil
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Converge groups and projects to a manifest",
+	Long: `Compares a manifest with the Gitlab instance, prints the plan and makes only the changes that are
+necessary to converge the Gitlab instance to the manifest.
+` + manifestHelp,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		changes, err := planManifest()
+		if err != nil {
+			return err
+		}
+		return applyChanges(changes)
+	},
+}
+
+// applyChanges prints the plan and makes the changes, it stops at the first change that fails
+func applyChanges(changes []*change) error {
+	printPlan(planOutput, changes)
+	for _, c := range changes {
+		if err := c.apply(); err != nil {
+			return fmt.Errorf("could not %s %s: %s", c.action, c.resource, err)
+		}
+	}
+	if len(changes) > 0 {
+		fmt.Fprintf(planOutput, "Applied %d changes.\n", len(changes))
+	}
+	return nil
+}
+
+func planManifest() ([]*change, error) {
+	if manifestFile == "" {
+		return nil, errors.New("required parameter `-f` or `--file` not given - exiting")
+	}
+	m, err := readManifest(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	p := &planner{userIds: map[string]int{}}
+	var changes []*change
+	for _, g := range m.Groups {
+		groupChanges, err := p.planGroup(g)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, groupChanges...)
+	}
+	for _, pm := range m.Projects {
+		projectChanges, err := p.planProject(pm)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, projectChanges...)
+	}
+	return changes, nil
+}
+
+func readManifest(file string) (*manifest, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	m := &manifest{}
+	if err := yaml.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("could not parse manifest %s: %s", file, err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %s", file, err)
+	}
+	// parent groups have to be created before their sub-groups
+	sort.SliceStable(m.Groups, func(i, j int) bool {
+		return strings.Count(m.Groups[i].Path, "/") < strings.Count(m.Groups[j].Path, "/")
+	})
+	return m, nil
+}
+
+func (m *manifest) validate() error {
+	for _, g := range m.Groups {
+		if g.Path == "" {
+			return errors.New("every group needs a path")
+		}
+		if err := checkSettings(g.Settings, gitlab.CreateGroupOptions{}, "name", "path", "parent_id"); err != nil {
+			return fmt.Errorf("group %s: %s", g.Path, err)
+		}
+		if err := validateMembers(g.Members); err != nil {
+			return fmt.Errorf("group %s: %s", g.Path, err)
+		}
+	}
+	for _, p := range m.Projects {
+		if !strings.Contains(p.Path, "/") {
+			return fmt.Errorf("project path '%s' has to be 'namespace/project'", p.Path)
+		}
+		if err := checkSettings(p.Settings, gitlab.CreateProjectOptions{}, "name", "path", "namespace_id"); err != nil {
+			return fmt.Errorf("project %s: %s", p.Path, err)
+		}
+		if err := validateMembers(p.Members); err != nil {
+			return fmt.Errorf("project %s: %s", p.Path, err)
+		}
+		for _, hook := range p.Hooks {
+			if _, ok := hook["url"].(string); !ok {
+				return fmt.Errorf("project %s: every hook needs a url", p.Path)
+			}
+			if err := checkSettings(hook, gitlab.AddProjectHookOptions{}); err != nil {
+				return fmt.Errorf("project %s: hook %s: %s", p.Path, hook["url"], err)
+			}
+		}
+		for _, branch := range p.ProtectedBranches {
+			if branch.Name == "" {
+				return fmt.Errorf("project %s: every protected branch needs a name", p.Path)
+			}
+		}
+	}
+	return nil
+}
+
+func validateMembers(members []*memberManifest) error {
+	for _, member := range members {
+		if member.Username == "" {
+			return errors.New("every member needs a username")
+		}
+		switch gitlab.AccessLevelValue(member.AccessLevel) {
+		case gitlab.GuestPermissions, gitlab.ReporterPermissions, gitlab.DeveloperPermissions, gitlab.MasterPermissions, gitlab.OwnerPermission:
+		default:
+			return fmt.Errorf("member %s: access_level has to be one of 10, 20, 30, 40 or 50", member.Username)
+		}
+		if member.ExpiresAt != "" {
+			if _, err := time.Parse(isoDate, member.ExpiresAt); err != nil {
+				return fmt.Errorf("member %s: expires_at has to be yyyy-mm-dd", member.Username)
+			}
+		}
+	}
+	return nil
+}
+
+// checkSettings returns an error if settings contain a key that is not a JSON parameter of options or is reserved
+func checkSettings(settings map[string]interface{}, options interface{}, reserved ...string) error {
+	allowed := map[string]bool{}
+	t := reflect.TypeOf(options)
+	for i := 0; i < t.NumField(); i++ {
+		allowed[strings.Split(t.Field(i).Tag.Get("json"), ",")[0]] = true
+	}
+	for _, key := range reserved {
+		delete(allowed, key)
+	}
+	for _, key := range sortedKeys(settings) {
+		if !allowed[key] {
+			return fmt.Errorf("unknown setting '%s'", key)
+		}
+	}
+	return nil
+}
+
+// toOptions sets the parameters of a go-gitlab options struct from settings, using their JSON names
+func toOptions(settings map[string]interface{}, options interface{}) error {
+	content, err := json.Marshal(stringKeys(settings))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, options)
+}
+
+// stringKeys converts the maps yaml.v2 decodes nested settings to, which have interface{} keys that JSON cannot
+// encode, to maps with string keys
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range v {
+			result[fmt.Sprint(key)] = stringKeys(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range v {
+			result[key] = stringKeys(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = stringKeys(item)
+		}
+		return result
+	}
+	return value
+}
+
+func sortedKeys(settings map[string]interface{}) []string {
+	var keys []string
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jsonValue(value interface{}) string {
+	content, err := json.Marshal(stringKeys(value))
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(content)
+}
+
+// settingDetails describes the settings of a resource that is created
+func settingDetails(settings map[string]interface{}) []string {
+	var details []string
+	for _, key := range sortedKeys(settings) {
+		details = append(details, fmt.Sprintf("%s: %s", key, jsonValue(settings[key])))
+	}
+	return details
+}
+
+// diffSettings describes the settings that differ from the live resource, settings that Gitlab does not return are skipped
+func diffSettings(settings map[string]interface{}, live interface{}) ([]string, error) {
+	generic, err := toGeneric(live)
+	if err != nil {
+		return nil, err
+	}
+	var details []string
+	for _, key := range sortedKeys(settings) {
+		current, ok := lookup(generic, key)
+		if !ok {
+			continue
+		}
+		if desired := jsonValue(settings[key]); jsonValue(current) != desired {
+			details = append(details, fmt.Sprintf("%s: %s => %s", key, jsonValue(current), desired))
+		}
+	}
+	return details, nil
+}
+
+func isNotFound(resp *gitlab.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusNotFound
+}
+
+func printPlan(w io.Writer, changes []*change) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes, the Gitlab instance matches the manifest.")
+		return
+	}
+	counts := map[string]int{}
+	for _, c := range changes {
+		fmt.Fprintf(w, "%s %s %s\n", planSymbols[c.action], c.action, c.resource)
+		for _, detail := range c.details {
+			fmt.Fprintf(w, "      %s\n", detail)
+		}
+		counts[c.action]++
+	}
+	fmt.Fprintf(w, "\nPlan: %d to create, %d to update, %d to delete.\n", counts["create"], counts["update"], counts["delete"])
+}
+
+// planner compares manifests with the Gitlab instance, it caches the IDs of users and the current user
+type planner struct {
+	userIds     map[string]int
+	currentUser *gitlab.User
+}
+
+// creatorMembers returns the members Gitlab adds to a group or project that the current user creates
+func (p *planner) creatorMembers(accessLevel gitlab.AccessLevelValue) (map[string]*liveMember, error) {
+	if p.currentUser == nil {
+		user, _, err := gitlabClient.Users.CurrentUser()
+		if err != nil {
+			return nil, err
+		}
+		p.currentUser = user
+	}
+	return map[string]*liveMember{p.currentUser.Username: {id: p.currentUser.ID, accessLevel: int(accessLevel)}}, nil
+}
+
+func (p *planner) userId(username string) (int, error) {
+	if id, ok := p.userIds[username]; ok {
+		return id, nil
+	}
+	users, _, err := gitlabClient.Users.ListUsers(&gitlab.ListUsersOptions{Username: &username})
+	if err != nil {
+		return 0, err
+	}
+	if len(users) == 0 {
+		return 0, fmt.Errorf("user %s does not exist", username)
+	}
+	p.userIds[username] = users[0].ID
+	return users[0].ID, nil
+}
+
+func (p *planner) planGroup(g *groupManifest) ([]*change, error) {
+	resource := "group " + g.Path
+	live, resp, err := gitlabClient.Groups.GetGroup(g.Path)
+	if err != nil && !isNotFound(resp) {
+		return nil, err
+	}
+
+	var changes []*change
+	var members map[string]*liveMember
+	if live == nil || isNotFound(resp) {
+		changes = append(changes, &change{action: "create", resource: resource, details: settingDetails(g.Settings), apply: func() error {
+			return createGroup(g)
+		}})
+		if members, err = p.creatorMembers(gitlab.OwnerPermission); err != nil {
+			return nil, err
+		}
+	} else {
+		details, err := diffSettings(g.Settings, live)
+		if err != nil {
+			return nil, err
+		}
+		if g.Name != "" && g.Name != live.Name {
+			details = append([]string{fmt.Sprintf("name: %s => %s", jsonValue(live.Name), jsonValue(g.Name))}, details...)
+		}
+		if len(details) > 0 {
+			changes = append(changes, &change{action: "update", resource: resource, details: details, apply: func() error {
+				opts := &gitlab.UpdateGroupOptions{}
+				if err := toOptions(g.Settings, opts); err != nil {
+					return err
+				}
+				if g.Name != "" {
+					opts.Name = &g.Name
+				}
+				_, _, err := gitlabClient.Groups.UpdateGroup(g.Path, opts)
+				return err
+			}})
+		}
+		if members, err = liveGroupMembers(g.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	if g.Members != nil {
+		memberChanges, err := p.planMembers(groupMemberOps(g.Path), g.Members, members)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, memberChanges...)
+	}
+	return changes, nil
+}
+
+func createGroup(g *groupManifest) error {
+	opts := &gitlab.CreateGroupOptions{}
+	if err := toOptions(g.Settings, opts); err != nil {
+		return err
+	}
+	name := g.Name
+	if name == "" {
+		name = path.Base(g.Path)
+	}
+	opts.Name = &name
+	opts.Path = gitlab.String(path.Base(g.Path))
+	if parentPath := path.Dir(g.Path); parentPath != "." {
+		parent, _, err := gitlabClient.Groups.GetGroup(parentPath)
+		if err != nil {
+			return err
+		}
+		opts.ParentID = &parent.ID
+	}
+	_, _, err := gitlabClient.Groups.CreateGroup(opts)
+	return err
+}
+
+func (p *planner) planProject(pm *projectManifest) ([]*change, error) {
+	resource := "project " + pm.Path
+	live, resp, err := gitlabClient.Projects.GetProject(pm.Path)
+	if err != nil && !isNotFound(resp) {
+		return nil, err
+	}
+	exists := live != nil && !isNotFound(resp)
+
+	var changes []*change
+	var members map[string]*liveMember
+	var hooks []*gitlab.ProjectHook
+	if !exists {
+		changes = append(changes, &change{action: "create", resource: resource, details: settingDetails(pm.Settings), apply: func() error {
+			return createProject(pm)
+		}})
+		if members, err = p.creatorMembers(gitlab.MasterPermissions); err != nil {
+			return nil, err
+		}
+	} else {
+		details, err := diffSettings(pm.Settings, live)
+		if err != nil {
+			return nil, err
+		}
+		if pm.Name != "" && pm.Name != live.Name {
+			details = append([]string{fmt.Sprintf("name: %s => %s", jsonValue(live.Name), jsonValue(pm.Name))}, details...)
+		}
+		if len(details) > 0 {
+			changes = append(changes, &change{action: "update", resource: resource, details: details, apply: func() error {
+				opts := &gitlab.EditProjectOptions{}
+				if err := toOptions(pm.Settings, opts); err != nil {
+					return err
+				}
+				if pm.Name != "" {
+					opts.Name = &pm.Name
+				}
+				_, _, err := gitlabClient.Projects.EditProject(pm.Path, opts)
+				return err
+			}})
+		}
+		if members, err = liveProjectMembers(pm.Path); err != nil {
+			return nil, err
+		}
+		if hooks, err = allProjectHooks(pm.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	if pm.Members != nil {
+		memberChanges, err := p.planMembers(projectMemberOps(pm.Path), pm.Members, members)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, memberChanges...)
+	}
+	if pm.Hooks != nil {
+		hookChanges, err := planHooks(pm.Path, pm.Hooks, hooks)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, hookChanges...)
+	}
+	branchChanges, err := planProtectedBranches(pm.Path, pm.ProtectedBranches, exists)
+	if err != nil {
+		return nil, err
+	}
+	return append(changes, branchChanges...), nil
+}
+
+func createProject(pm *projectManifest) error {
+	opts := &gitlab.CreateProjectOptions{}
+	if err := toOptions(pm.Settings, opts); err != nil {
+		return err
+	}
+	name := pm.Name
+	if name == "" {
+		name = path.Base(pm.Path)
+	}
+	opts.Name = &name
+	opts.Path = gitlab.String(path.Base(pm.Path))
+
+	namespace := path.Dir(pm.Path)
+	user, _, err := gitlabClient.Users.CurrentUser()
+	if err != nil {
+		return err
+	}
+	if namespace != user.Username {
+		group, _, err := gitlabClient.Groups.GetGroup(namespace)
+		if err != nil {
+			return fmt.Errorf("namespace %s: %s", namespace, err)
+		}
+		opts.NamespaceID = &group.ID
+	}
+	_, _, err = gitlabClient.Projects.CreateProject(opts)
+	return err
+}
+
+// liveMember is a member of a group or project as it exists on the Gitlab instance
+type liveMember struct {
+	id          int
+	accessLevel int
+	expiresAt   string
+}
+
+// memberOps change the members of either a group or a project
+type memberOps struct {
+	resource string
+	add      func(userId int, member *memberManifest) error
+	edit     func(userId int, member *memberManifest) error
+	remove   func(userId int) error
+}
+
+func liveGroupMembers(gid string) (map[string]*liveMember, error) {
+	members, err := allGroupMembers(gid)
+	if err != nil {
+		return nil, err
+	}
+	live := map[string]*liveMember{}
+	for _, member := range members {
+		live[member.Username] = &liveMember{id: member.ID, accessLevel: int(member.AccessLevel)}
+		if member.ExpiresAt != nil {
+			live[member.Username].expiresAt = time.Time(*member.ExpiresAt).Format(isoDate)
+		}
+	}
+	return live, nil
+}
+
+func liveProjectMembers(pid string) (map[string]*liveMember, error) {
+	members, err := allProjectMembers(pid)
+	if err != nil {
+		return nil, err
+	}
+	live := map[string]*liveMember{}
+	for _, member := range members {
+		live[member.Username] = &liveMember{id: member.ID, accessLevel: int(member.AccessLevel)}
+		if member.ExpiresAt != nil {
+			live[member.Username].expiresAt = time.Time(*member.ExpiresAt).Format(isoDate)
+		}
+	}
+	return live, nil
+}
+
+// memberExpiresAt returns the expiry date of a member for the API, nil if it does not expire
+func memberExpiresAt(member *memberManifest) *string {
+	if member.ExpiresAt == "" {
+		return nil
+	}
+	return &member.ExpiresAt
+}
+
+func groupMemberOps(gid string) memberOps {
+	return memberOps{
+		resource: "group " + gid,
+		add: func(userId int, member *memberManifest) error {
+			_, _, err := gitlabClient.GroupMembers.AddGroupMember(gid, &gitlab.AddGroupMemberOptions{
+				UserID:      &userId,
+				AccessLevel: gitlab.AccessLevel(gitlab.AccessLevelValue(member.AccessLevel)),
+				ExpiresAt:   memberExpiresAt(member),
+			})
+			return err
+		},
+		edit: func(userId int, member *memberManifest) error {
+			_, _, err := gitlabClient.GroupMembers.EditGroupMember(gid, userId, &gitlab.EditGroupMemberOptions{
+				AccessLevel: gitlab.AccessLevel(gitlab.AccessLevelValue(member.AccessLevel)),
+				ExpiresAt:   memberExpiresAt(member),
+			})
+			return err
+		},
+		remove: func(userId int) error {
+			_, err := gitlabClient.GroupMembers.RemoveGroupMember(gid, userId)
+			return err
+		},
+	}
+}
+
+func projectMemberOps(pid string) memberOps {
+	return memberOps{
+		resource: "project " + pid,
+		add: func(userId int, member *memberManifest) error {
+			_, _, err := projectMemberRequest("POST", pid, "", &projectMemberOptions{
+				UserID:      &userId,
+				AccessLevel: gitlab.AccessLevel(gitlab.AccessLevelValue(member.AccessLevel)),
+				ExpiresAt:   memberExpiresAt(member),
+			})
+			return err
+		},
+		edit: func(userId int, member *memberManifest) error {
+			_, _, err := projectMemberRequest("PUT", pid, "/"+strconv.Itoa(userId), &projectMemberOptions{
+				AccessLevel: gitlab.AccessLevel(gitlab.AccessLevelValue(member.AccessLevel)),
+				ExpiresAt:   memberExpiresAt(member),
+			})
+			return err
+		},
+		remove: func(userId int) error {
+			_, err := gitlabClient.ProjectMembers.DeleteProjectMember(pid, userId)
+			return err
+		},
+	}
+}
+
+func (p *planner) planMembers(ops memberOps, desired []*memberManifest, live map[string]*liveMember) ([]*change, error) {
+	var changes []*change
+	managed := map[string]bool{}
+	for _, member := range desired {
+		member := member
+		managed[member.Username] = true
+		resource := fmt.Sprintf("member %s of %s", member.Username, ops.resource)
+		current, exists := live[member.Username]
+		if !exists {
+			userId, err := p.userId(member.Username)
+			if err != nil {
+				return nil, err
+			}
+			details := []string{fmt.Sprintf("access_level: %d", member.AccessLevel)}
+			if member.ExpiresAt != "" {
+				details = append(details, "expires_at: "+member.ExpiresAt)
+			}
+			changes = append(changes, &change{action: "create", resource: resource, details: details, apply: func() error {
+				return ops.add(userId, member)
+			}})
+			continue
+		}
+		var details []string
+		if current.accessLevel != member.AccessLevel {
+			details = append(details, fmt.Sprintf("access_level: %d => %d", current.accessLevel, member.AccessLevel))
+		}
+		if current.expiresAt != member.ExpiresAt {
+			details = append(details, fmt.Sprintf("expires_at: %s => %s", jsonValue(current.expiresAt), jsonValue(member.ExpiresAt)))
+		}
+		if len(details) > 0 {
+			changes = append(changes, &change{action: "update", resource: resource, details: details, apply: func() error {
+				return ops.edit(current.id, member)
+			}})
+		}
+	}
+	if prune {
+		for _, username := range sortedMemberNames(live) {
+			if managed[username] {
+				continue
+			}
+			userId := live[username].id
+			changes = append(changes, &change{action: "delete", resource: fmt.Sprintf("member %s of %s", username, ops.resource), apply: func() error {
+				return ops.remove(userId)
+			}})
+		}
+	}
+	return changes, nil
+}
+
+func planHooks(pid string, desired []map[string]interface{}, live []*gitlab.ProjectHook) ([]*change, error) {
+	var changes []*change
+	managed := map[string]bool{}
+	for _, hook := range desired {
+		hook := hook
+		url := hook["url"].(string)
+		managed[url] = true
+		resource := fmt.Sprintf("hook %s of project %s", url, pid)
+		var current *gitlab.ProjectHook
+		for _, liveHook := range live {
+			if liveHook.URL == url {
+				current = liveHook
+			}
+		}
+		if current == nil {
+			changes = append(changes, &change{action: "create", resource: resource, details: settingDetails(withoutKeys(hook, "url", "token")), apply: func() error {
+				opts := &gitlab.AddProjectHookOptions{}
+				if err := toOptions(hook, opts); err != nil {
+					return err
+				}
+				_, _, err := gitlabClient.Projects.AddProjectHook(pid, opts)
+				return err
+			}})
+			continue
+		}
+		details, err := diffSettings(withoutKeys(hook, "url", "token"), current)
+		if err != nil {
+			return nil, err
+		}
+		if len(details) > 0 {
+			changes = append(changes, &change{action: "update", resource: resource, details: details, apply: func() error {
+				opts := &gitlab.EditProjectHookOptions{}
+				if err := toOptions(hook, opts); err != nil {
+					return err
+				}
+				_, _, err := gitlabClient.Projects.EditProjectHook(pid, current.ID, opts)
+				return err
+			}})
+		}
+	}
+	if prune {
+		for _, hook := range live {
+			if managed[hook.URL] {
+				continue
+			}
+			hookId := hook.ID
+			changes = append(changes, &change{action: "delete", resource: fmt.Sprintf("hook %s of project %s", hook.URL, pid), apply: func() error {
+				_, err := gitlabClient.Projects.DeleteProjectHook(pid, hookId)
+				return err
+			}})
+		}
+	}
+	return changes, nil
+}
+
+func withoutKeys(settings map[string]interface{}, keys ...string) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, value := range settings {
+		result[key] = value
+	}
+	for _, key := range keys {
+		delete(result, key)
+	}
+	return result
+}
+
+// planProtectedBranches protects branches or updates their settings. Branches that do not exist yet are skipped with
+// a note, this includes all branches of projects that are created by the same run, as they have an empty repository.
+func planProtectedBranches(pid string, desired []*protectedBranchManifest, projectExists bool) ([]*change, error) {
+	var changes []*change
+	for _, branch := range desired {
+		branch := branch
+		resource := fmt.Sprintf("protected branch %s of project %s", branch.Name, pid)
+		if !projectExists {
+			fmt.Fprintf(planOutput, "! skip %s, the branch does not exist yet\n", resource)
+			continue
+		}
+		live, resp, err := gitlabClient.Branches.GetBranch(pid, branch.Name)
+		if isNotFound(resp) {
+			fmt.Fprintf(planOutput, "! skip %s, the branch does not exist yet\n", resource)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		action := "create"
+		details := []string{
+			fmt.Sprintf("developers_can_push: %t", branch.DevelopersCanPush),
+			fmt.Sprintf("developers_can_merge: %t", branch.DevelopersCanMerge),
+		}
+		if live.Protected {
+			if live.DevelopersCanPush == branch.DevelopersCanPush && live.DevelopersCanMerge == branch.DevelopersCanMerge {
+				continue
+			}
+			action = "update"
+			details = []string{
+				fmt.Sprintf("developers_can_push: %t => %t", live.DevelopersCanPush, branch.DevelopersCanPush),
+				fmt.Sprintf("developers_can_merge: %t => %t", live.DevelopersCanMerge, branch.DevelopersCanMerge),
+			}
+		}
+		changes = append(changes, &change{action: action, resource: resource, details: details, apply: func() error {
+			_, _, err := gitlabClient.Branches.ProtectBranch(pid, branch.Name, &gitlab.ProtectBranchOptions{
+				DevelopersCanPush:  &branch.DevelopersCanPush,
+				DevelopersCanMerge: &branch.DevelopersCanMerge,
+			})
+			return err
+		}})
+	}
+	return changes, nil
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{planCmd, applyCmd} {
+		cmd.PersistentFlags().StringVarP(&manifestFile, "file", "f", "", "(required) YAML manifest with the desired groups and projects")
+		cmd.PersistentFlags().BoolVar(&prune, "prune", false, "(optional) remove members and hooks that are not listed in the manifest")
+		RootCmd.AddCommand(cmd)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
+	"github.com/michaellihs/golab/fake"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("plan and apply commands", func() {
+
+	var (
+		server *fake.Server
+		out    *bytes.Buffer
+		file   string
+	)
+
+	writeManifest := func(content string) {
+		Expect(ioutil.WriteFile(file, []byte(content), 0600)).To(Succeed())
+	}
+
+	const platformManifest = `
+groups:
+  - path: platform
+    name: Platform
+    settings:
+      description: Platform team
+    members:
+      - username: alice
+        access_level: 40
+projects:
+  - path: platform/api
+    settings:
+      description: Public API
+    hooks:
+      - url: https://ci.example.com/hook
+        merge_requests_events: true
+    protected_branches:
+      - name: master
+        developers_can_merge: true
+`
+
+	BeforeEach(func() {
+		server = fake.NewServer()
+		server.AddUser("alice", "alice@example.com", "Alice", "")
+		gitlabClient = gitlab.NewClient(nil, fake.Token)
+		gitlabClient.SetBaseURL(server.URL + "/api/v4")
+
+		out = &bytes.Buffer{}
+		planOutput = out
+		prune = false
+
+		tmp, err := ioutil.TempFile("", "golab-manifest")
+		Expect(err).To(BeNil())
+		tmp.Close()
+		file = tmp.Name()
+	})
+
+	AfterEach(func() {
+		server.Close()
+		planOutput = os.Stdout
+		os.Remove(file)
+	})
+
+	It("prints the plan without changing anything", func() {
+		writeManifest(platformManifest)
+
+		_, _, err := executeCommand(RootCmd, "plan", "-f", file)
+
+		Expect(err).To(BeNil())
+		Expect(out.String()).To(ContainSubstring("+ create group platform\n      description: \"Platform team\"\n"))
+		Expect(out.String()).To(ContainSubstring("+ create member alice of group platform\n      access_level: 40\n"))
+		Expect(out.String()).To(ContainSubstring("+ create project platform/api\n"))
+		Expect(out.String()).To(ContainSubstring("+ create hook https://ci.example.com/hook of project platform/api\n"))
+		Expect(out.String()).To(ContainSubstring("! skip protected branch master of project platform/api, the branch does not exist yet\n"))
+		Expect(out.String()).To(ContainSubstring("Plan: 4 to create, 0 to update, 0 to delete."))
+
+		_, resp, _ := gitlabClient.Groups.GetGroup("platform")
+		Expect(resp.StatusCode).To(Equal(404))
+	})
+
+	It("converges the Gitlab instance to the manifest", func() {
+		writeManifest(platformManifest)
+
+		_, _, err := executeCommand(RootCmd, "apply", "-f", file)
+		Expect(err).To(BeNil())
+		Expect(out.String()).To(ContainSubstring("Applied 4 changes."))
+
+		member, _, err := gitlabClient.GroupMembers.GetGroupMember("platform", 2)
+		Expect(err).To(BeNil())
+		Expect(member.AccessLevel).To(Equal(gitlab.MasterPermissions))
+		project, _, err := gitlabClient.Projects.GetProject("platform/api")
+		Expect(err).To(BeNil())
+		Expect(project.Description).To(Equal("Public API"))
+		hooks, _, err := gitlabClient.Projects.ListProjectHooks("platform/api", nil)
+		Expect(err).To(BeNil())
+		Expect(hooks).To(HaveLen(1))
+		Expect(hooks[0].MergeRequestsEvents).To(BeTrue())
+
+		// the project is created with an empty repository, its branch is protected once it was pushed
+		_, _, err = gitlabClient.RepositoryFiles.CreateFile("platform/api", "README.md", &gitlab.CreateFileOptions{
+			Branch:        gitlab.String("master"),
+			Content:       gitlab.String("# API"),
+			CommitMessage: gitlab.String("Initial commit"),
+		})
+		Expect(err).To(BeNil())
+		out.Reset()
+		_, _, err = executeCommand(RootCmd, "apply", "-f", file)
+		Expect(err).To(BeNil())
+		Expect(out.String()).To(ContainSubstring("+ create protected branch master of project platform/api\n"))
+		branch, _, err := gitlabClient.Branches.GetBranch("platform/api", "master")
+		Expect(err).To(BeNil())
+		Expect(branch.Protected).To(BeTrue())
+		Expect(branch.DevelopersCanMerge).To(BeTrue())
+
+		out.Reset()
+		_, _, err = executeCommand(RootCmd, "plan", "-f", file)
+		Expect(err).To(BeNil())
+		Expect(out.String()).To(Equal("No changes, the Gitlab instance matches the manifest.\n"))
+	})
+
+	It("changes only the differences and prunes unmanaged members and hooks", func() {
+		writeManifest(platformManifest)
+		_, _, err := executeCommand(RootCmd, "apply", "-f", file)
+		Expect(err).To(BeNil())
+		_, _, err = gitlabClient.Projects.AddProjectHook("platform/api", &gitlab.AddProjectHookOptions{URL: gitlab.String("http://unmanaged.example.com")})
+		Expect(err).To(BeNil())
+
+		writeManifest(`
+groups:
+  - path: platform
+    settings:
+      description: Platform team
+    members:
+      - username: alice
+        access_level: 30
+projects:
+  - path: platform/api
+    hooks:
+      - url: https://ci.example.com/hook
+        merge_requests_events: true
+`)
+		out.Reset()
+		_, _, err = executeCommand(RootCmd, "apply", "-f", file, "--prune")
+
+		Expect(err).To(BeNil())
+		Expect(out.String()).To(Equal(`~ update member alice of group platform
+      access_level: 40 => 30
+- delete member root of group platform
+- delete hook http://unmanaged.example.com of project platform/api
+
+Plan: 0 to create, 1 to update, 2 to delete.
+Applied 3 changes.
+`))
+		members, err := allGroupMembers("platform")
+		Expect(err).To(BeNil())
+		Expect(members).To(HaveLen(1))
+		Expect(members[0].AccessLevel).To(Equal(gitlab.DeveloperPermissions))
+		hooks, err := allProjectHooks("platform/api")
+		Expect(err).To(BeNil())
+		Expect(hooks).To(HaveLen(1))
+	})
+
+	It("plans the members Gitlab adds to groups and projects it creates as existing", func() {
+		writeManifest(`
+groups:
+  - path: platform
+    members:
+      - username: root
+        access_level: 50
+projects:
+  - path: platform/api
+    members:
+      - username: root
+        access_level: 30
+`)
+
+		_, _, err := executeCommand(RootCmd, "apply", "-f", file)
+
+		Expect(err).To(BeNil())
+		Expect(out.String()).NotTo(ContainSubstring("create member root"))
+		Expect(out.String()).To(ContainSubstring("~ update member root of project platform/api\n      access_level: 40 => 30\n"))
+		members, err := allProjectMembers("platform/api")
+		Expect(err).To(BeNil())
+		Expect(members).To(HaveLen(1))
+		Expect(members[0].AccessLevel).To(Equal(gitlab.DeveloperPermissions))
+	})
+
+	It("rejects invalid manifests", func() {
+		writeManifest("projects:\n  - path: platform/api\n    settings:\n      unknown: true\n")
+
+		_, _, err := executeCommand(RootCmd, "plan", "-f", file)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("invalid manifest " + file + ": project platform/api: unknown setting 'unknown'"))
+	})
+
+	It("converts and compares nested settings", func() {
+		settings := map[string]interface{}{}
+		Expect(yaml.Unmarshal([]byte("approvals:\n  required: 2\n  rules:\n    - name: security\n      users: [alice]\n"), &settings)).To(Succeed())
+
+		options := &struct {
+			Approvals struct {
+				Required int `json:"required"`
+				Rules    []struct {
+					Name  string   `json:"name"`
+					Users []string `json:"users"`
+				} `json:"rules"`
+			} `json:"approvals"`
+		}{}
+		Expect(toOptions(settings, options)).To(Succeed())
+		Expect(options.Approvals.Required).To(Equal(2))
+		Expect(options.Approvals.Rules[0].Users).To(Equal([]string{"alice"}))
+
+		Expect(settingDetails(settings)).To(Equal([]string{`approvals: {"required":2,"rules":[{"name":"security","users":["alice"]}]}`}))
+		details, err := diffSettings(settings, map[string]interface{}{"approvals": map[string]interface{}{"required": 1}})
+		Expect(err).To(BeNil())
+		Expect(details).To(Equal([]string{`approvals: {"required":1} => {"required":2,"rules":[{"name":"security","users":["alice"]}]}`}))
+	})
+
+})
@@ -30,10 +30,11 @@ import (
 var branchesCmd = &golabCommand{
 	Parent: RootCmd,
 	Cmd: &cobra.Command{
-		Use:     "branches",
-		Aliases: []string{"branch"},
-		Short:   "Branches",
-		Long:    `Manage repository branches`,
+		Use:         "branches",
+		Aliases:     []string{"branch"},
+		Short:       "Branches",
+		Long:        `Manage repository branches`,
+		Annotations: map[string]string{idResourceAnnotation: "project"},
 	},
 	Run: func(cmd golabCommand) error {
 		return errors.New("cannot use this command without further sub-commands")
@@ -42,7 +43,7 @@ var branchesCmd = &golabCommand{
 
 // see https://docs.gitlab.com/ce/api/branches.html#list-repository-branches
 type branchesListFlags struct {
-	Id *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Id *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
 }
 
 var branchesListCmd = &golabCommand{
@@ -57,17 +58,15 @@ var branchesListCmd = &golabCommand{
 	Run: func(cmd golabCommand) error {
 		flags := cmd.Flags.(*branchesListFlags)
 		opts := cmd.Opts.(*gitlab.ListBranchesOptions)
-		branches, _, err := gitlabClient.Branches.ListBranches(*flags.Id, opts)
-		if err != nil {
-			return err
-		}
-		return OutputJson(branches)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Branches.ListBranches(*flags.Id, opts, page)
+		})
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/branches.html#get-single-repository-branch
 type branchesGetSingleFlags struct {
-	Id     *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Id     *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
 	Branch *string `flag_name:"branch" short:"b" type:"string" required:"yes" description:"The name of the branch"`
 }
 
@@ -85,13 +84,13 @@ var branchesGetSingleCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(branch)
+		return Output(branch)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/branches.html#protect-repository-branch
 type branchesProtectFlags struct {
-	Id                 *string `flag_name:"id" short:"i" type:"integer/string" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Id                 *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
 	Branch             *string `flag_name:"branch" short:"b" type:"string" required:"yes" description:"The name of the branch"`
 	DevelopersCanPush  *bool   `flag_name:"developers_can_push" short:"p" type:"boolean" required:"no" description:"Flag if developers can push to the branch"`
 	DevelopersCanMerge *bool   `flag_name:"developers_can_merge" short:"m" type:"boolean" required:"no" description:"Flag if developers can merge to the branch"`
@@ -113,13 +112,13 @@ var branchesProtectCmd = &golabCommand{
 		if err != nil {
 		    return err
 		}
-		return OutputJson(branch)
+		return Output(branch)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/branches.html#unprotect-repository-branch
 type branchesUnprotectFlags struct {
-	Id     *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL-encoded path of the project"`
+	Id     *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
 	Branch *string `flag_name:"branch" short:"b" type:"string" required:"yes" description:"The name of the branch"`
 }
 
@@ -137,13 +136,13 @@ var branchesUnprotectCmd = &golabCommand{
 		if err != nil {
 		    return err
 		}
-		return OutputJson(branch)
+		return Output(branch)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/branches.html#create-repository-branch
 type branchesCreateFlags struct {
-	Id     *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL-encoded path of the project"`
+	Id     *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
 	Branch *string `flag_name:"branch" short:"b" type:"string" required:"yes" description:"The name of the branch"`
 	Ref    *string `flag_name:"ref" short:"r" type:"string" required:"yes" description:"The branch name or commit SHA to create branch from"`
 }
@@ -164,13 +163,13 @@ var branchesCreateCmd = &golabCommand{
 		if err != nil {
 		    return err
 		}
-		return OutputJson(branch)
+		return Output(branch)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/branches.html#delete-repository-branch
 type branchesDeleteFlags struct {
-	Id     *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL-encoded path of the project"`
+	Id     *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
 	Branch *string `flag_name:"branch" short:"b" type:"string" required:"yes" description:"The name of the branch"`
 }
 
@@ -191,7 +190,7 @@ var branchesDeleteCmd = &golabCommand{
 
 // see https://docs.gitlab.com/ce/api/branches.html#delete-merged-branches
 type branchesDeleteMergedFlags struct {
-	Id     *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL-encoded path of the project"`
+	Id     *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
 }
 
 var branchesDeleteMergedCmd = &golabCommand{
@@ -214,6 +213,7 @@ Protected branches will not be deleted as part of this operation.`,
 func init() {
 	branchesCmd.Init()
 	branchesListCmd.Init()
+	initPaginationFlags(branchesListCmd.Cmd)
 	branchesGetSingleCmd.Init()
 	branchesProtectCmd.Init()
 	branchesUnprotectCmd.Init()
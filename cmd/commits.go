@@ -0,0 +1,314 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xanzy/go-gitlab"
+)
+
+// see https://docs.gitlab.com/ce/api/commits.html
+var commitsCmd = &golabCommand{
+	Parent: RootCmd,
+	Cmd: &cobra.Command{
+		Use:         "commits",
+		Aliases:     []string{"commit"},
+		Short:       "Commits",
+		Long:        `List and show repository commits, their diffs, comments and statuses and cherry-pick commits`,
+		Annotations: map[string]string{idResourceAnnotation: "project"},
+	},
+	Run: func(cmd golabCommand) error {
+		return errors.New("cannot use this command without further sub-commands")
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/commits.html#list-repository-commits
+type commitsListFlags struct {
+	Id      *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	RefName *string `flag_name:"ref_name" short:"r" type:"string" required:"no" description:"The name of a repository branch or tag (default: the default branch)"`
+	Path    *string `flag_name:"path" short:"p" type:"string" required:"no" description:"Only commits that changed this file or directory"`
+	Since   *string `flag_name:"since" type:"string" required:"no" description:"Only commits after or on this date, in ISO 8601 format YYYY-MM-DD or YYYY-MM-DDTHH:MM:SSZ"`
+	Until   *string `flag_name:"until" type:"string" required:"no" description:"Only commits before or on this date, in ISO 8601 format YYYY-MM-DD or YYYY-MM-DDTHH:MM:SSZ"`
+}
+
+var commitsListCmd = &golabCommand{
+	Parent: commitsCmd.Cmd,
+	Flags:  &commitsListFlags{},
+	Cmd: &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List repository commits",
+		Long:    `Get a list of repository commits in a project, latest first.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*commitsListFlags)
+		// go-gitlab neither supports the path nor dates given as string
+		query := map[string]string{}
+		setQuery(query, "ref_name", flags.RefName)
+		setQuery(query, "path", flags.Path)
+		setQuery(query, "since", flags.Since)
+		setQuery(query, "until", flags.Until)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Commits.ListCommits(parsePid(*flags.Id), &gitlab.ListCommitsOptions{}, withQuery(query), page)
+		})
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/commits.html#get-a-single-commit
+type commitsGetFlags struct {
+	Id  *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Sha *string `flag_name:"sha" short:"s" type:"string" required:"yes" description:"The commit hash or name of a repository branch or tag"`
+}
+
+var commitsGetCmd = &golabCommand{
+	Parent: commitsCmd.Cmd,
+	Flags:  &commitsGetFlags{},
+	Cmd: &cobra.Command{
+		Use:   "get",
+		Short: "Get a single commit",
+		Long:  `Get a specific commit identified by the commit hash or name of a branch or tag.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*commitsGetFlags)
+		commit, _, err := gitlabClient.Commits.GetCommit(parsePid(*flags.Id), *flags.Sha)
+		if err != nil {
+			return err
+		}
+		return Output(commit)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/commits.html#get-the-diff-of-a-commit
+type commitsDiffFlags struct {
+	Id  *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Sha *string `flag_name:"sha" short:"s" type:"string" required:"yes" description:"The commit hash or name of a repository branch or tag"`
+}
+
+var commitsDiffCmd = &golabCommand{
+	Parent: commitsCmd.Cmd,
+	Flags:  &commitsDiffFlags{},
+	Cmd: &cobra.Command{
+		Use:   "diff",
+		Short: "Get the diff of a commit",
+		Long: `Prints the diff of a commit as unified patch in the format of 'git diff', which can be applied with 'git apply':
+
+    golab commits diff --sha 1a2b3c4d | git apply
+
+With an explicit --output, the diffs are rendered as returned by the API.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*commitsDiffFlags)
+		diffs, err := allCommitDiffs(parsePid(*flags.Id), *flags.Sha)
+		if err != nil {
+			return err
+		}
+		if cmd.Cmd.Flags().Changed("output") {
+			return Output(diffs)
+		}
+		return writePatch(os.Stdout, diffs)
+	},
+}
+
+// writePatch renders the diffs returned by Gitlab, which only contain the hunks, with the headers of 'git diff'
+func writePatch(w io.Writer, diffs []*gitlab.Diff) error {
+	for _, diff := range diffs {
+		header := fmt.Sprintf("diff --git a/%s b/%s\n", diff.OldPath, diff.NewPath)
+		switch {
+		case diff.NewFile:
+			header += fmt.Sprintf("new file mode %s\n", diff.BMode)
+		case diff.DeletedFile:
+			header += fmt.Sprintf("deleted file mode %s\n", diff.AMode)
+		default:
+			if diff.AMode != diff.BMode {
+				header += fmt.Sprintf("old mode %s\nnew mode %s\n", diff.AMode, diff.BMode)
+			}
+			if diff.RenamedFile {
+				header += fmt.Sprintf("rename from %s\nrename to %s\n", diff.OldPath, diff.NewPath)
+			}
+		}
+		// renames and mode changes have no hunks, binary files only a note
+		if strings.HasPrefix(diff.Diff, "@@") {
+			oldPath, newPath := "a/"+diff.OldPath, "b/"+diff.NewPath
+			if diff.NewFile {
+				oldPath = "/dev/null"
+			}
+			if diff.DeletedFile {
+				newPath = "/dev/null"
+			}
+			header += fmt.Sprintf("--- %s\n+++ %s\n", oldPath, newPath)
+		}
+		body := diff.Diff
+		if body != "" && !strings.HasSuffix(body, "\n") {
+			body += "\n"
+		}
+		if _, err := io.WriteString(w, header+body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// see https://docs.gitlab.com/ce/api/commits.html#get-the-comments-of-a-commit
+var commitsCommentsCmd = &golabCommand{
+	Parent: commitsCmd.Cmd,
+	Cmd: &cobra.Command{
+		Use:   "comments",
+		Short: "Commit comments",
+		Long:  `List and add comments of a commit`,
+	},
+	Run: func(cmd golabCommand) error {
+		return errors.New("cannot use this command without further sub-commands")
+	},
+}
+
+type commitsCommentsListFlags struct {
+	Id  *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Sha *string `flag_name:"sha" short:"s" type:"string" required:"yes" description:"The commit hash or name of a repository branch or tag"`
+}
+
+var commitsCommentsListCmd = &golabCommand{
+	Parent: commitsCommentsCmd.Cmd,
+	Flags:  &commitsCommentsListFlags{},
+	Cmd: &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "Get the comments of a commit",
+		Long:    `Get the comments of a commit in a project.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*commitsCommentsListFlags)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Commits.GetCommitComments(parsePid(*flags.Id), *flags.Sha, page)
+		})
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/commits.html#post-comment-to-commit
+type commitsCommentsAddFlags struct {
+	Id       *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Sha      *string `flag_name:"sha" short:"s" type:"string" required:"yes" description:"The commit hash or name of a repository branch or tag"`
+	Note     *string `flag_name:"note" short:"n" type:"string" file:"raw" required:"yes" description:"The text of the comment"`
+	Path     *string `flag_name:"path" short:"p" type:"string" required:"no" description:"The file path relative to the repository, to comment on a line of this file"`
+	Line     *int    `flag_name:"line" short:"l" type:"integer" required:"no" description:"The line number where the comment should be placed, requires --path"`
+	LineType *string `flag_name:"line_type" type:"string" choices:"new,old" required:"no" description:"Whether --line is a line of the new or the old version of the file (default: new)"`
+}
+
+var commitsCommentsAddCmd = &golabCommand{
+	Parent: commitsCommentsCmd.Cmd,
+	Flags:  &commitsCommentsAddFlags{},
+	Opts:   &gitlab.PostCommitCommentOptions{},
+	Cmd: &cobra.Command{
+		Use:   "add",
+		Short: "Post comment to commit",
+		Long:  `Adds a comment to a commit, to comment on a line of the diff give --path and --line.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*commitsCommentsAddFlags)
+		opts := cmd.Opts.(*gitlab.PostCommitCommentOptions)
+		if (flags.Path == nil) != (flags.Line == nil) || (flags.LineType != nil && flags.Line == nil) {
+			return errors.New("`--path` and `--line` are required to comment on a line - exiting")
+		}
+		if flags.Line != nil && opts.LineType == nil {
+			opts.LineType = gitlab.String("new")
+		}
+		comment, _, err := gitlabClient.Commits.PostCommitComment(parsePid(*flags.Id), *flags.Sha, opts)
+		if err != nil {
+			return err
+		}
+		return Output(comment)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/commits.html#get-the-status-of-a-commit
+type commitsStatusesFlags struct {
+	Id    *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Sha   *string `flag_name:"sha" short:"s" type:"string" required:"yes" description:"The commit hash"`
+	Ref   *string `flag_name:"ref" short:"r" type:"string" required:"no" description:"The name of a repository branch or tag"`
+	Stage *string `flag_name:"stage" type:"string" required:"no" description:"Filter by build stage, e.g. test"`
+	Name  *string `flag_name:"name" type:"string" required:"no" description:"Filter by job name, e.g. bundler:audit"`
+	All   *bool   `flag_name:"all_statuses" type:"boolean" required:"no" description:"Return all statuses, not only the latest ones"`
+}
+
+var commitsStatusesCmd = &golabCommand{
+	Parent: commitsCmd.Cmd,
+	Flags:  &commitsStatusesFlags{},
+	Opts:   &gitlab.GetCommitStatusesOptions{},
+	Cmd: &cobra.Command{
+		Use:   "statuses",
+		Short: "Get the statuses of a commit",
+		Long:  `Get the statuses of a commit in a project, e.g. of its CI jobs.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*commitsStatusesFlags)
+		opts := cmd.Opts.(*gitlab.GetCommitStatusesOptions)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Commits.GetCommitStatuses(parsePid(*flags.Id), *flags.Sha, opts, page)
+		})
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/commits.html#cherry-pick-a-commit
+type commitsCherryPickFlags struct {
+	Id           *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Sha          *string `flag_name:"sha" short:"s" type:"string" required:"yes" description:"The commit hash"`
+	TargetBranch *string `flag_name:"branch" short:"b" type:"string" required:"yes" description:"The name of the branch to cherry-pick the commit to"`
+}
+
+var commitsCherryPickCmd = &golabCommand{
+	Parent: commitsCmd.Cmd,
+	Flags:  &commitsCherryPickFlags{},
+	Opts:   &gitlab.CherryPickCommitOptions{},
+	Cmd: &cobra.Command{
+		Use:   "cherry-pick",
+		Short: "Cherry-pick a commit",
+		Long:  `Cherry-picks a commit to the given branch, the new commit is printed.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*commitsCherryPickFlags)
+		opts := cmd.Opts.(*gitlab.CherryPickCommitOptions)
+		commit, _, err := gitlabClient.Commits.CherryPickCommit(parsePid(*flags.Id), *flags.Sha, opts)
+		if err != nil {
+			return err
+		}
+		return Output(commit)
+	},
+}
+
+func init() {
+	commitsCmd.Init()
+	commitsListCmd.Init()
+	initPaginationFlags(commitsListCmd.Cmd)
+	commitsGetCmd.Init()
+	commitsDiffCmd.Init()
+	commitsCommentsCmd.Init()
+	commitsCommentsListCmd.Init()
+	initPaginationFlags(commitsCommentsListCmd.Cmd)
+	commitsCommentsAddCmd.Init()
+	commitsStatusesCmd.Init()
+	initPaginationFlags(commitsStatusesCmd.Cmd)
+	commitsCherryPickCmd.Init()
+}
@@ -0,0 +1,69 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("commits diff", func() {
+
+	It("adds the headers of git diff to the hunks returned by Gitlab", func() {
+		diffs := []*gitlab.Diff{
+			{OldPath: "new.txt", NewPath: "new.txt", AMode: "0", BMode: "100644", NewFile: true, Diff: "@@ -0,0 +1 @@\n+new\n"},
+			{OldPath: "old.txt", NewPath: "old.txt", AMode: "100644", BMode: "0", DeletedFile: true, Diff: "@@ -1 +0,0 @@\n-old"},
+			{OldPath: "run.sh", NewPath: "run.sh", AMode: "100644", BMode: "100755", Diff: "@@ -1 +1 @@\n-a\n+b\n"},
+			{OldPath: "a.md", NewPath: "b.md", AMode: "100644", BMode: "100644", RenamedFile: true},
+		}
+		out := &bytes.Buffer{}
+
+		Expect(writePatch(out, diffs)).To(Succeed())
+
+		Expect(out.String()).To(Equal(`diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++new
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-old
+diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+--- a/run.sh
++++ b/run.sh
+@@ -1 +1 @@
+-a
++b
+diff --git a/a.md b/b.md
+rename from a.md
+rename to b.md
+`))
+	})
+})
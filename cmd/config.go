@@ -0,0 +1,497 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/michaellihs/golab/cmd/mapper"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// contextName holds the value of the global `--context` flag
+var contextName string
+
+var contextUrl, contextToken, contextTokenCommand, contextTokenFile, contextDefaultGroup, contextDefaultProject, contextMaxRetryWait string
+var contextMaxRetries int
+
+// golabConfig is the content of a golab config file. The top-level url and token settings are still supported for
+// configurations without contexts.
+type golabConfig struct {
+	Url            string                    `yaml:"url,omitempty"`
+	Token          string                    `yaml:"token,omitempty"`
+	TokenCommand   string                    `yaml:"token_command,omitempty"`
+	TokenFile      string                    `yaml:"token_file,omitempty"`
+	OAuth          *oauthConfig              `yaml:"oauth,omitempty"`
+	Defaults       map[string]interface{}    `yaml:"defaults,omitempty"`
+	CurrentContext string                    `yaml:"current_context,omitempty"`
+	Contexts       map[string]*configContext `yaml:"contexts,omitempty"`
+	Other          map[string]interface{}    `yaml:",inline"`
+}
+
+// configContext holds the connection settings and defaults for one Gitlab instance
+type configContext struct {
+	Url            string                 `yaml:"url,omitempty" json:"url"`
+	Token          string                 `yaml:"token,omitempty" json:"-"`
+	TokenCommand   string                 `yaml:"token_command,omitempty" json:"token_command,omitempty"`
+	TokenFile      string                 `yaml:"token_file,omitempty" json:"token_file,omitempty"`
+	OAuth          *oauthConfig           `yaml:"oauth,omitempty" json:"-"`
+	CaFile         string                 `yaml:"ca_file,omitempty" json:"ca_file,omitempty"`
+	CaPath         string                 `yaml:"ca_path,omitempty" json:"ca_path,omitempty"`
+	DefaultGroup   string                 `yaml:"default_group,omitempty" json:"default_group,omitempty"`
+	DefaultProject string                 `yaml:"default_project,omitempty" json:"default_project,omitempty"`
+	MaxRetries     *int                   `yaml:"max_retries,omitempty" json:"max_retries,omitempty"`
+	MaxRetryWait   string                 `yaml:"max_retry_wait,omitempty" json:"max_retry_wait,omitempty"`
+	Defaults       map[string]interface{} `yaml:"defaults,omitempty" json:"defaults,omitempty"`
+	Other          map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+type contextInfo struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+	*configContext
+}
+
+// activeContext is the context selected with `--context`, $GOLAB_CONTEXT or `current_context`, nil if there is none
+var activeContext *configContext
+
+// flagDefaults holds the `defaults` of the config file and the active context, keyed by command path and flag name
+var flagDefaults map[string]interface{}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage golab configuration",
+	Long: `Manage named contexts in the golab configuration file.
+
+A context holds the URL and token of a Gitlab instance together with optional certificates and defaults:
+
+    ---
+    current_context: staging
+    contexts:
+      staging:
+        url: "https://gitlab.staging.example.com"
+        token: "<access token>"
+        ca_file: "/etc/ssl/staging.pem"
+        default_group: "my-group"
+        default_project: "my-group/my-project"
+        max_retries: 5
+        max_retry_wait: "1m"
+      production:
+        url: "https://gitlab.example.com"
+        token_command: "pass show gitlab/production"
+
+Instead of a token, a context can name a token_command that prints the token (e.g. from pass or vault)
+or a token_file that holds the token encrypted with a passphrase, see 'golab login --encrypt'.
+
+Flags can get defaults at the top level or per context, keyed by command path and flag name:
+
+    defaults:
+      merge-requests.create.target_branch: main
+
+Values from the command line win over environment variables (e.g. $GOLAB_PROJECT), which win over these defaults.
+
+Select a context for a single command with --context <name>.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("cannot run this command without further sub-commands")
+	},
+}
+
+var configGetContextsCmd = &cobra.Command{
+	Use:   "get-contexts",
+	Short: "List contexts",
+	Long:  `Lists all contexts of the configuration file, tokens are not shown.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, _, err := loadGolabConfig()
+		if err != nil {
+			return err
+		}
+		current := selectedContextName(conf)
+		var names []string
+		for name := range conf.Contexts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		contexts := []contextInfo{}
+		for _, name := range names {
+			contexts = append(contexts, contextInfo{Name: name, Current: name == current, configContext: conf.Contexts[name]})
+		}
+		return Output(contexts)
+	},
+}
+
+var configUseContextCmd = &cobra.Command{
+	Use:   "use-context <name>",
+	Short: "Set the current context",
+	Long:  `Sets the context that is used if no --context is given.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, path, err := loadGolabConfig()
+		if err != nil {
+			return err
+		}
+		if _, ok := conf.Contexts[args[0]]; !ok {
+			return fmt.Errorf("context '%s' does not exist in %s", args[0], path)
+		}
+		conf.CurrentContext = args[0]
+		if err := writeGolabConfig(conf, path); err != nil {
+			return err
+		}
+		fmt.Printf("** switched to context %s\n", args[0])
+		return nil
+	},
+}
+
+var configSetContextCmd = &cobra.Command{
+	Use:   "set-context <name>",
+	Short: "Create or update a context",
+	Long:  `Creates a context or updates the given settings of an existing context. Certificates are taken from --ca-file and --ca-path.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, path, err := loadGolabConfig()
+		if err != nil {
+			return err
+		}
+		if conf.Contexts == nil {
+			conf.Contexts = map[string]*configContext{}
+		}
+		context, ok := conf.Contexts[args[0]]
+		if !ok {
+			context = &configContext{}
+			conf.Contexts[args[0]] = context
+		}
+		flags := cmd.Flags()
+		if flags.Changed("url") {
+			context.Url = contextUrl
+		}
+		if flags.Changed("token") {
+			context.Token = contextToken
+		}
+		if flags.Changed("token-command") {
+			context.TokenCommand = contextTokenCommand
+		}
+		if flags.Changed("token-file") {
+			context.TokenFile = contextTokenFile
+		}
+		if flags.Changed("ca-file") {
+			context.CaFile = caFile
+		}
+		if flags.Changed("ca-path") {
+			context.CaPath = caPath
+		}
+		if flags.Changed("default-group") {
+			context.DefaultGroup = contextDefaultGroup
+		}
+		if flags.Changed("default-project") {
+			context.DefaultProject = contextDefaultProject
+		}
+		if flags.Changed("max-retries") {
+			context.MaxRetries = &contextMaxRetries
+		}
+		if flags.Changed("max-retry-wait") {
+			if _, err := time.ParseDuration(contextMaxRetryWait); err != nil {
+				return fmt.Errorf("invalid --max-retry-wait '%s', use a duration like 30s", contextMaxRetryWait)
+			}
+			context.MaxRetryWait = contextMaxRetryWait
+		}
+		if context.Url == "" {
+			return fmt.Errorf("context '%s' requires an url, use --url", args[0])
+		}
+		if conf.CurrentContext == "" {
+			conf.CurrentContext = args[0]
+		}
+		if err := writeGolabConfig(conf, path); err != nil {
+			return err
+		}
+		fmt.Printf("** context %s written to %s\n", args[0], path)
+		return nil
+	},
+}
+
+var configDeleteContextCmd = &cobra.Command{
+	Use:   "delete-context <name>",
+	Short: "Delete a context",
+	Long:  `Removes a context from the configuration file.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, path, err := loadGolabConfig()
+		if err != nil {
+			return err
+		}
+		if _, ok := conf.Contexts[args[0]]; !ok {
+			return fmt.Errorf("context '%s' does not exist in %s", args[0], path)
+		}
+		delete(conf.Contexts, args[0])
+		if conf.CurrentContext == args[0] {
+			conf.CurrentContext = ""
+		}
+		if err := writeGolabConfig(conf, path); err != nil {
+			return err
+		}
+		fmt.Printf("** context %s deleted\n", args[0])
+		return nil
+	},
+}
+
+// configFilePath returns the config file given with --config, the one that was found or ~/.config/golab/config.yml
+func configFilePath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	if used := viper.ConfigFileUsed(); used != "" {
+		return used, nil
+	}
+	return userConfigFile()
+}
+
+// loadGolabConfig reads the config file, a missing file results in an empty configuration
+func loadGolabConfig() (*golabConfig, string, error) {
+	path, err := configFilePath()
+	if err != nil {
+		return nil, "", err
+	}
+	conf, err := readGolabConfig(path)
+	return conf, path, err
+}
+
+func readGolabConfig(path string) (*golabConfig, error) {
+	conf := &golabConfig{}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return conf, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, conf); err != nil {
+		return nil, fmt.Errorf("could not parse config file %s: %s", path, err)
+	}
+	return conf, nil
+}
+
+func writeGolabConfig(conf *golabConfig, path string) error {
+	content, err := yaml.Marshal(conf)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append([]byte("---\n"), content...), 0600)
+}
+
+func selectedContextName(conf *golabConfig) string {
+	if contextName != "" {
+		return contextName
+	}
+	if env := os.Getenv("GOLAB_CONTEXT"); env != "" {
+		return env
+	}
+	return conf.CurrentContext
+}
+
+// applyContext makes the settings of the selected context available to the gitlab client,
+// command line flags take precedence over the settings of the context
+func applyContext() error {
+	conf, path, err := loadGolabConfig()
+	if err != nil {
+		return err
+	}
+	flagDefaults = map[string]interface{}{}
+	flattenDefaults("", conf.Defaults, flagDefaults)
+	name := selectedContextName(conf)
+	if name == "" {
+		return nil
+	}
+	context, ok := conf.Contexts[name]
+	if !ok {
+		return fmt.Errorf("context '%s' does not exist in %s", name, path)
+	}
+	activeContext = context
+	flattenDefaults("", context.Defaults, flagDefaults)
+	viper.Set("url", context.Url)
+	viper.Set("token", context.Token)
+	viper.Set("token_command", context.TokenCommand)
+	viper.Set("token_file", context.TokenFile)
+	if context.MaxRetries != nil {
+		viper.Set("max_retries", *context.MaxRetries)
+	}
+	if context.MaxRetryWait != "" {
+		viper.Set("max_retry_wait", context.MaxRetryWait)
+	}
+	if caFile == "" {
+		caFile = context.CaFile
+	}
+	if caPath == "" {
+		caPath = context.CaPath
+	}
+	return nil
+}
+
+// flattenDefaults adds the defaults to result with keys like `merge-requests.create.target_branch`, they can be
+// given with dotted keys or as nested maps
+func flattenDefaults(prefix string, defaults map[string]interface{}, result map[string]interface{}) {
+	for key, value := range defaults {
+		key = prefix + key
+		switch nested := value.(type) {
+		case map[interface{}]interface{}:
+			converted := map[string]interface{}{}
+			for k, v := range nested {
+				converted[fmt.Sprint(k)] = v
+			}
+			flattenDefaults(key+".", converted, result)
+		case map[string]interface{}:
+			flattenDefaults(key+".", nested, result)
+		default:
+			result[key] = value
+		}
+	}
+}
+
+// configDefault returns the default of a flag from the `defaults` of the config file, lists are returned
+// as several values, e.g. for flags that can be given more than once
+func configDefault(cmd *cobra.Command, flagName string) ([]string, string, bool) {
+	key := strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), RootCmd.Name()), " ")
+	key = strings.Replace(key, " ", ".", -1) + "." + flagName
+	value, ok := flagDefaults[key]
+	if !ok || value == nil {
+		return nil, "", false
+	}
+	source := "config default " + key
+	if list, ok := value.([]interface{}); ok {
+		var values []string
+		for _, v := range list {
+			values = append(values, fmt.Sprint(v))
+		}
+		return values, source, true
+	}
+	return []string{fmt.Sprint(value)}, source, true
+}
+
+// applyDefaultId sets the --id flag of project and group commands, if it was not given on the command line.
+// Projects are taken from the git remote of the working directory or the default project of the active context,
+// groups from the default group of the active context.
+func applyDefaultId(cmd *cobra.Command) error {
+	flag := cmd.Flags().Lookup("id")
+	if flag == nil || flag.Changed {
+		return nil
+	}
+	if _, ok := cmd.Annotations[explicitIdAnnotation]; ok {
+		return fmt.Errorf("'%s' needs an explicit --id, it is not taken from the git remote or the context", cmd.CommandPath())
+	}
+	defaultId := ""
+	switch resourceOfIdFlag(cmd) {
+	case "project":
+		if flag.Value.Type() == "string" {
+			path, err := projectFromGitRemote()
+			if err != nil && (remoteName != "" || activeContext == nil || activeContext.DefaultProject == "") {
+				return err
+			}
+			defaultId = path
+		}
+		if defaultId == "" && activeContext != nil {
+			defaultId = activeContext.DefaultProject
+		}
+	case "group":
+		if activeContext != nil {
+			defaultId = activeContext.DefaultGroup
+		}
+	}
+	if defaultId == "" {
+		return nil
+	}
+	if err := cmd.Flags().Set("id", defaultId); err != nil {
+		return fmt.Errorf("default '%s' cannot be used as --id: %s", defaultId, err)
+	}
+	return nil
+}
+
+// idResourceAnnotation tells whether the --id flag of a command and its sub-commands refers to a "project" or a
+// "group", it is set on top-level commands and on sub-commands that refer to another resource, e.g. `issues group-ls`
+const idResourceAnnotation = "id-resource"
+
+// configError and contextError hold the errors of reading the config file and applying the selected context,
+// initConfig cannot return them since cobra's initializers do not know the command
+var configError, contextError error
+
+// checkConfig returns the errors of initConfig before a command runs. Context errors are ignored for the `config`
+// commands, since they are used to fix a broken context.
+func checkConfig(cmd *cobra.Command) error {
+	if configError != nil {
+		return configError
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == configCmd {
+			return nil
+		}
+	}
+	return contextError
+}
+
+// explicitIdAnnotation marks destructive commands whose --id is never taken from the git remote or the active
+// context, so running them in the wrong directory cannot e.g. delete the wrong project
+const explicitIdAnnotation = "explicit-id"
+
+// resourceOfIdFlag returns the idResourceAnnotation of the command or its closest parent that has one
+func resourceOfIdFlag(cmd *cobra.Command) string {
+	for c := cmd; c != nil; c = c.Parent() {
+		if resource, ok := c.Annotations[idResourceAnnotation]; ok {
+			return resource
+		}
+	}
+	return ""
+}
+
+func init() {
+	configSetContextCmd.PersistentFlags().StringVar(&contextUrl, "url", "", "(required for new contexts) URL of the Gitlab server, e.g. https://gitlab.com")
+	configSetContextCmd.PersistentFlags().StringVar(&contextToken, "token", "", "(optional) access token for the Gitlab server")
+	configSetContextCmd.PersistentFlags().StringVar(&contextTokenCommand, "token-command", "", "(optional) command that prints the access token, e.g. 'pass show gitlab/token'")
+	configSetContextCmd.PersistentFlags().StringVar(&contextTokenFile, "token-file", "", "(optional) file with the access token encrypted by 'golab login --encrypt'")
+	configSetContextCmd.PersistentFlags().StringVar(&contextDefaultGroup, "default-group", "", "(optional) group used for group commands if no --id is given")
+	configSetContextCmd.PersistentFlags().StringVar(&contextDefaultProject, "default-project", "", "(optional) project used for project commands if no --id is given")
+	configSetContextCmd.PersistentFlags().IntVar(&contextMaxRetries, "max-retries", defaultMaxRetries, "(optional) how often failed requests are retried, 0 disables retries")
+	configSetContextCmd.PersistentFlags().StringVar(&contextMaxRetryWait, "max-retry-wait", defaultMaxRetryWait.String(), "(optional) longest wait before a retry, e.g. 30s")
+	configCmd.AddCommand(configGetContextsCmd)
+	configCmd.AddCommand(configUseContextCmd)
+	configCmd.AddCommand(configSetContextCmd)
+	configCmd.AddCommand(configDeleteContextCmd)
+	RootCmd.AddCommand(configCmd)
+	RootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := checkConfig(cmd); err != nil {
+			return err
+		}
+		if err := checkOutputFormat(outputFormat); err != nil {
+			return err
+		}
+		if err := mapper.ApplyDefaults(cmd, configDefault); err != nil {
+			return err
+		}
+		return applyDefaultId(cmd)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+)
+
+var _ = Describe("config contexts", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "golab-config")
+		Expect(err).To(BeNil())
+		cfgFile = filepath.Join(dir, ".golab.yml")
+		err = ioutil.WriteFile(cfgFile, []byte(`---
+current_context: staging
+defaults:
+  merge-requests:
+    create:
+      target_branch: main
+      remove_source_branch: true
+  merge-requests.ls.labels: [bug, feature]
+contexts:
+  staging:
+    url: "https://staging.example.com"
+    token: "staging-token"
+    default_project: "group/project"
+    defaults:
+      merge-requests.create.target_branch: develop
+  production:
+    url: "https://gitlab.example.com"
+    token: "production-token"
+    ca_file: "/etc/ssl/gitlab.pem"
+`), 0600)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+		cfgFile, contextName, caFile, activeContext, flagDefaults = "", "", "", nil, nil
+		viper.Set("url", nil)
+		viper.Set("token", nil)
+	})
+
+	It("uses the current context by default", func() {
+		Expect(applyContext()).To(BeNil())
+		Expect(viper.GetString("url")).To(Equal("https://staging.example.com"))
+		Expect(viper.GetString("token")).To(Equal("staging-token"))
+		Expect(activeContext.DefaultProject).To(Equal("group/project"))
+	})
+
+	It("prefers the context given with --context", func() {
+		contextName = "production"
+		Expect(applyContext()).To(BeNil())
+		Expect(viper.GetString("url")).To(Equal("https://gitlab.example.com"))
+		Expect(caFile).To(Equal("/etc/ssl/gitlab.pem"))
+	})
+
+	It("reads flag defaults by command path, the active context overrides the top level", func() {
+		Expect(applyContext()).To(BeNil())
+		create, _, err := RootCmd.Find([]string{"merge-requests", "create"})
+		Expect(err).To(BeNil())
+
+		values, source, ok := configDefault(create, "target_branch")
+		Expect(ok).To(BeTrue())
+		Expect(values).To(Equal([]string{"develop"}))
+		Expect(source).To(Equal("config default merge-requests.create.target_branch"))
+
+		values, _, _ = configDefault(create, "remove_source_branch")
+		Expect(values).To(Equal([]string{"true"}))
+
+		_, _, ok = configDefault(create, "title")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns lists in flag defaults as several values", func() {
+		Expect(applyContext()).To(BeNil())
+		ls, _, err := RootCmd.Find([]string{"merge-requests", "ls"})
+		Expect(err).To(BeNil())
+		values, _, ok := configDefault(ls, "labels")
+		Expect(ok).To(BeTrue())
+		Expect(values).To(Equal([]string{"bug", "feature"}))
+	})
+
+	It("returns an error for unknown contexts", func() {
+		contextName = "unknown"
+		err := applyContext()
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("context 'unknown' does not exist"))
+	})
+
+	It("keeps unknown keys when writing the config file", func() {
+		Expect(ioutil.WriteFile(cfgFile, []byte("---\nurl: http://localhost\ntoken: abc\nfoo: bar\n"), 0600)).To(BeNil())
+		_, _, err := executeCommand(RootCmd, "config", "set-context", "local", "--url", "http://localhost:8080")
+		Expect(err).To(BeNil())
+		content, _ := ioutil.ReadFile(cfgFile)
+		Expect(string(content)).To(ContainSubstring("foo: bar"))
+		Expect(string(content)).To(ContainSubstring("current_context: local"))
+	})
+
+	It("fails on config errors and on context errors except for the config commands", func() {
+		defer func() { configError, contextError = nil, nil }()
+		userGet, _, _ := RootCmd.Find([]string{"user", "get"})
+		getContexts, _, _ := RootCmd.Find([]string{"config", "get-contexts"})
+
+		contextError = errors.New("context 'nope' does not exist")
+		Expect(checkConfig(userGet)).To(MatchError("context 'nope' does not exist"))
+		Expect(checkConfig(getContexts)).To(Succeed())
+
+		configError = errors.New("cannot read config file")
+		Expect(checkConfig(getContexts)).To(MatchError("cannot read config file"))
+	})
+
+	It("takes the resource of the --id flag from the annotation of the command or its parents", func() {
+		for path, resource := range map[string]string{
+			"issues ls":          "project",
+			"issues group-ls":    "group",
+			"group-members ls":   "group",
+			"branches ls":        "project",
+			"project-members ls": "project",
+			"files get":          "project",
+			"files push":         "project",
+			"commits ls":         "project",
+			"tags ls":            "project",
+			"labels ls":          "project",
+			"milestones ls":      "project",
+			"wiki ls":            "project",
+			"wiki export":        "project",
+			"config use-context": "",
+		} {
+			cmd, _, err := RootCmd.Find(strings.Fields(path))
+			Expect(err).To(BeNil())
+			Expect(resourceOfIdFlag(cmd)).To(Equal(resource), path)
+		}
+	})
+})
@@ -0,0 +1,267 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/howeyc/gopass"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// passphraseEnv is the environment variable holding the passphrase of encrypted token files
+const passphraseEnv = "GOLAB_TOKEN_PASSPHRASE"
+
+// userConfigDir returns the directory for golab's configuration, $XDG_CONFIG_HOME/golab or ~/.config/golab
+func userConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "golab"), nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "golab"), nil
+}
+
+// userConfigFile returns the default location of the config file, config.yml in the user config dir
+func userConfigFile() (string, error) {
+	dir, err := userConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.yml"), nil
+}
+
+// configSearchPath lists the config files golab looks for, in the order of precedence
+func configSearchPath() []string {
+	var paths []string
+	if file, err := userConfigFile(); err == nil {
+		paths = append(paths, file)
+	}
+	if home, err := homedir.Dir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".golab.yml"))
+	}
+	if pwd, err := os.Getwd(); err == nil {
+		paths = append(paths, filepath.Join(pwd, ".golab.yml"))
+	}
+	return paths
+}
+
+// findConfigFile returns the first existing file of the config search path, an empty string if there is none
+func findConfigFile() string {
+	for _, path := range configSearchPath() {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// resolveToken returns the token given in the configuration: a plain token, the output of the token command
+// or the content of the encrypted token file, in this order
+func resolveToken(token string, tokenCommand string, tokenFile string) (string, error) {
+	if token != "" {
+		return token, nil
+	}
+	if tokenCommand != "" {
+		return runTokenCommand(tokenCommand)
+	}
+	if tokenFile != "" {
+		return readTokenFile(tokenFile)
+	}
+	return "", nil
+}
+
+// runTokenCommand runs the command with the shell and returns its trimmed output, e.g. `pass show gitlab/token`
+func runTokenCommand(command string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token_command '%s' failed: %s", command, err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("token_command '%s' returned no token", command)
+	}
+	return token, nil
+}
+
+// readTokenFile decrypts a token file written by `golab login --encrypt` (an OpenPGP message encrypted with a passphrase)
+func readTokenFile(path string) (string, error) {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read token_file: %s", err)
+	}
+	token, err := decryptToken(content, func() ([]byte, error) {
+		return passphrase(fmt.Sprintf("Enter passphrase for %s: ", path))
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt token_file %s: %s", path, err)
+	}
+	return token, nil
+}
+
+func encryptToken(token string, passphrase []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	armored, err := armor.Encode(buf, "PGP MESSAGE", nil)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := openpgp.SymmetricallyEncrypt(armored, passphrase, nil, &packet.Config{DefaultCipher: packet.CipherAES256})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := plaintext.Write([]byte(token)); err != nil {
+		return nil, err
+	}
+	if err := plaintext.Close(); err != nil {
+		return nil, err
+	}
+	if err := armored.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decryptToken(content []byte, passphrase func() ([]byte, error)) (string, error) {
+	block, err := armor.Decode(bytes.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	// the prompt is called again as long as the passphrase is wrong, so it is asked only once
+	asked := false
+	prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		if asked {
+			return nil, errors.New("wrong passphrase")
+		}
+		asked = true
+		return passphrase()
+	}
+	message, err := openpgp.ReadMessage(block.Body, nil, prompt, nil)
+	if err != nil {
+		return "", err
+	}
+	token, err := ioutil.ReadAll(message.UnverifiedBody)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// passphrase returns the passphrase from $GOLAB_TOKEN_PASSPHRASE or asks for it interactively
+func passphrase(prompt string) ([]byte, error) {
+	if env := os.Getenv(passphraseEnv); env != "" {
+		return []byte(env), nil
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	pass, err := gopass.GetPasswd()
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	return pass, nil
+}
+
+// tokenTransport adds the token of the token_command or token_file setting to all requests. The token is
+// resolved with the first request, so commands that don't talk to Gitlab never run the command or ask for a passphrase.
+type tokenTransport struct {
+	next    http.RoundTripper
+	resolve func() (string, error)
+	once    sync.Once
+	token   string
+	err     error
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Private-Token") != "" {
+		return t.next.RoundTrip(req)
+	}
+	t.once.Do(func() {
+		t.token, t.err = t.resolve()
+	})
+	if t.err != nil {
+		return nil, t.err
+	}
+	// a RoundTripper must not modify the given request
+	clone := new(http.Request)
+	*clone = *req
+	clone.Header = http.Header{}
+	for name, values := range req.Header {
+		clone.Header[name] = values
+	}
+	clone.Header.Set("Private-Token", t.token)
+	return t.next.RoundTrip(clone)
+}
+
+// withConfiguredToken wraps the transport of the client if the token is given by token_command, token_file
+// or the OAuth tokens of `golab login --oauth`
+func withConfiguredToken(client *http.Client) error {
+	tokenCommand, tokenFile := viper.GetString("token_command"), viper.GetString("token_file")
+	if viper.GetString("token") != "" {
+		return nil
+	}
+	if tokenCommand != "" || tokenFile != "" {
+		client.Transport = &tokenTransport{next: client.Transport, resolve: func() (string, error) {
+			return resolveToken("", tokenCommand, tokenFile)
+		}}
+		return nil
+	}
+	oauth, err := configuredOAuth()
+	if err != nil || oauth == nil {
+		return err
+	}
+	client.Transport = &oauthTransport{
+		next:   client.Transport,
+		tokens: withoutDryRun(client.Transport),
+		host:   viper.GetString("url"),
+		oauth:  oauth,
+		save:   saveOAuth,
+		now:    time.Now,
+	}
+	return nil
+}
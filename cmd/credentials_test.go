@@ -0,0 +1,89 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("credentials", func() {
+
+	It("decrypts an encrypted token with the right passphrase only", func() {
+		encrypted, err := encryptToken("my-token", []byte("secret"))
+		Expect(err).To(BeNil())
+		Expect(string(encrypted)).NotTo(ContainSubstring("my-token"))
+
+		token, err := decryptToken(encrypted, func() ([]byte, error) { return []byte("secret"), nil })
+		Expect(err).To(BeNil())
+		Expect(token).To(Equal("my-token"))
+
+		_, err = decryptToken(encrypted, func() ([]byte, error) { return []byte("wrong"), nil })
+		Expect(err).To(MatchError(ContainSubstring("wrong passphrase")))
+	})
+
+	It("prefers the token over the token command", func() {
+		token, err := resolveToken("plain", "echo from-command", "")
+		Expect(err).To(BeNil())
+		Expect(token).To(Equal("plain"))
+
+		token, err = resolveToken("", "echo ' from-command '", "does-not-exist")
+		Expect(err).To(BeNil())
+		Expect(token).To(Equal("from-command"))
+	})
+
+	It("fails for token commands without output", func() {
+		_, err := resolveToken("", "true", "")
+		Expect(err).To(MatchError("token_command 'true' returned no token"))
+	})
+
+	It("resolves the token once with the first request", func() {
+		var tokens []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokens = append(tokens, r.Header.Get("Private-Token"))
+		}))
+		defer server.Close()
+
+		resolved := 0
+		client := &http.Client{Transport: &tokenTransport{next: http.DefaultTransport, resolve: func() (string, error) {
+			resolved++
+			return "resolved-token", nil
+		}}}
+		for i := 0; i < 2; i++ {
+			_, err := client.Get(server.URL)
+			Expect(err).To(BeNil())
+		}
+		Expect(tokens).To(Equal([]string{"resolved-token", "resolved-token"}))
+		Expect(resolved).To(Equal(1))
+	})
+
+	It("fails requests if the token cannot be resolved", func() {
+		client := &http.Client{Transport: &tokenTransport{next: http.DefaultTransport, resolve: func() (string, error) {
+			return "", errors.New("no token")
+		}}}
+		_, err := client.Get("http://localhost")
+		Expect(err).To(MatchError(ContainSubstring("no token")))
+	})
+})
@@ -0,0 +1,251 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// debug, trace and curl hold the values of the global `--debug`, `--trace` and `--curl` flags
+var debug, trace, curl bool
+
+// maxDebugBody is the number of bytes of a body that are logged with --trace
+const maxDebugBody = 10000
+
+const redacted = "[REDACTED]"
+
+// debugTransport logs every request sent to Gitlab and its response: with debug method, URL, status and timing,
+// with trace also headers and bodies, with curl an equivalent curl command line. Secrets are redacted.
+type debugTransport struct {
+	next  http.RoundTripper
+	out   io.Writer
+	debug bool
+	trace bool
+	curl  bool
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && (t.trace || t.curl) {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req = req.WithContext(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	if t.curl {
+		fmt.Fprintln(t.out, curlCommand(req, body))
+	}
+	if t.debug || t.trace {
+		fmt.Fprintf(t.out, "--> %s %s\n", req.Method, redactedUrl(req))
+		if t.trace {
+			printHeaders(t.out, req.Header)
+			printBody(t.out, req.Header, body)
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if !(t.debug || t.trace) {
+		return resp, err
+	}
+	elapsed := time.Since(start) / time.Millisecond
+	if err != nil {
+		fmt.Fprintf(t.out, "<-- %s %s failed after %dms: %s\n", req.Method, redactedUrl(req), elapsed, err)
+		return resp, err
+	}
+	fmt.Fprintf(t.out, "<-- %s %s %s (%dms)\n", resp.Status, req.Method, redactedUrl(req), elapsed)
+	if t.trace {
+		printHeaders(t.out, resp.Header)
+		if isTextContent(resp.Header) {
+			content, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = ioutil.NopCloser(bytes.NewReader(content))
+			if err != nil {
+				return resp, err
+			}
+			printBody(t.out, resp.Header, content)
+		} else {
+			fmt.Fprintf(t.out, "[%s body not shown]\n", resp.Header.Get("Content-Type"))
+		}
+	}
+	return resp, nil
+}
+
+func printHeaders(w io.Writer, header http.Header) {
+	var names []string
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "    %s: %s\n", name, headerValue(header, name))
+	}
+}
+
+func headerValue(header http.Header, name string) string {
+	if isSecretHeader(name) {
+		return redacted
+	}
+	return strings.Join(header[name], ", ")
+}
+
+func printBody(w io.Writer, header http.Header, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	if !isTextContent(header) {
+		fmt.Fprintf(w, "    [%d bytes of %s]\n", len(body), header.Get("Content-Type"))
+		return
+	}
+	body = redactBody(body)
+	if len(body) > maxDebugBody {
+		body = append(body[:maxDebugBody:maxDebugBody], []byte("... (truncated)")...)
+	}
+	fmt.Fprintf(w, "    %s\n", body)
+}
+
+func isTextContent(header http.Header) bool {
+	contentType := header.Get("Content-Type")
+	return contentType == "" || strings.Contains(contentType, "json") || strings.HasPrefix(contentType, "text/")
+}
+
+func isSecretKey(key string) bool {
+	lower := strings.ToLower(key)
+	return strings.Contains(lower, "token") || strings.Contains(lower, "password") || strings.Contains(lower, "secret")
+}
+
+// redactedUrl returns the URL of a request with secrets in query parameters redacted
+func redactedUrl(req *http.Request) string {
+	if req.URL.RawQuery == "" {
+		return requestUrl(req)
+	}
+	query := req.URL.Query()
+	for key := range query {
+		if isSecretKey(key) {
+			query.Set(key, redacted)
+		}
+	}
+	copied := *req.URL
+	copied.RawQuery = query.Encode()
+	return requestUrl(&http.Request{URL: &copied})
+}
+
+// redactBody redacts secrets in a JSON object or list of objects, other bodies are returned unchanged
+func redactBody(body []byte) []byte {
+	var generic interface{}
+	if err := json.Unmarshal(body, &generic); err != nil {
+		return body
+	}
+	if !redactValue(generic) {
+		return body
+	}
+	result, err := json.Marshal(generic)
+	if err != nil {
+		return body
+	}
+	return result
+}
+
+func redactValue(value interface{}) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if _, isString := nested.(string); isString && isSecretKey(key) {
+				v[key] = redacted
+				changed = true
+			} else if redactValue(nested) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, nested := range v {
+			if redactValue(nested) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// curlCommand returns a curl command line for a request, the token is taken from $GITLAB_TOKEN and other secrets
+// are redacted. Multipart and binary bodies are replaced by a placeholder for a file with the body.
+func curlCommand(req *http.Request, body []byte) string {
+	parts := []string{"curl", "-X", req.Method}
+	var names []string
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "User-Agent" {
+			continue
+		}
+		if isSecretHeader(name) {
+			parts = append(parts, "-H", fmt.Sprintf(`"%s: $GITLAB_TOKEN"`, name))
+			continue
+		}
+		parts = append(parts, "-H", shellQuote(name+": "+strings.Join(req.Header[name], ", ")))
+	}
+	if len(body) > 0 {
+		contentType := req.Header.Get("Content-Type")
+		switch {
+		case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+			parts = append(parts, "--data", shellQuote(redactForm(body)))
+		case isTextContent(req.Header):
+			parts = append(parts, "--data", shellQuote(string(redactBody(body))))
+		default:
+			parts = append(parts, "--data-binary", shellQuote(fmt.Sprintf("@<file with %d bytes of %s>", len(body), strings.SplitN(contentType, ";", 2)[0])))
+		}
+	}
+	return strings.Join(append(parts, shellQuote(redactedUrl(req))), " ")
+}
+
+// redactForm redacts secrets in a form encoded body
+func redactForm(body []byte) string {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return string(body)
+	}
+	for key := range form {
+		if isSecretKey(key) {
+			form.Set(key, redacted)
+		}
+	}
+	return form.Encode()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
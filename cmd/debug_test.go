@@ -0,0 +1,102 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/michaellihs/golab/fake"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("debug transport", func() {
+
+	var (
+		server *fake.Server
+		out    *bytes.Buffer
+	)
+
+	clientWith := func(transport *debugTransport) *gitlab.Client {
+		transport.next = http.DefaultTransport
+		transport.out = out
+		client := gitlab.NewClient(&http.Client{Transport: transport}, fake.Token)
+		client.SetBaseURL(server.URL + "/api/v4")
+		return client
+	}
+
+	BeforeEach(func() {
+		server = fake.NewServer()
+		out = &bytes.Buffer{}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("logs method, URL, status and duration with --debug", func() {
+		_, _, err := clientWith(&debugTransport{debug: true}).Users.CurrentUser()
+
+		Expect(err).To(BeNil())
+		Expect(out.String()).To(MatchRegexp(`^--> GET %s/api/v4/user\n<-- 200 OK GET %s/api/v4/user \(\d+ms\)\n$`, server.URL, server.URL))
+	})
+
+	It("logs headers and bodies with secrets redacted with --trace", func() {
+		login, password := "root", fake.RootPassword
+		_, _, err := clientWith(&debugTransport{trace: true}).Session.GetSession(&gitlab.GetSessionOptions{Login: &login, Password: &password})
+
+		Expect(err).To(BeNil())
+		Expect(out.String()).To(ContainSubstring("    Private-Token: [REDACTED]\n"))
+		Expect(out.String()).To(ContainSubstring(`    {"login":"root","password":"[REDACTED]"}`))
+		Expect(out.String()).To(ContainSubstring(`"private_token":"[REDACTED]"`))
+		Expect(out.String()).NotTo(ContainSubstring(fake.Token))
+		Expect(out.String()).NotTo(ContainSubstring(fake.RootPassword))
+	})
+
+	It("logs an equivalent curl command with --curl", func() {
+		_, _, err := clientWith(&debugTransport{curl: true}).Groups.CreateGroup(&gitlab.CreateGroupOptions{Name: gitlab.String("It's"), Path: gitlab.String("its")})
+
+		Expect(err).To(BeNil())
+		Expect(out.String()).To(Equal(`curl -X POST -H 'Accept: application/json' -H 'Content-Type: application/json' -H "Private-Token: $GITLAB_TOKEN" ` +
+			`--data '{"name":"It'\''s","path":"its"}' '` + server.URL + "/api/v4/groups'\n"))
+	})
+
+	It("redacts secrets in the curl command and does not print binary bodies", func() {
+		login, password := "root", fake.RootPassword
+		_, _, err := clientWith(&debugTransport{curl: true}).Session.GetSession(&gitlab.GetSessionOptions{Login: &login, Password: &password})
+		Expect(err).To(BeNil())
+		Expect(out.String()).To(ContainSubstring(`--data '{"login":"root","password":"[REDACTED]"}'`))
+		Expect(out.String()).NotTo(ContainSubstring(fake.RootPassword))
+
+		form, _ := http.NewRequest("POST", server.URL+"/oauth/token", strings.NewReader("grant_type=password&password=secret&username=root"))
+		form.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		Expect(curlCommand(form, []byte("grant_type=password&password=secret&username=root"))).To(ContainSubstring(
+			`--data 'grant_type=password&password=%5BREDACTED%5D&username=root'`))
+
+		upload, _ := http.NewRequest("POST", server.URL+"/api/v4/projects/1/uploads", nil)
+		upload.Header.Set("Content-Type", "multipart/form-data; boundary=abc")
+		Expect(curlCommand(upload, []byte{0x1f, 0x8b, 0x08})).To(ContainSubstring(`--data-binary '@<file with 3 bytes of multipart/form-data>'`))
+	})
+
+})
@@ -0,0 +1,127 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// dryRun holds the value of the global `--dry-run` flag
+var dryRun bool
+
+// dryRunTransport sends GET requests, all other requests are printed to out and answered with a synthetic response
+type dryRunTransport struct {
+	next http.RoundTripper
+	out  io.Writer
+}
+
+func (t *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == "GET" || req.Method == "HEAD" {
+		return t.next.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	printDryRunRequest(t.out, req, body)
+
+	// the request body is echoed, so commands print what would have been created or changed
+	responseBody := []byte("{}")
+	var object map[string]interface{}
+	if json.Unmarshal(body, &object) == nil {
+		responseBody = body
+	}
+	status := http.StatusOK
+	if req.Method == "POST" {
+		status = http.StatusCreated
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(responseBody)),
+		ContentLength: int64(len(responseBody)),
+		Request:       req,
+	}, nil
+}
+
+func printDryRunRequest(w io.Writer, req *http.Request, body []byte) {
+	fmt.Fprintf(w, "DRY RUN: %s %s\n", req.Method, redactedUrl(req))
+	var names []string
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s: %s\n", name, headerValue(req.Header, name))
+	}
+	if len(body) > 0 {
+		contentType := req.Header.Get("Content-Type")
+		switch {
+		case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+			fmt.Fprintf(w, "\n%s\n", redactForm(body))
+		case isTextContent(req.Header):
+			body = redactBody(body)
+			var indented bytes.Buffer
+			if json.Indent(&indented, body, "", "  ") == nil {
+				body = indented.Bytes()
+			}
+			fmt.Fprintf(w, "\n%s\n", body)
+		default:
+			// uploads are summarised like with --trace instead of writing binary content to the terminal
+			fmt.Fprintf(w, "\n[%d bytes of %s]\n", len(body), strings.SplitN(contentType, ";", 2)[0])
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// requestUrl returns the URL of a request as sent, go-gitlab keeps the escaped path in URL.Opaque
+func requestUrl(req *http.Request) string {
+	path := req.URL.Opaque
+	if path == "" {
+		path = req.URL.EscapedPath()
+	}
+	result := req.URL.Scheme + "://" + req.URL.Host + path
+	if req.URL.RawQuery != "" {
+		result += "?" + req.URL.RawQuery
+	}
+	return result
+}
+
+func isSecretHeader(name string) bool {
+	lower := strings.ToLower(name)
+	return lower == "authorization" || strings.Contains(lower, "token")
+}
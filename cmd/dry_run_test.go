@@ -0,0 +1,100 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/michaellihs/golab/fake"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("dry run transport", func() {
+
+	var (
+		server *fake.Server
+		out    *bytes.Buffer
+		client *gitlab.Client
+	)
+
+	BeforeEach(func() {
+		server = fake.NewServer()
+		out = &bytes.Buffer{}
+		client = gitlab.NewClient(&http.Client{Transport: &dryRunTransport{next: http.DefaultTransport, out: out}}, fake.Token)
+		client.SetBaseURL(server.URL + "/api/v4")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("sends GET requests", func() {
+		user, _, err := client.Users.CurrentUser()
+
+		Expect(err).To(BeNil())
+		Expect(user.Username).To(Equal("root"))
+		Expect(out.String()).To(BeEmpty())
+	})
+
+	It("prints other requests with redacted token instead of sending them", func() {
+		group, _, err := client.Groups.CreateGroup(&gitlab.CreateGroupOptions{Name: gitlab.String("Group"), Path: gitlab.String("group")})
+
+		Expect(err).To(BeNil())
+		Expect(group.Name).To(Equal("Group"))
+		Expect(out.String()).To(HavePrefix("DRY RUN: POST " + server.URL + "/api/v4/groups\n"))
+		Expect(out.String()).To(ContainSubstring("Content-Type: application/json\nPrivate-Token: [REDACTED]\n"))
+		Expect(out.String()).To(HaveSuffix("\n{\n  \"name\": \"Group\",\n  \"path\": \"group\"\n}\n\n"))
+		Expect(out.String()).NotTo(ContainSubstring(fake.Token))
+		_, resp, _ := client.Groups.GetGroup("group")
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("prints escaped paths as sent", func() {
+		_, err := client.Projects.DeleteProject("group/project")
+
+		Expect(err).To(BeNil())
+		Expect(out.String()).To(HavePrefix("DRY RUN: DELETE " + server.URL + "/api/v4/projects/group%2Fproject\n"))
+	})
+
+	It("prints a summary instead of the content of uploads", func() {
+		req, err := http.NewRequest("POST", server.URL+"/api/v4/projects/1/uploads", nil)
+		Expect(err).To(BeNil())
+		req.Header.Set("Content-Type", "multipart/form-data; boundary=abc")
+
+		printDryRunRequest(out, req, []byte("--abc\r\n\x89PNG\r\n--abc--\r\n"))
+		Expect(out.String()).To(HaveSuffix("\n[22 bytes of multipart/form-data]\n\n"))
+		Expect(out.String()).NotTo(ContainSubstring("PNG"))
+	})
+
+	It("prints form bodies with redacted secrets", func() {
+		req, err := http.NewRequest("POST", server.URL+"/oauth/token", nil)
+		Expect(err).To(BeNil())
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		printDryRunRequest(out, req, []byte("grant_type=password&password=secret"))
+		Expect(out.String()).NotTo(ContainSubstring("=secret"))
+		Expect(out.String()).To(ContainSubstring("grant_type=password"))
+	})
+
+})
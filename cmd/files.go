@@ -0,0 +1,390 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/xanzy/go-gitlab"
+)
+
+// see https://docs.gitlab.com/ce/api/repository_files.html
+var filesCmd = &golabCommand{
+	Parent: RootCmd,
+	Cmd: &cobra.Command{
+		Use:         "files",
+		Aliases:     []string{"file"},
+		Short:       "Repository files",
+		Long:        `Get, create, update and delete files in a repository or push a local directory in a single commit`,
+		Annotations: map[string]string{idResourceAnnotation: "project"},
+	},
+	Run: func(cmd golabCommand) error {
+		return errors.New("cannot use this command without further sub-commands")
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/repository_files.html#get-file-from-repository
+type filesGetFlags struct {
+	Id       *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	FilePath *string `flag_name:"file_path" short:"f" type:"string" required:"yes" description:"Full path of the file, e.g. lib/class.rb"`
+	Ref      *string `flag_name:"ref" short:"r" type:"string" required:"yes" description:"The name of branch, tag or commit"`
+}
+
+var filesGetCmd = &golabCommand{
+	Parent: filesCmd.Cmd,
+	Flags:  &filesGetFlags{},
+	Opts:   &gitlab.GetFileOptions{},
+	Cmd: &cobra.Command{
+		Use:   "get",
+		Short: "Get file from repository",
+		Long:  `Receive information about a file in the repository like name, size and content. The content is base64 encoded, use 'files raw' for the plain content.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*filesGetFlags)
+		opts := cmd.Opts.(*gitlab.GetFileOptions)
+		file, _, err := gitlabClient.RepositoryFiles.GetFile(parsePid(*flags.Id), *flags.FilePath, opts)
+		if err != nil {
+			return err
+		}
+		return Output(file)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/repository_files.html#get-raw-file-from-repository
+type filesRawFlags struct {
+	Id       *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	FilePath *string `flag_name:"file_path" short:"f" type:"string" required:"yes" description:"Full path of the file, e.g. lib/class.rb"`
+	Ref      *string `flag_name:"ref" short:"r" type:"string" required:"yes" description:"The name of branch, tag or commit"`
+	Path     *string `flag_name:"path" short:"p" type:"string" required:"no" description:"Save the file to this path instead of writing it to stdout"`
+}
+
+var filesRawCmd = &golabCommand{
+	Parent: filesCmd.Cmd,
+	Flags:  &filesRawFlags{},
+	Cmd: &cobra.Command{
+		Use:   "raw",
+		Short: "Get raw file from repository",
+		Long:  `Writes the unchanged content of a file in the repository to stdout or to the file given by --path.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*filesRawFlags)
+		content, err := rawFile(*flags.Id, *flags.FilePath, *flags.Ref)
+		if err != nil {
+			return err
+		}
+		if flags.Path != nil {
+			return ioutil.WriteFile(*flags.Path, content, 0644)
+		}
+		_, err = os.Stdout.Write(content)
+		return err
+	},
+}
+
+// rawFile fetches the content of a repository file, go-gitlab's GetRawFile tries to decode it as JSON
+func rawFile(pid string, filePath string, ref string) ([]byte, error) {
+	u := fmt.Sprintf("projects/%s/repository/files/%s/raw", url.QueryEscape(pid), url.QueryEscape(filePath))
+	req, err := gitlabClient.NewRequest("GET", u, &gitlab.GetRawFileOptions{Ref: &ref}, nil)
+	if err != nil {
+		return nil, err
+	}
+	content := &bytes.Buffer{}
+	if _, err = gitlabClient.Do(req, content); err != nil {
+		return nil, err
+	}
+	return content.Bytes(), nil
+}
+
+// see https://docs.gitlab.com/ce/api/repository_files.html#create-new-file-in-repository
+type filesCreateFlags struct {
+	Id            *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	FilePath      *string `flag_name:"file_path" short:"f" type:"string" required:"yes" description:"Full path of the new file, e.g. lib/class.rb"`
+	Branch        *string `flag_name:"branch" short:"b" type:"string" required:"yes" description:"Name of the branch"`
+	Content       *string `flag_name:"content" short:"c" type:"string" file:"raw" required:"yes" description:"File content"`
+	CommitMessage *string `flag_name:"commit_message" short:"m" type:"string" required:"yes" description:"Commit message"`
+	Encoding      *string `flag_name:"encoding" short:"e" type:"string" choices:"text,base64" required:"no" description:"Encoding of the content, binary content is base64 encoded if no encoding is given"`
+	AuthorEmail   *string `flag_name:"author_email" type:"string" required:"no" description:"Specify the commit author's email address"`
+	AuthorName    *string `flag_name:"author_name" type:"string" required:"no" description:"Specify the commit author's name"`
+}
+
+var filesCreateCmd = &golabCommand{
+	Parent: filesCmd.Cmd,
+	Flags:  &filesCreateFlags{},
+	Opts:   &gitlab.CreateFileOptions{},
+	Cmd: &cobra.Command{
+		Use:   "create",
+		Short: "Create new file in repository",
+		Long:  `Create a new file in the repository with a commit on the given branch.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*filesCreateFlags)
+		opts := cmd.Opts.(*gitlab.CreateFileOptions)
+		opts.Content, opts.Encoding = encodeContent(*opts.Content, opts.Encoding)
+		file, _, err := gitlabClient.RepositoryFiles.CreateFile(parsePid(*flags.Id), *flags.FilePath, opts)
+		if err != nil {
+			return err
+		}
+		return Output(file)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/repository_files.html#update-existing-file-in-repository
+type filesUpdateFlags struct {
+	Id            *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	FilePath      *string `flag_name:"file_path" short:"f" type:"string" required:"yes" description:"Full path of the file, e.g. lib/class.rb"`
+	Branch        *string `flag_name:"branch" short:"b" type:"string" required:"yes" description:"Name of the branch"`
+	Content       *string `flag_name:"content" short:"c" type:"string" file:"raw" required:"yes" description:"New file content"`
+	CommitMessage *string `flag_name:"commit_message" short:"m" type:"string" required:"yes" description:"Commit message"`
+	Encoding      *string `flag_name:"encoding" short:"e" type:"string" choices:"text,base64" required:"no" description:"Encoding of the content, binary content is base64 encoded if no encoding is given"`
+	AuthorEmail   *string `flag_name:"author_email" type:"string" required:"no" description:"Specify the commit author's email address"`
+	AuthorName    *string `flag_name:"author_name" type:"string" required:"no" description:"Specify the commit author's name"`
+	LastCommitID  *string `flag_name:"last_commit_id" short:"l" type:"string" required:"no" description:"Last known file commit id, the update fails if the file was changed since"`
+}
+
+var filesUpdateCmd = &golabCommand{
+	Parent: filesCmd.Cmd,
+	Flags:  &filesUpdateFlags{},
+	Opts:   &gitlab.UpdateFileOptions{},
+	Cmd: &cobra.Command{
+		Use:   "update",
+		Short: "Update existing file in repository",
+		Long:  `Update an existing file in the repository with a commit on the given branch.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*filesUpdateFlags)
+		opts := cmd.Opts.(*gitlab.UpdateFileOptions)
+		opts.Content, opts.Encoding = encodeContent(*opts.Content, opts.Encoding)
+		file, _, err := gitlabClient.RepositoryFiles.UpdateFile(parsePid(*flags.Id), *flags.FilePath, opts)
+		if err != nil {
+			return err
+		}
+		return Output(file)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/repository_files.html#delete-existing-file-in-repository
+type filesDeleteFlags struct {
+	Id            *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	FilePath      *string `flag_name:"file_path" short:"f" type:"string" required:"yes" description:"Full path of the file, e.g. lib/class.rb"`
+	Branch        *string `flag_name:"branch" short:"b" type:"string" required:"yes" description:"Name of the branch"`
+	CommitMessage *string `flag_name:"commit_message" short:"m" type:"string" required:"yes" description:"Commit message"`
+	AuthorEmail   *string `flag_name:"author_email" type:"string" required:"no" description:"Specify the commit author's email address"`
+	AuthorName    *string `flag_name:"author_name" type:"string" required:"no" description:"Specify the commit author's name"`
+}
+
+var filesDeleteCmd = &golabCommand{
+	Parent: filesCmd.Cmd,
+	Flags:  &filesDeleteFlags{},
+	Opts:   &gitlab.DeleteFileOptions{},
+	Cmd: &cobra.Command{
+		Use:   "delete",
+		Short: "Delete existing file in repository",
+		Long:  `Delete a file from the repository with a commit on the given branch.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*filesDeleteFlags)
+		opts := cmd.Opts.(*gitlab.DeleteFileOptions)
+		_, err := gitlabClient.RepositoryFiles.DeleteFile(parsePid(*flags.Id), *flags.FilePath, opts)
+		return err
+	},
+}
+
+// encodeContent base64 encodes content that is not valid UTF-8 and thus cannot be sent as JSON string,
+// unless an encoding was given explicitly
+func encodeContent(content string, encoding *string) (*string, *string) {
+	if encoding != nil || utf8.ValidString(content) {
+		return &content, encoding
+	}
+	encoded, base64Encoding := fileContent([]byte(content))
+	return &encoded, &base64Encoding
+}
+
+// fileContent returns the content of a file as it is sent to Gitlab: plain text if it is valid UTF-8, base64 otherwise
+func fileContent(content []byte) (string, string) {
+	if utf8.Valid(content) {
+		return string(content), "text"
+	}
+	return base64.StdEncoding.EncodeToString(content), "base64"
+}
+
+// see https://docs.gitlab.com/ce/api/commits.html#create-a-commit-with-multiple-files-and-actions
+type filesPushFlags struct {
+	Id          *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Dir         *string `flag_name:"dir" short:"d" type:"string" required:"yes" description:"Local directory with the files to push"`
+	Branch      *string `flag_name:"branch" short:"b" type:"string" required:"yes" description:"Name of the branch to commit to"`
+	Message     *string `flag_name:"message" short:"m" type:"string" required:"yes" description:"Commit message"`
+	Path        *string `flag_name:"path" short:"p" type:"string" required:"no" description:"Directory in the repository to push the files to (default: the root of the repository)"`
+	Delete      *bool   `flag_name:"delete" type:"boolean" required:"no" description:"Delete files in the repository directory that do not exist in the local directory"`
+	AuthorEmail *string `flag_name:"author_email" type:"string" required:"no" description:"Specify the commit author's email address"`
+	AuthorName  *string `flag_name:"author_name" type:"string" required:"no" description:"Specify the commit author's name"`
+}
+
+var filesPushCmd = &golabCommand{
+	Parent: filesCmd.Cmd,
+	Flags:  &filesPushFlags{},
+	Cmd: &cobra.Command{
+		Use:   "push",
+		Short: "Push a local directory in a single commit",
+		Long: `Compares the files of a local directory with the files of a directory in the repository and creates a single commit
+on the given branch that creates all new and updates all changed files. Files that only exist in the repository are
+deleted with --delete. Nothing is committed if there are no changes, .git directories are skipped.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*filesPushFlags)
+		pid := parsePid(*flags.Id)
+		prefix := ""
+		if flags.Path != nil {
+			prefix = strings.Trim(*flags.Path, "/")
+		}
+		local, err := localFiles(*flags.Dir)
+		if err != nil {
+			return err
+		}
+		remote, err := remoteBlobs(pid, *flags.Branch, prefix)
+		if err != nil {
+			return err
+		}
+		actions := planPush(local, remote, prefix, flags.Delete != nil && *flags.Delete)
+		if len(actions) == 0 {
+			fmt.Fprintf(os.Stderr, "nothing to push, %s is up to date\n", *flags.Branch)
+			return nil
+		}
+		commit, _, err := gitlabClient.Commits.CreateCommit(pid, &gitlab.CreateCommitOptions{
+			Branch:        flags.Branch,
+			CommitMessage: flags.Message,
+			Actions:       actions,
+			AuthorEmail:   flags.AuthorEmail,
+			AuthorName:    flags.AuthorName,
+		})
+		if err != nil {
+			return err
+		}
+		return Output(commit)
+	},
+}
+
+// localFiles reads the files below dir by their slash separated path relative to dir, .git directories and
+// everything that is not a regular file (e.g. symlinks) are skipped
+func localFiles(dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	return files, err
+}
+
+// remoteBlobs returns the blob IDs of the files below prefix on the branch by their path relative to prefix,
+// a missing directory has no files
+func remoteBlobs(pid interface{}, branch string, prefix string) (map[string]string, error) {
+	recursive := true
+	opts := &gitlab.ListTreeOptions{Ref: &branch, Recursive: &recursive}
+	if prefix != "" {
+		opts.Path = &prefix
+	}
+	nodes, resp, err := allTreeNodes(pid, opts)
+	if isNotFound(resp) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	blobs := map[string]string{}
+	for _, node := range nodes {
+		if node.Type == "blob" {
+			blobs[strings.TrimPrefix(node.Path, prefix+"/")] = node.ID
+		}
+	}
+	return blobs, nil
+}
+
+// planPush returns the actions that make the files below prefix in the repository equal to the local files, sorted
+// by path, files are compared by their git blob ID and files that only exist in the repository are deleted with remove
+func planPush(local map[string][]byte, remote map[string]string, prefix string, remove bool) []*gitlab.CommitAction {
+	actions := []*gitlab.CommitAction{}
+	for file, content := range local {
+		blob, exists := remote[file]
+		if exists && blob == gitBlobId(content) {
+			continue
+		}
+		action := &gitlab.CommitAction{Action: gitlab.FileCreate, FilePath: path.Join(prefix, file)}
+		if exists {
+			action.Action = gitlab.FileUpdate
+		}
+		action.Content, action.Encoding = fileContent(content)
+		actions = append(actions, action)
+	}
+	if remove {
+		for file := range remote {
+			if _, exists := local[file]; !exists {
+				actions = append(actions, &gitlab.CommitAction{Action: gitlab.FileDelete, FilePath: path.Join(prefix, file)})
+			}
+		}
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].FilePath < actions[j].FilePath
+	})
+	return actions
+}
+
+// gitBlobId returns the SHA-1 git uses for a blob with the given content, as returned for files of a repository tree
+func gitBlobId(content []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(append([]byte(fmt.Sprintf("blob %d\x00", len(content))), content...)))
+}
+
+func init() {
+	filesCmd.Init()
+	filesGetCmd.Init()
+	filesRawCmd.Init()
+	filesCreateCmd.Init()
+	filesUpdateCmd.Init()
+	filesDeleteCmd.Init()
+	filesPushCmd.Init()
+}
@@ -0,0 +1,102 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("files", func() {
+
+	It("computes the blob ID git uses for a file", func() {
+		// git hash-object of a file containing "hello\n"
+		Expect(gitBlobId([]byte("hello\n"))).To(Equal("ce013625030ba8dba906f756967f9e9ca394464a"))
+	})
+
+	It("base64 encodes content that is not valid UTF-8 unless an encoding is given", func() {
+		content, encoding := encodeContent("plain", nil)
+		Expect(*content).To(Equal("plain"))
+		Expect(encoding).To(BeNil())
+
+		content, encoding = encodeContent("\x00\xff", nil)
+		Expect(*content).To(Equal("AP8="))
+		Expect(*encoding).To(Equal("base64"))
+
+		text := "text"
+		content, encoding = encodeContent("\x00\xff", &text)
+		Expect(*content).To(Equal("\x00\xff"))
+		Expect(*encoding).To(Equal("text"))
+	})
+
+	It("reads local files by their relative path and skips .git directories", func() {
+		dir, err := ioutil.TempDir("", "golab-files")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+		Expect(os.MkdirAll(filepath.Join(dir, "sub", ".git"), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, ".gitlab-ci.yml"), []byte("ci"), 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "sub", "file.txt"), []byte("file"), 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "sub", ".git", "HEAD"), []byte("ref"), 0600)).To(Succeed())
+
+		files, err := localFiles(dir)
+
+		Expect(err).To(BeNil())
+		Expect(files).To(Equal(map[string][]byte{".gitlab-ci.yml": []byte("ci"), "sub/file.txt": []byte("file")}))
+	})
+
+	Describe("planPush", func() {
+
+		local := map[string][]byte{
+			"same.txt":    []byte("same"),
+			"changed.txt": []byte("new"),
+			"dir/new.bin": {0x00, 0xff},
+		}
+		remote := map[string]string{
+			"same.txt":    gitBlobId([]byte("same")),
+			"changed.txt": gitBlobId([]byte("old")),
+			"gone.txt":    gitBlobId([]byte("gone")),
+		}
+
+		It("creates new and updates changed files below the prefix", func() {
+			Expect(planPush(local, remote, "templates", false)).To(Equal([]*gitlab.CommitAction{
+				{Action: gitlab.FileUpdate, FilePath: "templates/changed.txt", Content: "new", Encoding: "text"},
+				{Action: gitlab.FileCreate, FilePath: "templates/dir/new.bin", Content: "AP8=", Encoding: "base64"},
+			}))
+		})
+
+		It("deletes files that only exist in the repository with remove", func() {
+			Expect(planPush(local, remote, "", true)).To(Equal([]*gitlab.CommitAction{
+				{Action: gitlab.FileUpdate, FilePath: "changed.txt", Content: "new", Encoding: "text"},
+				{Action: gitlab.FileCreate, FilePath: "dir/new.bin", Content: "AP8=", Encoding: "base64"},
+				{Action: gitlab.FileDelete, FilePath: "gone.txt"},
+			}))
+		})
+
+		It("returns no actions if nothing changed", func() {
+			Expect(planPush(map[string][]byte{"same.txt": []byte("same")}, remote, "", false)).To(BeEmpty())
+		})
+	})
+})
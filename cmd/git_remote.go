@@ -0,0 +1,195 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// remoteName holds the value of the global `--remote` flag
+var remoteName string
+
+const defaultRemote = "origin"
+
+// projectFromGitRemote returns the `namespace/project` path of the git remote of the repository in the working directory.
+// An empty path without error is returned, if the working directory is not inside a git repository or the remote
+// does not exist and was not explicitly requested with `--remote`.
+func projectFromGitRemote() (string, error) {
+	name := remoteName
+	if name == "" {
+		name = defaultRemote
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	gitConfig, err := findGitConfig(dir)
+	if err != nil {
+		return "", err
+	}
+	if gitConfig == "" {
+		if remoteName != "" {
+			return "", errors.New("--remote given, but the working directory is not inside a git repository")
+		}
+		return "", nil
+	}
+	remotes, err := readGitRemotes(gitConfig)
+	if err != nil {
+		return "", err
+	}
+	remoteUrl, ok := remotes[name]
+	if !ok {
+		if remoteName != "" {
+			return "", fmt.Errorf("git remote '%s' does not exist in %s", name, gitConfig)
+		}
+		return "", nil
+	}
+	return projectFromRemoteUrl(remoteUrl, viper.GetString("url"))
+}
+
+// findGitConfig walks up from dir and returns the path of the `config` file of the first git repository found
+func findGitConfig(dir string) (string, error) {
+	for {
+		gitPath := filepath.Join(dir, ".git")
+		info, err := os.Stat(gitPath)
+		if err == nil {
+			if info.IsDir() {
+				return filepath.Join(gitPath, "config"), nil
+			}
+			return gitConfigOfWorktree(gitPath)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// gitConfigOfWorktree resolves a `.git` file (as used by worktrees and submodules) that points to the actual git directory
+func gitConfigOfWorktree(gitFile string) (string, error) {
+	content, err := ioutil.ReadFile(gitFile)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", fmt.Errorf("could not parse %s", gitFile)
+	}
+	gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(gitFile), gitDir)
+	}
+	// worktrees share the config of the main repository
+	if common, err := ioutil.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir := strings.TrimSpace(string(common))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+		gitDir = commonDir
+	}
+	return filepath.Join(gitDir, "config"), nil
+}
+
+// readGitRemotes returns the URLs of all remotes of a git config file by remote name
+func readGitRemotes(gitConfig string) (map[string]string, error) {
+	file, err := os.Open(gitConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	remotes := map[string]string{}
+	remote := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			remote = ""
+			section := strings.Trim(line, "[]")
+			if strings.HasPrefix(section, "remote ") {
+				remote = strings.Trim(strings.TrimSpace(strings.TrimPrefix(section, "remote ")), `"`)
+			}
+			continue
+		}
+		if remote == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "url" {
+			if _, exists := remotes[remote]; !exists {
+				remotes[remote] = strings.Trim(strings.TrimSpace(parts[1]), `"`)
+			}
+		}
+	}
+	return remotes, scanner.Err()
+}
+
+// projectFromRemoteUrl returns the `namespace/project` path of an ssh, scp-like or http(s) remote URL,
+// if the remote belongs to the Gitlab server at gitlabUrl
+func projectFromRemoteUrl(remoteUrl string, gitlabUrl string) (string, error) {
+	gitlab, err := url.Parse(gitlabUrl)
+	if err != nil || gitlab.Hostname() == "" {
+		return "", fmt.Errorf("cannot match git remote %s, no valid Gitlab url configured", remoteUrl)
+	}
+
+	var host, path string
+	if strings.Contains(remoteUrl, "://") {
+		remote, err := url.Parse(remoteUrl)
+		if err != nil {
+			return "", fmt.Errorf("could not parse git remote %s: %s", remoteUrl, err)
+		}
+		host, path = remote.Hostname(), remote.Path
+		if remote.Scheme == "http" || remote.Scheme == "https" {
+			// Gitlab might be served from a sub-directory, e.g. https://example.com/gitlab
+			path = strings.TrimPrefix(path, strings.TrimSuffix(gitlab.Path, "/"))
+		}
+	} else if i := strings.Index(remoteUrl, ":"); i > 0 {
+		// scp-like syntax, e.g. git@gitlab.com:namespace/project.git
+		host, path = remoteUrl[:i], remoteUrl[i+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	} else {
+		return "", fmt.Errorf("git remote %s is not a remote URL", remoteUrl)
+	}
+
+	if !strings.EqualFold(host, gitlab.Hostname()) {
+		return "", fmt.Errorf("git remote %s does not belong to the configured Gitlab host %s, use --id or choose another remote with --remote", remoteUrl, gitlab.Hostname())
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if !strings.Contains(path, "/") {
+		return "", fmt.Errorf("could not determine namespace and project from git remote %s", remoteUrl)
+	}
+	return path, nil
+}
@@ -28,9 +28,10 @@ import (
 )
 
 var groupCmd = &cobra.Command{
-	Use:   "group",
-	Short: "Manage Gitlab Groups",
-	Long:  `Show, create, update and delete Gitlab groups.`,
+	Use:         "group",
+	Short:       "Manage Gitlab Groups",
+	Long:        `Show, create, update and delete Gitlab groups.`,
+	Annotations: map[string]string{idResourceAnnotation: "group"},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return errors.New("check usage of group with `golab group -h`")
 	},
@@ -41,8 +42,8 @@ type groupLsFlags struct {
 	SkipGroups   *[]string `flag_name:"skip_groups" type:"array" required:"no" description:"Skip the group IDs passed"`
 	AllAvailable *bool     `flag_name:"all_available" type:"bool" required:"no" description:"Show all the groups you have access to (defaults to false for authenticated users)"`
 	Search       *string   `flag_name:"search" type:"string" required:"no" description:"Return the list of authorized groups matching the search criteria"`
-	OrderBy      *string   `flag_name:"order_by" type:"string" required:"no" description:"Order groups by name or path. Default is name"`
-	Sort         *string   `flag_name:"sort" type:"string" required:"no" description:"Order groups in asc or desc order. Default is asc"`
+	OrderBy      *string   `flag_name:"order_by" type:"string" choices:"name,path" required:"no" description:"Order groups by name or path. Default is name"`
+	Sort         *string   `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"Order groups in asc or desc order. Default is asc"`
 	Statistics   *bool     `flag_name:"statistics" type:"bool" required:"no" description:"Include group statistics (admins only)"`
 	Owned        *bool     `flag_name:"owned" type:"boolean" required:"no" description:"Limit to groups owned by the current user"`
 }
@@ -57,21 +58,20 @@ var groupLsCmd = &golabCommand{
 		Long:  `Get a list of visible groups for the authenticated user.`,
 	},
 	Run: func(cmd golabCommand) error {
-		groups, _, err := gitlabClient.Groups.ListGroups(cmd.Opts.(*gitlab.ListGroupsOptions))
-		if err != nil {
-			return err
-		}
-		return OutputJson(groups)
+		opts := cmd.Opts.(*gitlab.ListGroupsOptions)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Groups.ListGroups(opts, page)
+		})
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/groups.html#list-a-group-39-s-projects
 type listGroupProjectsFlags struct {
-	Id         *string `flag_name:"id" type:"integer/string" required:"yes" description:"The ID or URL-encoded path of the group owned by the authenticated user"`
+	Id         *string `flag_name:"id" type:"integer/string" env:"GOLAB_GROUP" required:"yes" description:"The ID or URL-encoded path of the group owned by the authenticated user"`
 	Archived   *bool   `flag_name:"archived" type:"bool" required:"no" description:"Limit by archived status"`
-	Visibility *string `flag_name:"visibility" type:"string" transform:"str2Visibility" required:"no" description:"Limit by visibility public, internal, or private"`
-	OrderBy    *string `flag_name:"order_by" type:"string" required:"no" description:"Return projects ordered by id, name, path, created_at, updated_at, or last_activity_at fields. Default is created_at"`
-	Sort       *string `flag_name:"sort" type:"string" required:"no" description:"Return projects sorted in asc or desc order. Default is desc"`
+	Visibility *string `flag_name:"visibility" type:"string" choices:"private,internal,public" transform:"str2Visibility" required:"no" description:"Limit by visibility public, internal, or private"`
+	OrderBy    *string `flag_name:"order_by" type:"string" choices:"id,name,path,created_at,updated_at,last_activity_at" required:"no" description:"Return projects ordered by id, name, path, created_at, updated_at, or last_activity_at fields. Default is created_at"`
+	Sort       *string `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"Return projects sorted in asc or desc order. Default is desc"`
 	Search     *string `flag_name:"search" type:"string" required:"no" description:"Return list of authorized projects matching the search criteria"`
 	Simple     *bool   `flag_name:"simple" type:"bool" required:"no" description:"Return only the ID, URL, name, and path of each project"`
 	Owned      *bool   `flag_name:"owned" type:"bool" required:"no" description:"Limit by projects owned by the current user"`
@@ -89,17 +89,16 @@ var groupProjectsCmd = &golabCommand{
 	},
 	Run: func(cmd golabCommand) error {
 		flags := cmd.Flags.(*listGroupProjectsFlags)
-		projects, _, err := gitlabClient.Groups.ListGroupProjects(*flags.Id, cmd.Opts.(*gitlab.ListGroupProjectsOptions))
-		if err != nil {
-			return err
-		}
-		return OutputJson(projects)
+		opts := cmd.Opts.(*gitlab.ListGroupProjectsOptions)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Groups.ListGroupProjects(*flags.Id, opts, page)
+		})
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/groups.html#details-of-a-group
 type groupGetFlags struct {
-	Id *string `flag_name:"id" type:"integer/string" required:"yes" description:"The ID or URL-encoded path of the group owned by the authenticated user"`
+	Id *string `flag_name:"id" type:"integer/string" env:"GOLAB_GROUP" required:"yes" description:"The ID or URL-encoded path of the group owned by the authenticated user"`
 }
 
 var groupGetCmd = &golabCommand{
@@ -116,7 +115,7 @@ var groupGetCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(group)
+		return Output(group)
 	},
 }
 
@@ -124,8 +123,8 @@ var groupGetCmd = &golabCommand{
 type groupCreateFlags struct {
 	Name                 *string `flag_name:"name" short:"n" type:"string" required:"yes" description:"The name of the group"`
 	Path                 *string `flag_name:"path" short:"p" type:"string" required:"yes" description:"The path of the group"`
-	Description          *string `flag_name:"description" type:"string" required:"no" description:"The group's description"`
-	Visibility           *string `flag_name:"visibility" type:"string" transform:"str2Visibility" required:"no" description:"The group's visibility. Can be private, internal, or public."`
+	Description          *string `flag_name:"description" type:"string" file:"raw" required:"no" description:"The group's description"`
+	Visibility           *string `flag_name:"visibility" type:"string" choices:"private,internal,public" transform:"str2Visibility" required:"no" description:"The group's visibility. Can be private, internal, or public."`
 	LfsEnabled           *bool   `flag_name:"lfs_enabled" type:"bool" required:"no" description:"Enable/disable Large File Storage (LFS) for the projects in this group"`
 	RequestAccessEnabled *bool   `flag_name:"request_access_enabled" type:"bool" required:"no" description:"- Allow users to request member access."`
 	ParentId             *int    `flag_name:"parent_id" type:"int" required:"no" description:"The parent group id for creating nested group."`
@@ -146,13 +145,13 @@ var groupCreateCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(group)
+		return Output(group)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/groups.html#transfer-project-to-group
 type transferProjectFlags struct {
-	Id        *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL-encoded path of the group owned by the authenticated user"`
+	Id        *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_GROUP" required:"yes" description:"The ID or URL-encoded path of the group owned by the authenticated user"`
 	ProjectId *int    `flag_name:"project_id" short:"p" type:"string" required:"yes" description:"The ID or URL-encoded path of a project"`
 	// TODO go-gitlab does not support ID or URL-encoded path here
 	// ProjectId *string `flag_name:"project_id" short:"p" type:"string" required:"yes" description:"The ID or URL-encoded path of a project"`
@@ -173,7 +172,7 @@ var transferProjectCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(group)
+		return Output(group)
 	},
 }
 
@@ -182,8 +181,8 @@ type groupUpdateFlags struct {
 	Id                   *int    `flag_name:"id" type:"integer" required:"yes" description:"The ID of the group"`
 	Name                 *string `flag_name:"name" type:"string" required:"no" description:"The name of the group"`
 	Path                 *string `flag_name:"path" type:"string" required:"no" description:"The path of the group"`
-	Description          *string `flag_name:"description" type:"string" required:"no" description:"The description of the group"`
-	Visibility           *string `flag_name:"visibility" type:"string" transform:"str2Visibility" required:"no" description:"The visibility level of the group. Can be private, internal, or public."`
+	Description          *string `flag_name:"description" type:"string" file:"raw" required:"no" description:"The description of the group"`
+	Visibility           *string `flag_name:"visibility" type:"string" choices:"private,internal,public" transform:"str2Visibility" required:"no" description:"The visibility level of the group. Can be private, internal, or public."`
 	LfsEnabled           *bool   `flag_name:"lfs_enabled" type:"boolean" required:"no" description:"Enable/disable Large File Storage (LFS) for the projects in this group"`
 	RequestAccessEnabled *bool   `flag_name:"request_access_enabled" type:"boolean" required:"no" description:"Allow users to request member access."`
 }
@@ -204,13 +203,13 @@ var groupUpdateCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(group)
+		return Output(group)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/groups.html#remove-group
 type groupDeleteFlags struct {
-	Id *string `flag_name:"id" type:"string" required:"yes" description:"The ID or URL encoded path of a user group"`
+	Id *string `flag_name:"id" type:"string" env:"GOLAB_GROUP" required:"yes" description:"The ID or URL encoded path of a user group"`
 }
 
 var groupDeleteCmd = &golabCommand{
@@ -218,9 +217,10 @@ var groupDeleteCmd = &golabCommand{
 	Flags:  &groupDeleteFlags{},
 	Opts:   nil,
 	Cmd: &cobra.Command{
-		Use:   "delete",
-		Short: "Remove group",
-		Long:  `Removes group with all projects inside.`,
+		Use:         "delete",
+		Short:       "Remove group",
+		Annotations: map[string]string{explicitIdAnnotation: ""},
+		Long:        `Removes group with all projects inside. The group is not taken from the context, --id (or $GOLAB_GROUP) must be given.`,
 	},
 	Run: func(cmd golabCommand) error {
 		flags := cmd.Flags.(*groupDeleteFlags)
@@ -249,13 +249,15 @@ var groupSearchCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(groups)
+		return Output(groups)
 	},
 }
 
 func init() {
 	groupLsCmd.Init()
+	initPaginationFlags(groupLsCmd.Cmd)
 	groupProjectsCmd.Init()
+	initPaginationFlags(groupProjectsCmd.Cmd)
 	groupGetCmd.Init()
 	groupCreateCmd.Init()
 	transferProjectCmd.Init()
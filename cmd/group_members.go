@@ -23,20 +23,20 @@ package cmd
 import (
 	"errors"
 
+	"github.com/michaellihs/golab/cmd/mapper"
 	"github.com/spf13/cobra"
 	"github.com/xanzy/go-gitlab"
 )
 
-var accessLevel, source, target int
+var accessLevel int
 
 var expiresAt string
 
-var remove bool
-
 var groupMembersCmd = &cobra.Command{
 	Use: "group-members",
 	Short: "Access group members",
 	Long: `Show members and access level of groups`,
+	Annotations: map[string]string{idResourceAnnotation: "group"},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return errors.New("check usage of `group-members` with `golab group-members -h`")
 	},
@@ -50,12 +50,9 @@ var groupMembersLsCmd = &cobra.Command{
 		if id == 0 {
 			return errors.New("required parameter `-i` or `--id`not given - exiting")
 		}
-		opts := &gitlab.ListGroupMembersOptions{
-			ListOptions: gitlab.ListOptions{Page: 1, PerPage: 1000},
-		}
-		members, _, err := gitlabClient.Groups.ListGroupMembers(id, opts)
-		if err != nil { return err }
-		return OutputJson(members)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Groups.ListGroupMembers(id, &gitlab.ListGroupMembersOptions{}, page)
+		})
 	},
 }
 
@@ -74,7 +71,7 @@ var groupMemberGetCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return OutputJson(member)
+		return Output(member)
 	},
 }
 
@@ -100,16 +97,18 @@ var groupMemberAddCmd = &cobra.Command{
 		if accessLevel == 0 {
 			return errors.New("required parameter `-a` or `--access_level` not given - exiting")
 		}
+		level, err := int2AccessLevel(accessLevel)
+		if err != nil { return err }
 		opts := &gitlab.AddGroupMemberOptions{
 			UserID:      &userId,
-			AccessLevel: int2AccessLevel(accessLevel),
+			AccessLevel: level,
 		}
 		if expiresAt != "" {
 			opts.ExpiresAt = &expiresAt
 		}
 		member, _, err := gitlabClient.GroupMembers.AddGroupMember(id, opts)
 		if err != nil { return err }
-		return OutputJson(member)
+		return Output(member)
 	},
 }
 
@@ -135,15 +134,17 @@ var groupMemberEditCmd = &cobra.Command{
 		if accessLevel == 0 {
 			return errors.New("required parameter `-a` or `-access_level` not given - exiting")
 		}
+		level, err := int2AccessLevel(accessLevel)
+		if err != nil { return err }
 		opts := &gitlab.EditGroupMemberOptions{
-			AccessLevel: int2AccessLevel(accessLevel),
+			AccessLevel: level,
 		}
 		if expiresAt != "" {
 			opts.ExpiresAt = &expiresAt
 		}
 		member, _, err := gitlabClient.GroupMembers.EditGroupMember(id, userId, opts)
 		if err != nil { return err }
-		return OutputJson(member)
+		return Output(member)
 	},
 }
 
@@ -163,85 +164,58 @@ var groupMemberDeleteCmd = &cobra.Command{
 	},
 }
 
-var groupMemberSyncCmd = &cobra.Command{
-	Use: "sync",
-	Short: "Synchronizes members of 2 groups",
-	Long: `Synchronizes the members of 2 groups, by either
+type groupMemberSyncFlags struct {
+	Source             *string `flag_name:"source" short:"s" type:"string" required:"yes" description:"The ID or path of the group to copy members from"`
+	Target             *string `flag_name:"target" short:"t" type:"string" env:"GOLAB_GROUP" required:"yes" description:"The ID or path of the group to copy members to"`
+	UpdateAccessLevels *bool   `flag_name:"update-access-levels" type:"bool" required:"no" description:"Update access level and expiry date of members that exist in both groups"`
+	Remove             *bool   `flag_name:"remove" short:"r" type:"bool" required:"no" description:"Remove members in target group that don't exist in source group"`
+}
 
-* merging them (default) - members that exist in target group but not in source group are kept
-* removing them (--remove) - members that exist in target group but not in source group are deleted`,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		if source == 0 {
-			return errors.New("required parameter `--source` not given - exiting")
-		}
-		if target == 0 {
-			return errors.New("required parameter `--target` not given - exiting")
-		}
+var groupMemberSyncCmd = &golabCommand{
+	Parent: groupMembersCmd,
+	Flags:  &groupMemberSyncFlags{},
+	Cmd: &cobra.Command{
+		Use:   "sync",
+		Short: "Synchronizes members of 2 groups",
+		Long: `Synchronizes the members of 2 groups, by either
 
-		opts := &gitlab.ListGroupMembersOptions{
-			ListOptions: gitlab.ListOptions{Page: 1, PerPage: 1000},
-		}
+* merging them (default) - members that exist in target group but not in source group are kept
+* removing them (--remove) - members that exist in target group but not in source group are deleted
 
-		createNonExistingTargetUsers(source, target, opts)
+Members that are missing in the target group are added with the access level and expiry date they have in the
+source group. With --update-access-levels, members of both groups get the access level and expiry date of the
+source group, otherwise they are not changed.
 
-		if remove {
-			err := removeTargetMembers(target, source, opts)
-			if err != nil { return err }
-		}
+The changes are printed as a list with the action (add, update or remove) and status (applied, failed or skipped)
+for every member, also if a change failed. With --dry-run they
+are shown without changing the target group:
 
-		members, _, err := gitlabClient.Groups.ListGroupMembers(target, opts)
-		if err != nil { return err }
-		return OutputJson(members)
+    golab group-members sync --source my-group --target my-group/subgroup --remove --dry-run -o table`,
 	},
-}
-
-func createNonExistingTargetUsers(source int, target int, opts *gitlab.ListGroupMembersOptions) error {
-	sourceMembers, _, err := gitlabClient.Groups.ListGroupMembers(source, opts)
-	if err != nil { return err }
-	for _, sourceMember := range sourceMembers  {
-		_, resp, err := gitlabClient.GroupMembers.GetGroupMember(target, sourceMember.ID)
-		if resp.StatusCode == 404 {   // 404 means "Not Found" --> does not exist yet
-			newMemberOpts := &gitlab.AddGroupMemberOptions{
-				UserID: &sourceMember.ID,
-				AccessLevel: &sourceMember.AccessLevel,
-			}
-			if sourceMember.ExpiresAt != nil {
-				expires, err := isoTime2String(sourceMember.ExpiresAt)
-				if err != nil { return err }
-				newMemberOpts.ExpiresAt = &expires
-			}
-			gitlabClient.GroupMembers.AddGroupMember(target, newMemberOpts)
-		} else if err != nil {
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*groupMemberSyncFlags)
+		source, err := liveGroupMembers(*flags.Source)
+		if err != nil {
 			return err
 		}
-	}
-	return nil
-}
-
-func removeTargetMembers(target int, source int, opts *gitlab.ListGroupMembersOptions) error {
-	targetMembers, _, err := gitlabClient.Groups.ListGroupMembers(target, opts)
-	if err != nil { return err }
-	for _, targetMember := range targetMembers {
-		_, resp, err := gitlabClient.GroupMembers.GetGroupMember(source, targetMember.ID)
-		if resp.StatusCode == 404 {
-			_, err := gitlabClient.GroupMembers.RemoveGroupMember(target, targetMember.ID)
-			if err != nil { return err }
-		} else if err != nil {
+		target, err := liveGroupMembers(*flags.Target)
+		if err != nil {
 			return err
 		}
-	}
-	return nil
+		return syncMembers(source, target, groupMemberOps(*flags.Target), memberSyncOptions{
+			update: flags.UpdateAccessLevels != nil && *flags.UpdateAccessLevels,
+			remove: flags.Remove != nil && *flags.Remove,
+		})
+	},
 }
 
-func int2AccessLevel(accessLevel int) *gitlab.AccessLevelValue {
-	switch accessLevel {
-	case 10: return gitlab.AccessLevel(gitlab.GuestPermissions)
-	case 20: return gitlab.AccessLevel(gitlab.ReporterPermissions)
-	case 30: return gitlab.AccessLevel(gitlab.DeveloperPermissions)
-	case 40: return gitlab.AccessLevel(gitlab.MasterPermissions)
-	case 50: return gitlab.AccessLevel(gitlab.OwnerPermission)
-	default: panic("Unrecognized value for AccessLevel")
+// int2AccessLevel returns the access level given with --access_level, a *mapper.ValidationError for unknown levels
+func int2AccessLevel(accessLevel int) (*gitlab.AccessLevelValue, error) {
+	level, err := mapper.AccessLevel(accessLevel)
+	if validationErr, ok := err.(*mapper.ValidationError); ok {
+		validationErr.Flag = "access_level"
 	}
+	return level, err
 }
 
 func init() {
@@ -250,12 +224,13 @@ func init() {
 	initGroupMemberAddCmd()
 	initGroupMemberUpdateCmd()
 	initGroupMemberDeleteCmd()
-	initGroupMemberSyncCmd()
+	groupMemberSyncCmd.Init()
 	RootCmd.AddCommand(groupMembersCmd)
 }
 
 func initGroupMembersLsCmd() {
 	groupMembersLsCmd.PersistentFlags().IntVarP(&id, "id", "i", 0, "(required) id of group to show members for")
+	initPaginationFlags(groupMembersLsCmd)
 	groupMembersCmd.AddCommand(groupMembersLsCmd)
 }
 
@@ -286,10 +261,3 @@ func initGroupMemberDeleteCmd() {
 	groupMemberDeleteCmd.PersistentFlags().IntVarP(&userId, "user_id", "u", 0, "(required) the id of the user to be removed from group")
 	groupMembersCmd.AddCommand(groupMemberDeleteCmd)
 }
-
-func initGroupMemberSyncCmd() {
-	groupMemberSyncCmd.PersistentFlags().IntVarP(&source, "source", "s", 0, "(required) id of group to copy members from")
-	groupMemberSyncCmd.PersistentFlags().IntVarP(&target, "target", "t", 0, "(required) id of group to copy members to")
-	groupMemberSyncCmd.PersistentFlags().BoolVarP(&remove, "remove", "r", false, "(optional) remove members in target group that don't exist in source group")
-	groupMembersCmd.AddCommand(groupMemberSyncCmd)
-}
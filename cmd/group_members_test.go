@@ -25,6 +25,7 @@ import (
 	"net/http/httptest"
 	"net/http"
 
+	"github.com/michaellihs/golab/cmd/mapper"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/xanzy/go-gitlab"
@@ -39,6 +40,11 @@ var _ = Describe("int2AccessLevel", func() {
 		Expect(int2AccessLevel(40)).To(Equal(gitlab.AccessLevel(gitlab.MasterPermissions)))
 		Expect(int2AccessLevel(50)).To(Equal(gitlab.AccessLevel(gitlab.OwnerPermission)))
 	})
+
+	It("returns an error for unknown access levels", func() {
+		_, err := int2AccessLevel(35)
+		Expect(err).To(MatchError("invalid value '35' for --access_level: expected " + mapper.AccessLevels))
+	})
 })
 
 var _ = Describe("group-members command", func() {
@@ -0,0 +1,419 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/xanzy/go-gitlab"
+)
+
+// see https://docs.gitlab.com/ce/api/issues.html
+var issuesCmd = &cobra.Command{
+	Use:         "issues",
+	Aliases:     []string{"issue"},
+	Short:       "Manage Issues",
+	Long:        `Show, create, edit, close and delete Issues`,
+	Annotations: map[string]string{idResourceAnnotation: "project"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("this command cannot be run without a sub-command")
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/issues.html#list-issues
+type issuesListFlags struct {
+	State           *string `flag_name:"state" type:"string" choices:"opened,closed" required:"no" description:"Return all issues or just those that are opened or closed"`
+	Labels          *string `flag_name:"labels" type:"[]string" transform:"string2Labels" required:"no" description:"Comma-separated list of label names, issues must have all labels to be returned. No+Label lists all issues with no labels"`
+	Milestone       *string `flag_name:"milestone" type:"string" required:"no" description:"The milestone title"`
+	Scope           *string `flag_name:"scope" type:"string" choices:"created-by-me,assigned-to-me,all" required:"no" description:"Return issues for the given scope: created-by-me, assigned-to-me or all. Defaults to created-by-me"`
+	AuthorID        *int    `flag_name:"author_id" type:"integer" required:"no" description:"Return issues created by the given user id. Combine with scope=all or scope=assigned-to-me"`
+	AssigneeID      *int    `flag_name:"assignee_id" type:"integer" required:"no" description:"Return issues assigned to the given user id"`
+	MyReactionEmoji *string `flag_name:"my_reaction_emoji" type:"string" required:"no" description:"Return issues reacted by the authenticated user by the given emoji"`
+	IIDs            []int   `flag_name:"iids" type:"Array[integer]" required:"no" description:"Return only the issues having the given iid"`
+	OrderBy         *string `flag_name:"order_by" type:"string" choices:"created_at,updated_at" required:"no" description:"Return issues ordered by created_at or updated_at fields. Default is created_at"`
+	Sort            *string `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"Return issues sorted in asc or desc order. Default is desc"`
+	Search          *string `flag_name:"search" type:"string" required:"no" description:"Search issues against their title and description"`
+}
+
+var issuesListCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issuesListFlags{},
+	Opts:   &gitlab.ListIssuesOptions{},
+	Cmd: &cobra.Command{
+		Use:   "ls",
+		Short: "List issues",
+		Long:  `Get all issues the authenticated user has access to. By default it returns only issues created by the current user. To get all issues, use parameter scope=all.`,
+	},
+	Run: func(cmd golabCommand) error {
+		opts := cmd.Opts.(*gitlab.ListIssuesOptions)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Issues.ListIssues(opts, page)
+		})
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/issues.html#list-group-issues
+type issuesListForGroupFlags struct {
+	Id              *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_GROUP" required:"yes" description:"The ID or URL-encoded path of the group owned by the authenticated user"`
+	State           *string `flag_name:"state" type:"string" choices:"opened,closed" required:"no" description:"Return all issues or just those that are opened or closed"`
+	Labels          *string `flag_name:"labels" type:"[]string" transform:"string2Labels" required:"no" description:"Comma-separated list of label names, issues must have all labels to be returned. No+Label lists all issues with no labels"`
+	IIDs            []int   `flag_name:"iids" type:"Array[integer]" required:"no" description:"Return only the issues having the given iid"`
+	Milestone       *string `flag_name:"milestone" type:"string" required:"no" description:"The milestone title"`
+	Scope           *string `flag_name:"scope" type:"string" choices:"created-by-me,assigned-to-me,all" required:"no" description:"Return issues for the given scope: created-by-me, assigned-to-me or all"`
+	AuthorID        *int    `flag_name:"author_id" type:"integer" required:"no" description:"Return issues created by the given user id"`
+	AssigneeID      *int    `flag_name:"assignee_id" type:"integer" required:"no" description:"Return issues assigned to the given user id"`
+	MyReactionEmoji *string `flag_name:"my_reaction_emoji" type:"string" required:"no" description:"Return issues reacted by the authenticated user by the given emoji"`
+	OrderBy         *string `flag_name:"order_by" type:"string" choices:"created_at,updated_at" required:"no" description:"Return issues ordered by created_at or updated_at fields. Default is created_at"`
+	Sort            *string `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"Return issues sorted in asc or desc order. Default is desc"`
+	Search          *string `flag_name:"search" type:"string" required:"no" description:"Search group issues against their title and description"`
+}
+
+var issuesListForGroupCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issuesListForGroupFlags{},
+	Opts:   &gitlab.ListGroupIssuesOptions{},
+	Cmd: &cobra.Command{
+		Use:         "group-ls",
+		Short:       "List group issues",
+		Long:        `Get a list of a group's issues.`,
+		Annotations: map[string]string{idResourceAnnotation: "group"},
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*issuesListForGroupFlags)
+		opts := cmd.Opts.(*gitlab.ListGroupIssuesOptions)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Issues.ListGroupIssues(parsePid(*flags.Id), opts, page)
+		})
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/issues.html#list-project-issues
+type issuesListForProjectFlags struct {
+	Id              *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	IIDs            []int   `flag_name:"iids" type:"Array[integer]" required:"no" description:"Return only the issues having the given iid"`
+	State           *string `flag_name:"state" type:"string" choices:"opened,closed" required:"no" description:"Return all issues or just those that are opened or closed"`
+	Labels          *string `flag_name:"labels" type:"[]string" transform:"string2Labels" required:"no" description:"Comma-separated list of label names, issues must have all labels to be returned. No+Label lists all issues with no labels"`
+	Milestone       *string `flag_name:"milestone" type:"string" required:"no" description:"The milestone title"`
+	Scope           *string `flag_name:"scope" type:"string" choices:"created-by-me,assigned-to-me,all" required:"no" description:"Return issues for the given scope: created-by-me, assigned-to-me or all"`
+	AuthorID        *int    `flag_name:"author_id" type:"integer" required:"no" description:"Return issues created by the given user id"`
+	AssigneeID      *int    `flag_name:"assignee_id" type:"integer" required:"no" description:"Return issues assigned to the given user id"`
+	MyReactionEmoji *string `flag_name:"my_reaction_emoji" type:"string" required:"no" description:"Return issues reacted by the authenticated user by the given emoji"`
+	OrderBy         *string `flag_name:"order_by" type:"string" choices:"created_at,updated_at" required:"no" description:"Return issues ordered by created_at or updated_at fields. Default is created_at"`
+	Sort            *string `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"Return issues sorted in asc or desc order. Default is desc"`
+	Search          *string `flag_name:"search" type:"string" required:"no" description:"Search project issues against their title and description"`
+	CreatedAfter    *string `flag_name:"created_after" type:"datetime" transform:"string2Time" required:"no" description:"Return issues created after the given date (YYYY-MM-DD)"`
+	CreatedBefore   *string `flag_name:"created_before" type:"datetime" transform:"string2Time" required:"no" description:"Return issues created before the given date (YYYY-MM-DD)"`
+}
+
+var issuesListForProjectCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issuesListForProjectFlags{},
+	Opts:   &gitlab.ListProjectIssuesOptions{},
+	Cmd: &cobra.Command{
+		Use:   "project-ls",
+		Short: "List project issues",
+		Long:  `Get a list of a project's issues.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*issuesListForProjectFlags)
+		opts := cmd.Opts.(*gitlab.ListProjectIssuesOptions)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Issues.ListProjectIssues(parsePid(*flags.Id), opts, page)
+		})
+	},
+}
+
+// issueFlags are used by all commands that work on a single issue without further parameters
+type issueFlags struct {
+	Id       *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	IssueIid *int    `flag_name:"issue_iid" type:"integer" required:"yes" description:"The internal ID of a project's issue"`
+}
+
+// see https://docs.gitlab.com/ce/api/issues.html#single-issue
+var issuesGetCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issueFlags{},
+	Cmd: &cobra.Command{
+		Use:   "get",
+		Short: "Get single issue",
+		Long:  `Get a single project issue.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*issueFlags)
+		issue, _, err := gitlabClient.Issues.GetIssue(parsePid(*flags.Id), *flags.IssueIid)
+		if err != nil {
+			return err
+		}
+		return Output(issue)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/issues.html#new-issue
+type issuesCreateFlags struct {
+	Id                                 *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Title                              *string `flag_name:"title" short:"t" type:"string" required:"yes" description:"The title of an issue"`
+	Description                        *string `flag_name:"description" short:"d" type:"string" file:"raw" required:"no" description:"The description of an issue"`
+	Confidential                       *bool   `flag_name:"confidential" type:"boolean" required:"no" description:"Set an issue to be confidential. Default is false"`
+	AssigneeIDs                        []int   `flag_name:"assignee_ids" type:"Array[integer]" required:"no" description:"Comma-separated list of the IDs of the users to assign the issue to"`
+	MilestoneID                        *int    `flag_name:"milestone_id" type:"integer" required:"no" description:"The global ID of a milestone to assign the issue to"`
+	Labels                             *string `flag_name:"labels" type:"[]string" transform:"string2Labels" required:"no" description:"Comma-separated label names for an issue"`
+	CreatedAt                          *string `flag_name:"created_at" type:"string" transform:"string2Time" required:"no" description:"Date when the issue was created (YYYY-MM-DD), requires admin or project owner rights"`
+	DueDate                            *string `flag_name:"due_date" type:"string" transform:"string2IsoTime" required:"no" description:"Date string in the format YEAR-MONTH-DAY, e.g. 2016-03-11"`
+	MergeRequestToResolveDiscussionsOf *int    `flag_name:"merge_request_to_resolve_discussions_of" type:"integer" required:"no" description:"The IID of a merge request in which to resolve all issues"`
+	DiscussionToResolve                *string `flag_name:"discussion_to_resolve" type:"string" required:"no" description:"The ID of a discussion to resolve, use in combination with merge_request_to_resolve_discussions_of"`
+}
+
+var issuesCreateCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issuesCreateFlags{},
+	Opts:   &gitlab.CreateIssueOptions{},
+	Cmd: &cobra.Command{
+		Use:   "create",
+		Short: "Create new issue",
+		Long:  `Creates a new project issue.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*issuesCreateFlags)
+		opts := cmd.Opts.(*gitlab.CreateIssueOptions)
+		issue, _, err := gitlabClient.Issues.CreateIssue(parsePid(*flags.Id), opts)
+		if err != nil {
+			return err
+		}
+		return Output(issue)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/issues.html#edit-issue
+type issuesUpdateFlags struct {
+	Id          *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	IssueIid    *int    `flag_name:"issue_iid" type:"integer" required:"yes" description:"The internal ID of a project's issue"`
+	Title       *string `flag_name:"title" short:"t" type:"string" required:"no" description:"The title of an issue"`
+	Description *string `flag_name:"description" short:"d" type:"string" file:"raw" required:"no" description:"The description of an issue"`
+	AssigneeID  *int    `flag_name:"assignee_id" type:"integer" required:"no" description:"The ID of the user to assign the issue to"`
+	MilestoneID *int    `flag_name:"milestone_id" type:"integer" required:"no" description:"The global ID of a milestone to assign the issue to"`
+	Labels      *string `flag_name:"labels" type:"[]string" transform:"string2Labels" required:"no" description:"Comma-separated label names for an issue"`
+	StateEvent  *string `flag_name:"state_event" type:"string" choices:"close,reopen" required:"no" description:"The state event of an issue. Set close to close the issue and reopen to reopen it"`
+	UpdatedAt   *string `flag_name:"updated_at" type:"string" transform:"string2Time" required:"no" description:"Date when the issue was updated (YYYY-MM-DD), requires admin or project owner rights"`
+}
+
+var issuesUpdateCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issuesUpdateFlags{},
+	Opts:   &gitlab.UpdateIssueOptions{},
+	Cmd: &cobra.Command{
+		Use:   "update",
+		Short: "Edit issue",
+		Long:  `Updates an existing project issue. This command is also used to close or reopen an issue (with state_event).`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*issuesUpdateFlags)
+		opts := cmd.Opts.(*gitlab.UpdateIssueOptions)
+		issue, _, err := gitlabClient.Issues.UpdateIssue(parsePid(*flags.Id), *flags.IssueIid, opts)
+		if err != nil {
+			return err
+		}
+		return Output(issue)
+	},
+}
+
+var issuesCloseCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issueFlags{},
+	Cmd: &cobra.Command{
+		Use:   "close",
+		Short: "Close issue",
+		Long:  `Closes an issue, shortcut for update with --state_event close.`,
+	},
+	Run: func(cmd golabCommand) error {
+		return changeIssueState(cmd.Flags.(*issueFlags), "close")
+	},
+}
+
+var issuesReopenCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issueFlags{},
+	Cmd: &cobra.Command{
+		Use:   "reopen",
+		Short: "Reopen issue",
+		Long:  `Reopens a closed issue, shortcut for update with --state_event reopen.`,
+	},
+	Run: func(cmd golabCommand) error {
+		return changeIssueState(cmd.Flags.(*issueFlags), "reopen")
+	},
+}
+
+func changeIssueState(flags *issueFlags, stateEvent string) error {
+	opts := &gitlab.UpdateIssueOptions{StateEvent: &stateEvent}
+	issue, _, err := gitlabClient.Issues.UpdateIssue(parsePid(*flags.Id), *flags.IssueIid, opts)
+	if err != nil {
+		return err
+	}
+	return Output(issue)
+}
+
+// see https://docs.gitlab.com/ce/api/issues.html#delete-an-issue
+var issuesDeleteCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issueFlags{},
+	Cmd: &cobra.Command{
+		Use:   "delete",
+		Short: "Delete an issue",
+		Long:  `Only for admins and project owners. Soft deletes the issue in question.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*issueFlags)
+		_, err := gitlabClient.Issues.DeleteIssue(parsePid(*flags.Id), *flags.IssueIid)
+		return err
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/issues.html#set-a-time-estimate-for-an-issue
+type issuesSetTimeEstimateFlags struct {
+	Id       *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	IssueIid *int    `flag_name:"issue_iid" type:"integer" required:"yes" description:"The internal ID of a project's issue"`
+	Duration *string `flag_name:"duration" short:"d" type:"string" required:"yes" description:"The duration in human format. e.g: 3h30m"`
+}
+
+var issuesSetTimeEstimateCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issuesSetTimeEstimateFlags{},
+	Opts:   &gitlab.SetTimeEstimateOptions{},
+	Cmd: &cobra.Command{
+		Use:   "set-time-estimate",
+		Short: "Set a time estimate for an issue",
+		Long:  `Sets an estimated time of work for this issue.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*issuesSetTimeEstimateFlags)
+		opts := cmd.Opts.(*gitlab.SetTimeEstimateOptions)
+		timeStats, _, err := gitlabClient.Issues.SetTimeEstimate(parsePid(*flags.Id), *flags.IssueIid, opts)
+		if err != nil {
+			return err
+		}
+		return Output(timeStats)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/issues.html#reset-the-time-estimate-for-an-issue
+var issuesResetTimeEstimateCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issueFlags{},
+	Cmd: &cobra.Command{
+		Use:   "reset-time-estimate",
+		Short: "Reset the time estimate for an issue",
+		Long:  `Resets the estimated time for this issue to 0 seconds.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*issueFlags)
+		timeStats, _, err := gitlabClient.Issues.ResetTimeEstimate(parsePid(*flags.Id), *flags.IssueIid)
+		if err != nil {
+			return err
+		}
+		return Output(timeStats)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/issues.html#add-spent-time-for-an-issue
+type issuesAddSpentTimeFlags struct {
+	Id       *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	IssueIid *int    `flag_name:"issue_iid" type:"integer" required:"yes" description:"The internal ID of a project's issue"`
+	Duration *string `flag_name:"duration" short:"d" type:"string" required:"yes" description:"The duration in human format. e.g: 3h30m"`
+}
+
+var issuesAddSpentTimeCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issuesAddSpentTimeFlags{},
+	Opts:   &gitlab.AddSpentTimeOptions{},
+	Cmd: &cobra.Command{
+		Use:   "add-spent-time",
+		Short: "Add spent time for an issue",
+		Long:  `Adds spent time for this issue.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*issuesAddSpentTimeFlags)
+		opts := cmd.Opts.(*gitlab.AddSpentTimeOptions)
+		timeStats, _, err := gitlabClient.Issues.AddSpentTime(parsePid(*flags.Id), *flags.IssueIid, opts)
+		if err != nil {
+			return err
+		}
+		return Output(timeStats)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/issues.html#reset-spent-time-for-an-issue
+var issuesResetSpentTimeCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issueFlags{},
+	Cmd: &cobra.Command{
+		Use:   "reset-spent-time",
+		Short: "Reset spent time for an issue",
+		Long:  `Resets the total spent time for this issue to 0 seconds.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*issueFlags)
+		timeStats, _, err := gitlabClient.Issues.ResetSpentTime(parsePid(*flags.Id), *flags.IssueIid)
+		if err != nil {
+			return err
+		}
+		return Output(timeStats)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/issues.html#get-time-tracking-stats
+var issuesGetTimeTrackingStatsCmd = &golabCommand{
+	Parent: issuesCmd,
+	Flags:  &issueFlags{},
+	Cmd: &cobra.Command{
+		Use:   "time-tracking-stats",
+		Short: "Get time tracking stats",
+		Long:  `Get time tracking stats for an issue.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*issueFlags)
+		stats, _, err := gitlabClient.Issues.GetTimeSpent(parsePid(*flags.Id), *flags.IssueIid)
+		if err != nil {
+			return err
+		}
+		return Output(stats)
+	},
+}
+
+func init() {
+	issuesListCmd.Init()
+	initPaginationFlags(issuesListCmd.Cmd)
+	issuesListForGroupCmd.Init()
+	initPaginationFlags(issuesListForGroupCmd.Cmd)
+	issuesListForProjectCmd.Init()
+	initPaginationFlags(issuesListForProjectCmd.Cmd)
+	issuesGetCmd.Init()
+	issuesCreateCmd.Init()
+	issuesUpdateCmd.Init()
+	issuesCloseCmd.Init()
+	issuesReopenCmd.Init()
+	issuesDeleteCmd.Init()
+	issuesSetTimeEstimateCmd.Init()
+	issuesResetTimeEstimateCmd.Init()
+	issuesAddSpentTimeCmd.Init()
+	issuesResetSpentTimeCmd.Init()
+	issuesGetTimeTrackingStatsCmd.Init()
+	RootCmd.AddCommand(issuesCmd)
+}
@@ -0,0 +1,87 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("issues command", func() {
+
+	var (
+		mux    *http.ServeMux
+		server *httptest.Server
+		body   map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		resetCommandLineFlagSet()
+		mux = http.NewServeMux()
+		server = httptest.NewServer(mux)
+		gitlabClient = gitlab.NewClient(nil, "")
+		gitlabClient.SetBaseURL(server.URL + "/api/v4")
+		body = map[string]interface{}{}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	readBody := func(r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		Expect(json.Unmarshal(content, &body)).To(BeNil())
+	}
+
+	It("creates an issue with labels, assignees and due date", func() {
+		mux.HandleFunc("/api/v4/projects/group/project/issues", func(w http.ResponseWriter, r *http.Request) {
+			testMethod(r, "POST")
+			readBody(r)
+			fmt.Fprint(w, `{"id":1,"iid":7,"title":"Broken"}`)
+		})
+		stdout, _, err := executeCommand(RootCmd, "issues", "create", "-i", "group/project", "-t", "Broken",
+			"--labels", "bug,ui", "--assignee_ids", "3,4", "--due_date", "2018-03-31")
+		Expect(err).To(BeNil())
+		Expect(stdout).To(ContainSubstring(`"iid": 7`))
+		Expect(body["title"]).To(Equal("Broken"))
+		Expect(body["labels"]).To(Equal("bug,ui"))
+		Expect(body["assignee_ids"]).To(Equal([]interface{}{3.0, 4.0}))
+		Expect(body["due_date"]).To(Equal("2018-03-31"))
+	})
+
+	It("closes an issue", func() {
+		mux.HandleFunc("/api/v4/projects/12/issues/7", func(w http.ResponseWriter, r *http.Request) {
+			testMethod(r, "PUT")
+			readBody(r)
+			fmt.Fprint(w, `{"id":1,"iid":7,"state":"closed"}`)
+		})
+		_, _, err := executeCommand(RootCmd, "issues", "close", "-i", "12", "--issue_iid", "7")
+		Expect(err).To(BeNil())
+		Expect(body).To(Equal(map[string]interface{}{"state_event": "close"}))
+	})
+})
@@ -0,0 +1,404 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xanzy/go-gitlab"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// jobTraceInterval is the time between two polls of `jobs trace --follow`
+var jobTraceInterval = 2 * time.Second
+
+var (
+	// traceSectionMarker matches the markers Gitlab uses to fold sections of a job log
+	traceSectionMarker = regexp.MustCompile(`section_(start|end):[0-9]+:[^\r\n]*\r\x1b\[0K`)
+	ansiEscape         = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+)
+
+// see https://docs.gitlab.com/ce/api/jobs.html
+var jobsCmd = &cobra.Command{
+	Use:         "jobs",
+	Aliases:     []string{"job"},
+	Short:       "Manage jobs",
+	Long:        `List, run, retry, cancel and erase jobs, show job logs and download artifacts`,
+	Annotations: map[string]string{idResourceAnnotation: "project"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("this command cannot be run without a sub-command")
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/jobs.html#list-project-jobs
+type jobsListFlags struct {
+	Id         *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	PipelineId *int    `flag_name:"pipeline_id" short:"p" type:"integer" required:"no" description:"Only list the jobs of this pipeline"`
+	Scope      *string `flag_name:"scope" type:"string" required:"no" description:"Comma-separated list of job states to show: created, pending, running, failed, success, canceled, skipped or manual; showing all jobs if none provided"`
+}
+
+var jobsListCmd = &golabCommand{
+	Parent: jobsCmd,
+	Flags:  &jobsListFlags{},
+	Cmd: &cobra.Command{
+		Use:   "ls",
+		Short: "List project or pipeline jobs",
+		Long:  `Get a list of the jobs of a project or, with --pipeline_id, of a pipeline.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*jobsListFlags)
+		opts := &gitlab.ListJobsOptions{}
+		if flags.Scope != nil {
+			for _, scope := range strings.Split(*flags.Scope, ",") {
+				opts.Scope = append(opts.Scope, gitlab.BuildState(strings.TrimSpace(scope)))
+			}
+		}
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			if flags.PipelineId != nil {
+				return gitlabClient.Jobs.ListPipelineJobs(parsePid(*flags.Id), *flags.PipelineId, opts, page)
+			}
+			return gitlabClient.Jobs.ListProjectJobs(parsePid(*flags.Id), opts, page)
+		})
+	},
+}
+
+// jobFlags are used by all commands that work on a single job
+type jobFlags struct {
+	Id    *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	JobId *int    `flag_name:"job_id" short:"j" type:"integer" required:"yes" description:"The ID of a job"`
+}
+
+// jobAction is the signature shared by the go-gitlab functions that change a single job
+type jobAction func(pid interface{}, jobID int, options ...gitlab.OptionFunc) (*gitlab.Job, *gitlab.Response, error)
+
+// newJobActionCmd creates a command that runs action on the job given by --id and --job_id and prints the job
+func newJobActionCmd(use string, short string, long string, action func() jobAction) *golabCommand {
+	return &golabCommand{
+		Parent: jobsCmd,
+		Flags:  &jobFlags{},
+		Cmd: &cobra.Command{
+			Use:   use,
+			Short: short,
+			Long:  long,
+		},
+		Run: func(cmd golabCommand) error {
+			flags := cmd.Flags.(*jobFlags)
+			job, _, err := action()(parsePid(*flags.Id), *flags.JobId)
+			if err != nil {
+				return err
+			}
+			return Output(job)
+		},
+	}
+}
+
+// the go-gitlab functions are looked up on execution, since the gitlab client is not initialized before
+var (
+	// see https://docs.gitlab.com/ce/api/jobs.html#get-a-single-job
+	jobsGetCmd = newJobActionCmd("get", "Get a single job", `Get a single job of a project.`,
+		func() jobAction { return gitlabClient.Jobs.GetJob })
+	// see https://docs.gitlab.com/ce/api/jobs.html#play-a-job
+	jobsPlayCmd = newJobActionCmd("play", "Play a job", `Triggers a manual action to start a job.`,
+		func() jobAction { return gitlabClient.Jobs.PlayJob })
+	// see https://docs.gitlab.com/ce/api/jobs.html#retry-a-job
+	jobsRetryCmd = newJobActionCmd("retry", "Retry a job", `Retries a single job of a project.`,
+		func() jobAction { return gitlabClient.Jobs.RetryJob })
+	// see https://docs.gitlab.com/ce/api/jobs.html#cancel-a-job
+	jobsCancelCmd = newJobActionCmd("cancel", "Cancel a job", `Cancels a single job of a project.`,
+		func() jobAction { return gitlabClient.Jobs.CancelJob })
+	// see https://docs.gitlab.com/ce/api/jobs.html#erase-a-job
+	jobsEraseCmd = newJobActionCmd("erase", "Erase a job", `Erases a single job of a project (removes job artifacts and the job trace).`,
+		func() jobAction { return gitlabClient.Jobs.EraseJob })
+	// see https://docs.gitlab.com/ce/api/jobs.html#keep-artifacts
+	jobsKeepCmd = newJobActionCmd("keep", "Keep artifacts", `Prevents artifacts from being deleted when expiration is set.`,
+		func() jobAction { return gitlabClient.Jobs.KeepArtifacts })
+)
+
+// see https://docs.gitlab.com/ce/api/jobs.html#get-a-trace-file
+type jobsTraceFlags struct {
+	Id      *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	JobId   *int    `flag_name:"job_id" short:"j" type:"integer" required:"yes" description:"The ID of a job"`
+	Follow  *bool   `flag_name:"follow" short:"f" type:"boolean" required:"no" description:"Keep printing the log while the job is running"`
+	NoColor *bool   `flag_name:"no_color" type:"boolean" required:"no" description:"Remove ANSI colours from the log, default if stdout is no terminal"`
+}
+
+var jobsTraceCmd = &golabCommand{
+	Parent: jobsCmd,
+	Flags:  &jobsTraceFlags{},
+	Cmd: &cobra.Command{
+		Use:   "trace",
+		Short: "Show the log of a job",
+		Long:  `Prints the log (trace) of a job. With --follow the log is printed incrementally until the job is finished.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*jobsTraceFlags)
+		colors := terminal.IsTerminal(int(os.Stdout.Fd())) && (flags.NoColor == nil || !*flags.NoColor)
+		follow := flags.Follow != nil && *flags.Follow
+		return printTrace(os.Stdout, parsePid(*flags.Id), *flags.JobId, follow, colors)
+	},
+}
+
+// printTrace writes the log of a job to w. If follow is set, the job is polled and the log is written
+// line by line, until the job is finished. Each poll only requests the part of the log that was not printed yet.
+func printTrace(w io.Writer, pid interface{}, jobId int, follow bool, colors bool) error {
+	printed := 0
+	for {
+		finished := true
+		if follow {
+			job, _, err := gitlabClient.Jobs.GetJob(pid, jobId)
+			if err != nil {
+				return err
+			}
+			finished = isFinishedJob(job.Status)
+		}
+
+		content, offset, err := getTraceFrom(pid, jobId, printed)
+		if err != nil {
+			return err
+		}
+		end := offset + len(content)
+		if !finished {
+			// only print complete lines while the job is running
+			end = offset + bytes.LastIndexByte(content, '\n') + 1
+		}
+		if end > printed {
+			fmt.Fprint(w, renderTrace(string(content[printed-offset:end-offset]), colors))
+			printed = end
+		}
+
+		if finished {
+			return nil
+		}
+		time.Sleep(jobTraceInterval)
+	}
+}
+
+// getTraceFrom fetches the log of a job from the given offset on. The returned offset is the position of the
+// content in the log, it is 0 if the server ignored the range and sent the whole log.
+func getTraceFrom(pid interface{}, jobId int, offset int) ([]byte, int, error) {
+	path := fmt.Sprintf("projects/%s/jobs/%d/trace", url.QueryEscape(fmt.Sprint(pid)), jobId)
+	req, err := gitlabClient.NewRequest("GET", path, nil, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	// go-gitlab treats partial content as error, so the request is sent with the underlying client
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusRequestedRangeNotSatisfiable:
+		// nothing was added to the log since the last request
+		return nil, offset, nil
+	case http.StatusPartialContent:
+	default:
+		if err := gitlab.CheckResponse(resp); err != nil {
+			return nil, 0, err
+		}
+		offset = 0
+	}
+	content, err := ioutil.ReadAll(resp.Body)
+	return content, offset, err
+}
+
+func isFinishedJob(status string) bool {
+	switch status {
+	case "created", "pending", "running":
+		return false
+	}
+	return true
+}
+
+// renderTrace removes Gitlab's section markers from a job log and the ANSI colours, if colors is not set
+func renderTrace(trace string, colors bool) string {
+	trace = traceSectionMarker.ReplaceAllString(trace, "")
+	if !colors {
+		trace = ansiEscape.ReplaceAllString(trace, "")
+	}
+	return trace
+}
+
+var jobsArtifactsCmd = &cobra.Command{
+	Use:   "artifacts",
+	Short: "Manage job artifacts",
+	Long:  `Download job artifacts`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("this command cannot be run without a sub-command")
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/jobs.html#get-job-artifacts
+// and https://docs.gitlab.com/ce/api/jobs.html#download-the-artifacts-file
+type jobsArtifactsDownloadFlags struct {
+	Id      *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	JobId   *int    `flag_name:"job_id" short:"j" type:"integer" required:"no" description:"The ID of a job, either this or --ref_name and --job are required"`
+	RefName *string `flag_name:"ref_name" short:"r" type:"string" required:"no" description:"Download the artifacts of the latest successful job for this branch or tag"`
+	Job     *string `flag_name:"job" type:"string" required:"no" description:"The name of the job, used with --ref_name"`
+	Path    *string `flag_name:"path" short:"p" type:"string" required:"no" description:"Save the zip archive to this file (default: artifacts.zip)"`
+	Extract *string `flag_name:"extract" short:"x" type:"string" required:"no" description:"Extract the archive into this directory instead of saving it"`
+	File    *string `flag_name:"file" short:"f" type:"string" required:"no" description:"Write a single file of the archive to stdout instead of saving it"`
+}
+
+var jobsArtifactsDownloadCmd = &golabCommand{
+	Parent: jobsArtifactsCmd,
+	Flags:  &jobsArtifactsDownloadFlags{},
+	Cmd: &cobra.Command{
+		Use:   "download",
+		Short: "Download the artifacts of a job",
+		Long: `Downloads the artifacts archive of a job, given by --job_id or by --ref_name and --job.
+
+The archive is saved as a zip file (--path), extracted into a directory (--extract) or a single file of it is written to stdout (--file).`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*jobsArtifactsDownloadFlags)
+		pid := parsePid(*flags.Id)
+
+		var archive io.Reader
+		var err error
+		if flags.JobId != nil {
+			archive, _, err = gitlabClient.Jobs.GetJobArtifacts(pid, *flags.JobId)
+		} else if flags.RefName != nil && flags.Job != nil {
+			archive, _, err = gitlabClient.Jobs.DownloadArtifactsFile(pid, *flags.RefName, *flags.Job)
+		} else {
+			return errors.New("either --job_id or --ref_name and --job are required")
+		}
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadAll(archive)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case flags.File != nil:
+			return writeArtifactFile(os.Stdout, content, *flags.File)
+		case flags.Extract != nil:
+			return extractArtifacts(content, *flags.Extract)
+		default:
+			path := "artifacts.zip"
+			if flags.Path != nil {
+				path = *flags.Path
+			}
+			return ioutil.WriteFile(path, content, 0644)
+		}
+	},
+}
+
+// writeArtifactFile writes the file with the given name from a zip archive to w
+func writeArtifactFile(w io.Writer, archive []byte, name string) error {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+	for _, file := range reader.File {
+		if file.Name != strings.TrimPrefix(name, "./") {
+			continue
+		}
+		content, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+		_, err = io.Copy(w, content)
+		return err
+	}
+	return fmt.Errorf("file '%s' not found in artifacts", name)
+}
+
+// extractArtifacts extracts a zip archive into dir, entries pointing outside of dir are rejected
+func extractArtifacts(archive []byte, dir string) error {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range reader.File {
+		target := filepath.Join(root, file.Name)
+		if target != root && !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+			return fmt.Errorf("illegal file path in artifacts: %s", file.Name)
+		}
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := extractArtifactFile(file, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractArtifactFile(file *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	content, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, file.Mode()|0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, content)
+	return err
+}
+
+func init() {
+	jobsListCmd.Init()
+	initPaginationFlags(jobsListCmd.Cmd)
+	jobsGetCmd.Init()
+	jobsPlayCmd.Init()
+	jobsRetryCmd.Init()
+	jobsCancelCmd.Init()
+	jobsEraseCmd.Init()
+	jobsKeepCmd.Init()
+	jobsTraceCmd.Init()
+	jobsArtifactsDownloadCmd.Init()
+	jobsCmd.AddCommand(jobsArtifactsCmd)
+	RootCmd.AddCommand(jobsCmd)
+}
@@ -0,0 +1,171 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("jobs command", func() {
+
+	var (
+		mux    *http.ServeMux
+		server *httptest.Server
+	)
+
+	zipArchive := func(files map[string]string) []byte {
+		buf := new(bytes.Buffer)
+		w := zip.NewWriter(buf)
+		for name, content := range files {
+			f, err := w.Create(name)
+			Expect(err).To(BeNil())
+			f.Write([]byte(content))
+		}
+		Expect(w.Close()).To(BeNil())
+		return buf.Bytes()
+	}
+
+	BeforeEach(func() {
+		resetCommandLineFlagSet()
+		mux = http.NewServeMux()
+		server = httptest.NewServer(mux)
+		gitlabClient = gitlab.NewClient(nil, "")
+		gitlabClient.SetBaseURL(server.URL + "/api/v4")
+		httpClient = http.DefaultClient
+		jobTraceInterval = time.Millisecond
+	})
+
+	AfterEach(func() {
+		server.Close()
+		jobTraceInterval = 2 * time.Second
+	})
+
+	It("follows the trace of a running job", func() {
+		polls := 0
+		ranges := []string{}
+		traces := []string{
+			"section_start:1:prepare\r\x1b[0KPreparing\n\x1b[32;1mRunning\x1b[0;m",
+			"section_start:1:prepare\r\x1b[0KPreparing\n\x1b[32;1mRunning\x1b[0;m tests\nok\n",
+			"section_start:1:prepare\r\x1b[0KPreparing\n\x1b[32;1mRunning\x1b[0;m tests\nok\n",
+			"section_start:1:prepare\r\x1b[0KPreparing\n\x1b[32;1mRunning\x1b[0;m tests\nok\nJob succeeded",
+		}
+		mux.HandleFunc("/api/v4/projects/12/jobs/7", func(w http.ResponseWriter, r *http.Request) {
+			polls++
+			status := "running"
+			if polls == len(traces) {
+				status = "success"
+			}
+			fmt.Fprintf(w, `{"id":7,"status":"%s"}`, status)
+		})
+		mux.HandleFunc("/api/v4/projects/12/jobs/7/trace", func(w http.ResponseWriter, r *http.Request) {
+			trace := traces[polls-1]
+			ranges = append(ranges, r.Header.Get("Range"))
+			if r.Header.Get("Range") == "" {
+				fmt.Fprint(w, trace)
+				return
+			}
+			var offset int
+			fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &offset)
+			if offset >= len(trace) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprint(w, trace[offset:])
+		})
+
+		out := new(bytes.Buffer)
+		Expect(printTrace(out, 12, 7, true, false)).To(BeNil())
+		Expect(polls).To(Equal(4))
+		Expect(ranges).To(Equal([]string{"", "bytes=38-", "bytes=67-", "bytes=67-"}))
+		Expect(out.String()).To(Equal("Preparing\nRunning tests\nok\nJob succeeded"))
+	})
+
+	It("prints the new part of the trace if the server ignores the range", func() {
+		polls := 0
+		traces := []string{"Preparing\nRun", "Preparing\nRunning\nJob succeeded"}
+		mux.HandleFunc("/api/v4/projects/12/jobs/7", func(w http.ResponseWriter, r *http.Request) {
+			polls++
+			status := "running"
+			if polls == len(traces) {
+				status = "success"
+			}
+			fmt.Fprintf(w, `{"id":7,"status":"%s"}`, status)
+		})
+		mux.HandleFunc("/api/v4/projects/12/jobs/7/trace", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, traces[polls-1])
+		})
+
+		out := new(bytes.Buffer)
+		Expect(printTrace(out, 12, 7, true, false)).To(BeNil())
+		Expect(out.String()).To(Equal("Preparing\nRunning\nJob succeeded"))
+	})
+
+	It("keeps ANSI colours if requested", func() {
+		Expect(renderTrace("\x1b[31;1mfailed\x1b[0;m\n", true)).To(Equal("\x1b[31;1mfailed\x1b[0;m\n"))
+		Expect(renderTrace("\x1b[31;1mfailed\x1b[0;m\n", false)).To(Equal("failed\n"))
+	})
+
+	It("writes a single file of the artifacts to stdout", func() {
+		archive := zipArchive(map[string]string{"build/app.txt": "binary", "report.xml": "<xml/>"})
+		mux.HandleFunc("/api/v4/projects/12/jobs/8/artifacts", func(w http.ResponseWriter, r *http.Request) {
+			w.Write(archive)
+		})
+		stdout, _, err := executeCommand(RootCmd, "jobs", "artifacts", "download", "-i", "12", "-j", "8", "--file", "report.xml")
+		Expect(err).To(BeNil())
+		Expect(stdout).To(Equal("<xml/>"))
+	})
+
+	It("extracts artifacts into a directory", func() {
+		dir, err := ioutil.TempDir("", "golab-artifacts")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+
+		archive := zipArchive(map[string]string{"build/app.txt": "binary"})
+		Expect(extractArtifacts(archive, dir)).To(BeNil())
+		content, err := ioutil.ReadFile(filepath.Join(dir, "build", "app.txt"))
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(Equal("binary"))
+	})
+
+	It("rejects artifacts with paths outside of the target directory", func() {
+		dir, err := ioutil.TempDir("", "golab-artifacts")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+
+		archive := zipArchive(map[string]string{"../evil.sh": "rm -rf"})
+		err = extractArtifacts(archive, dir)
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("illegal file path"))
+	})
+})
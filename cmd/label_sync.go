@@ -0,0 +1,217 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+	"gopkg.in/yaml.v2"
+)
+
+// labelsManifest lists the desired labels of the listed projects and all projects of the listed groups
+type labelsManifest struct {
+	Groups   []string         `yaml:"groups"`
+	Projects []string         `yaml:"projects"`
+	Labels   []*labelManifest `yaml:"labels"`
+}
+
+// labelManifest is the desired state of a label, labels named like one of the aliases are renamed to Name
+type labelManifest struct {
+	Name        string   `yaml:"name"`
+	Color       string   `yaml:"color"`
+	Description string   `yaml:"description"`
+	Aliases     []string `yaml:"aliases"`
+}
+
+func readLabelsManifest(file string) (*labelsManifest, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	m := &labelsManifest{}
+	if err := yaml.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("could not parse labels %s: %s", file, err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid labels %s: %s", file, err)
+	}
+	return m, nil
+}
+
+func (m *labelsManifest) validate() error {
+	if len(m.Groups) == 0 && len(m.Projects) == 0 {
+		return errors.New("no groups or projects given")
+	}
+	names := map[string]string{}
+	for _, label := range m.Labels {
+		if label.Name == "" || label.Color == "" {
+			return errors.New("every label needs a name and a color")
+		}
+		for _, name := range append([]string{label.Name}, label.Aliases...) {
+			if other, exists := names[strings.ToLower(name)]; exists {
+				return fmt.Errorf("'%s' is used by the labels %s and %s", name, other, label.Name)
+			}
+			names[strings.ToLower(name)] = label.Name
+		}
+	}
+	return nil
+}
+
+// labelProjects returns the paths of the projects and of all projects of the groups, each project only once
+func labelProjects(groups []string, projects []string) ([]string, error) {
+	var paths []string
+	seen := map[string]bool{}
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	for _, group := range groups {
+		groupProjects, err := allGroupProjects(group)
+		if err != nil {
+			return nil, fmt.Errorf("could not get the projects of group %s: %s", group, err)
+		}
+		for _, project := range groupProjects {
+			add(project.PathWithNamespace)
+		}
+	}
+	for _, project := range projects {
+		add(project)
+	}
+	return paths, nil
+}
+
+// labelTargets returns the project given by target or, if there is no such project, all projects of the group
+func labelTargets(target string) ([]string, error) {
+	project, resp, err := gitlabClient.Projects.GetProject(parsePid(target))
+	if err == nil {
+		return []string{project.PathWithNamespace}, nil
+	}
+	if !isNotFound(resp) {
+		return nil, err
+	}
+	return labelProjects([]string{target}, nil)
+}
+
+// applyLabels plans the label changes of all projects before it makes them
+func applyLabels(projects []string, desired []*labelManifest, prune bool) error {
+	var changes []*change
+	for _, pid := range projects {
+		live, err := allLabels(pid)
+		if err != nil {
+			return fmt.Errorf("could not get the labels of project %s: %s", pid, err)
+		}
+		changes = append(changes, planLabels(pid, desired, live, prune)...)
+	}
+	return applyChanges(changes)
+}
+
+// planLabels compares the desired with the live labels of a project, a live label matches a desired label with the
+// same name, a name that only differs in case or one of its aliases, in this order
+func planLabels(pid string, desired []*labelManifest, live []*gitlab.Label, prune bool) []*change {
+	var changes []*change
+	matched := map[*gitlab.Label]bool{}
+	for _, label := range desired {
+		label := label
+		resource := fmt.Sprintf("label %s of project %s", label.Name, pid)
+		current := matchLabel(label, live, matched)
+		if current == nil {
+			details := []string{"color: " + label.Color}
+			if label.Description != "" {
+				details = append(details, "description: "+jsonValue(label.Description))
+			}
+			changes = append(changes, &change{action: "create", resource: resource, details: details, apply: func() error {
+				_, _, err := gitlabClient.Labels.CreateLabel(pid, &gitlab.CreateLabelOptions{Name: &label.Name, Color: &label.Color, Description: &label.Description})
+				return err
+			}})
+			continue
+		}
+		matched[current] = true
+		var details []string
+		opts := &gitlab.UpdateLabelOptions{Name: gitlab.String(current.Name)}
+		if current.Name != label.Name {
+			details = append(details, fmt.Sprintf("name: %s => %s", current.Name, label.Name))
+			opts.NewName = &label.Name
+		}
+		if !strings.EqualFold(current.Color, label.Color) {
+			details = append(details, fmt.Sprintf("color: %s => %s", current.Color, label.Color))
+			opts.Color = &label.Color
+		}
+		if current.Description != label.Description {
+			details = append(details, fmt.Sprintf("description: %s => %s", jsonValue(current.Description), jsonValue(label.Description)))
+			opts.Description = &label.Description
+		}
+		if len(details) > 0 {
+			changes = append(changes, &change{action: "update", resource: resource, details: details, apply: func() error {
+				_, _, err := gitlabClient.Labels.UpdateLabel(pid, opts)
+				return err
+			}})
+		}
+	}
+	if prune {
+		var unmatched []string
+		for _, label := range live {
+			if !matched[label] {
+				unmatched = append(unmatched, label.Name)
+			}
+		}
+		sort.Strings(unmatched)
+		for _, name := range unmatched {
+			name := name
+			changes = append(changes, &change{action: "delete", resource: fmt.Sprintf("label %s of project %s", name, pid), apply: func() error {
+				_, err := gitlabClient.Labels.DeleteLabel(pid, &gitlab.DeleteLabelOptions{Name: &name})
+				return err
+			}})
+		}
+	}
+	return changes
+}
+
+// matchLabel returns the live label that is not matched yet and has the name of the desired label, a name that only
+// differs in case or one of its aliases
+func matchLabel(label *labelManifest, live []*gitlab.Label, matched map[*gitlab.Label]bool) *gitlab.Label {
+	matches := []func(name string) bool{
+		func(name string) bool { return name == label.Name },
+		func(name string) bool { return strings.EqualFold(name, label.Name) },
+		func(name string) bool {
+			for _, alias := range label.Aliases {
+				if strings.EqualFold(name, alias) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+	for _, match := range matches {
+		for _, current := range live {
+			if !matched[current] && match(current.Name) {
+				return current
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("label sync", func() {
+
+	summary := func(changes []*change) [][]string {
+		var result [][]string
+		for _, c := range changes {
+			result = append(result, append([]string{c.action, c.resource}, c.details...))
+		}
+		return result
+	}
+
+	live := []*gitlab.Label{
+		{Name: "Bug", Color: "#FF0000"},
+		{Name: "feature", Color: "#00ff00", Description: "New features"},
+		{Name: "wontfix", Color: "#cccccc"},
+	}
+
+	It("renames labels that differ in case or match an alias", func() {
+		desired := []*labelManifest{
+			{Name: "bug", Color: "#ff0000"},
+			{Name: "enhancement", Color: "#00ff00", Description: "New features", Aliases: []string{"Feature"}},
+		}
+
+		Expect(summary(planLabels("group/project", desired, live, false))).To(Equal([][]string{
+			{"update", "label bug of project group/project", "name: Bug => bug"},
+			{"update", "label enhancement of project group/project", "name: feature => enhancement"},
+		}))
+	})
+
+	It("creates missing labels, updates colors and descriptions and deletes unknown labels with prune", func() {
+		desired := []*labelManifest{
+			{Name: "Bug", Color: "#aa0000", Description: "Something is broken"},
+			{Name: "security", Color: "#000000"},
+		}
+
+		Expect(summary(planLabels("group/project", desired, live, true))).To(Equal([][]string{
+			{"update", "label Bug of project group/project", "color: #FF0000 => #aa0000", `description: "" => "Something is broken"`},
+			{"create", "label security of project group/project", "color: #000000"},
+			{"delete", "label feature of project group/project"},
+			{"delete", "label wontfix of project group/project"},
+		}))
+	})
+
+	It("prefers an exact match over a match by case or alias", func() {
+		labels := []*gitlab.Label{{Name: "BUG"}, {Name: "bug"}}
+
+		Expect(matchLabel(&labelManifest{Name: "bug"}, labels, map[*gitlab.Label]bool{})).To(Equal(labels[1]))
+		Expect(matchLabel(&labelManifest{Name: "bug"}, labels, map[*gitlab.Label]bool{labels[1]: true})).To(Equal(labels[0]))
+		Expect(matchLabel(&labelManifest{Name: "defect", Aliases: []string{"Bug"}}, labels, map[*gitlab.Label]bool{})).To(Equal(labels[0]))
+	})
+
+	It("returns no changes if the labels are in sync", func() {
+		desired := []*labelManifest{{Name: "Bug", Color: "#ff0000"}, {Name: "feature", Color: "#00FF00", Description: "New features"}}
+
+		Expect(planLabels("group/project", desired, live[:2], true)).To(BeEmpty())
+	})
+
+	It("rejects manifests with duplicate names or aliases", func() {
+		m := &labelsManifest{Projects: []string{"group/project"}, Labels: []*labelManifest{
+			{Name: "bug", Color: "#ff0000"},
+			{Name: "defect", Color: "#ff0000", Aliases: []string{"Bug"}},
+		}}
+		Expect(m.validate()).To(MatchError("'Bug' is used by the labels bug and defect"))
+
+		m.Labels = []*labelManifest{{Name: "bug"}}
+		Expect(m.validate()).To(MatchError("every label needs a name and a color"))
+
+		m.Projects = nil
+		Expect(m.validate()).To(MatchError("no groups or projects given"))
+	})
+})
@@ -0,0 +1,238 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/xanzy/go-gitlab"
+)
+
+// see https://docs.gitlab.com/ce/api/labels.html
+var labelsCmd = &golabCommand{
+	Parent: RootCmd,
+	Cmd: &cobra.Command{
+		Use:         "labels",
+		Aliases:     []string{"label"},
+		Short:       "Labels",
+		Long:        `Manage the labels of projects and keep them consistent across projects`,
+		Annotations: map[string]string{idResourceAnnotation: "project"},
+	},
+	Run: func(cmd golabCommand) error {
+		return errors.New("cannot use this command without further sub-commands")
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/labels.html#list-labels
+type labelsListFlags struct {
+	Id *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+}
+
+var labelsListCmd = &golabCommand{
+	Parent: labelsCmd.Cmd,
+	Flags:  &labelsListFlags{},
+	Cmd: &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List labels",
+		Long:    `Get all labels for a given project.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*labelsListFlags)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Labels.ListLabels(parsePid(*flags.Id), page)
+		})
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/labels.html#create-a-new-label
+type labelsCreateFlags struct {
+	Id          *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Name        *string `flag_name:"name" short:"n" type:"string" required:"yes" description:"The name of the label"`
+	Color       *string `flag_name:"color" short:"c" type:"string" required:"yes" description:"The color of the label given in 6-digit hex notation with leading '#' sign (e.g. #FFAABB) or one of the CSS color names"`
+	Description *string `flag_name:"description" short:"d" type:"string" file:"raw" required:"no" description:"The description of the label"`
+}
+
+var labelsCreateCmd = &golabCommand{
+	Parent: labelsCmd.Cmd,
+	Flags:  &labelsCreateFlags{},
+	Opts:   &gitlab.CreateLabelOptions{},
+	Cmd: &cobra.Command{
+		Use:   "create",
+		Short: "Create a new label",
+		Long:  `Creates a new label for the given repository with the given name and color.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*labelsCreateFlags)
+		opts := cmd.Opts.(*gitlab.CreateLabelOptions)
+		label, _, err := gitlabClient.Labels.CreateLabel(parsePid(*flags.Id), opts)
+		if err != nil {
+			return err
+		}
+		return Output(label)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/labels.html#edit-an-existing-label
+type labelsUpdateFlags struct {
+	Id          *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Name        *string `flag_name:"name" short:"n" type:"string" required:"yes" description:"The name of the existing label"`
+	NewName     *string `flag_name:"new_name" type:"string" required:"no" description:"The new name of the label"`
+	Color       *string `flag_name:"color" short:"c" type:"string" required:"no" description:"The color of the label given in 6-digit hex notation with leading '#' sign (e.g. #FFAABB) or one of the CSS color names"`
+	Description *string `flag_name:"description" short:"d" type:"string" file:"raw" required:"no" description:"The new description of the label"`
+}
+
+var labelsUpdateCmd = &golabCommand{
+	Parent: labelsCmd.Cmd,
+	Flags:  &labelsUpdateFlags{},
+	Opts:   &gitlab.UpdateLabelOptions{},
+	Cmd: &cobra.Command{
+		Use:   "update",
+		Short: "Edit an existing label",
+		Long:  `Updates an existing label with new name or new color. At least one parameter is required, to update the label.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*labelsUpdateFlags)
+		opts := cmd.Opts.(*gitlab.UpdateLabelOptions)
+		if opts.NewName == nil && opts.Color == nil && opts.Description == nil {
+			return errors.New("one of `--new_name`, `--color` or `--description` is required - exiting")
+		}
+		label, _, err := gitlabClient.Labels.UpdateLabel(parsePid(*flags.Id), opts)
+		if err != nil {
+			return err
+		}
+		return Output(label)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/labels.html#delete-a-label
+type labelsDeleteFlags struct {
+	Id   *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Name *string `flag_name:"name" short:"n" type:"string" required:"yes" description:"The name of the label"`
+}
+
+var labelsDeleteCmd = &golabCommand{
+	Parent: labelsCmd.Cmd,
+	Flags:  &labelsDeleteFlags{},
+	Opts:   &gitlab.DeleteLabelOptions{},
+	Cmd: &cobra.Command{
+		Use:   "delete",
+		Short: "Delete a label",
+		Long:  `Deletes a label with a given name.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*labelsDeleteFlags)
+		opts := cmd.Opts.(*gitlab.DeleteLabelOptions)
+		_, err := gitlabClient.Labels.DeleteLabel(parsePid(*flags.Id), opts)
+		return err
+	},
+}
+
+type labelsSyncFlags struct {
+	From  *string `flag_name:"from" type:"string" required:"yes" description:"The ID or path of the project to copy the labels from"`
+	To    *string `flag_name:"to" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or path of the project or group to copy the labels to, for a group all its projects are synced"`
+	Prune *bool   `flag_name:"prune" type:"boolean" required:"no" description:"Delete labels that do not exist in the source project"`
+}
+
+var labelsSyncCmd = &golabCommand{
+	Parent: labelsCmd.Cmd,
+	Flags:  &labelsSyncFlags{},
+	Cmd: &cobra.Command{
+		Use:   "sync",
+		Short: "Copy labels from one project to other projects",
+		Long: `Creates the labels of the source project in the target project - or in all projects of the target group - and
+updates the color and description of labels that exist with the same name. Labels that only exist in a target project
+are deleted with --prune. The changes are printed before they are made, use --dry-run to only print them:
+
+    golab labels sync --from my-group/template --to my-group --dry-run`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*labelsSyncFlags)
+		source, err := allLabels(*flags.From)
+		if err != nil {
+			return err
+		}
+		targets, err := labelTargets(*flags.To)
+		if err != nil {
+			return err
+		}
+		var desired []*labelManifest
+		for _, label := range source {
+			desired = append(desired, &labelManifest{Name: label.Name, Color: label.Color, Description: label.Description})
+		}
+		return applyLabels(targets, desired, flags.Prune != nil && *flags.Prune)
+	},
+}
+
+type labelsApplyFlags struct {
+	File  *string `flag_name:"file" short:"f" type:"string" required:"yes" description:"YAML file with the labels and the groups and projects to apply them to"`
+	Prune *bool   `flag_name:"prune" type:"boolean" required:"no" description:"Delete labels that are not listed in the file"`
+}
+
+var labelsApplyCmd = &golabCommand{
+	Parent: labelsCmd.Cmd,
+	Flags:  &labelsApplyFlags{},
+	Cmd: &cobra.Command{
+		Use:   "apply",
+		Short: "Standardise labels across projects",
+		Long: `Converges the labels of all listed projects and all projects of the listed groups to the labels of a YAML file:
+
+    groups:
+      - my-group
+    projects:
+      - other-group/project
+    labels:
+      - name: bug
+        color: "#d9534f"
+        description: Something isn't working
+        aliases: [Bug, defect]
+      - name: feature
+        color: "#5cb85c"
+
+Missing labels are created, labels that differ in color or description are updated. Labels whose name only differs
+in case or matches one of the aliases are renamed, so issues and merge requests keep their labels. Labels that are not
+listed are deleted with --prune. The changes are printed before they are made, use --dry-run to only print them.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*labelsApplyFlags)
+		m, err := readLabelsManifest(*flags.File)
+		if err != nil {
+			return err
+		}
+		projects, err := labelProjects(m.Groups, m.Projects)
+		if err != nil {
+			return err
+		}
+		return applyLabels(projects, m.Labels, flags.Prune != nil && *flags.Prune)
+	},
+}
+
+func init() {
+	labelsCmd.Init()
+	labelsListCmd.Init()
+	initPaginationFlags(labelsListCmd.Cmd)
+	labelsCreateCmd.Init()
+	labelsUpdateCmd.Init()
+	labelsDeleteCmd.Init()
+	labelsSyncCmd.Init()
+	labelsApplyCmd.Init()
+}
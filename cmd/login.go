@@ -32,45 +32,155 @@ import (
 	"net/url"
 	"github.com/spf13/viper"
 	"io/ioutil"
-	path2 "path"
+	"path/filepath"
 )
 
-var host string
+var host, loginToken, oauthClientId, oauthClientSecret string
+var encrypt, oauth bool
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Login to a Gitlab server",
-	Long: `Log in to a Gitlab server with your username and password.`,
+	Long: `Log in to a Gitlab server with your username and password, a personal access token or OAuth2.
+
+Without further flags, golab gets a private token with the session API. This API was removed in newer
+Gitlab versions, use one of these instead:
+
+    # stores a personal access token after checking it with the Gitlab server
+    golab login --host https://gitlab.example.com --token <personal access token>
+
+    # gets an OAuth2 token with the resource owner password credentials grant
+    golab login --host https://gitlab.example.com --user <username> --oauth
+
+OAuth2 tokens are refreshed automatically when they expire, some Gitlab versions require an OAuth2
+application for the password grant, its credentials are given with --oauth-client-id and --oauth-client-secret.
+
+The token is written to the selected context (or the top-level settings) of the config file given with
+--config, the config file in use or ~/.config/golab/config.yml - a .golab.yml in the working directory
+is never written to.
+
+With --encrypt the token is stored in a file next to the config file, encrypted with a passphrase that is
+taken from $GOLAB_TOKEN_PASSPHRASE or asked for. The file is an OpenPGP message and can be decrypted
+with 'gpg --decrypt' as well. Use 'golab logout' to remove the stored token.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if dryRun {
+			return errors.New("login cannot be combined with --dry-run")
+		}
 		if host == "" {
 			return errors.New("required parameter `--host` or `-s` not given - exiting")
 		}
-		if username == "" {
-			return errors.New("required parameter `--user` or `-u` not given - exiting")
+		if loginToken != "" && oauth {
+			return errors.New("--token cannot be combined with --oauth")
+		}
+		if encrypt && oauth {
+			return errors.New("--encrypt cannot be combined with --oauth")
 		}
-		if password == "" {
+		token := loginToken
+		var oauthTokens *oauthConfig
+		if token == "" {
+			if username == "" {
+				return errors.New("required parameter `--user` or `-u` not given - exiting")
+			}
+			if password == "" {
+				var err error
+				password, err = askForPassword()
+				if err != nil { return err }
+			}
 			var err error
-			password, err = askForPassword()
+			if oauth {
+				oauthTokens, err = getOAuthToken(host, username, password)
+			} else {
+				token, err = getPrivateToken(host, username, password)
+			}
 			if err != nil { return err }
 		}
-		token, err := getPrivateToken(host, username, password)
+		user, version, err := checkLogin(host, token, oauthTokens)
 		if err != nil { return err }
-		err = writeGolabConf(host, token)
+		err = writeGolabConf(host, token, oauthTokens)
 		if err != nil { return err}
-		fmt.Printf("** successfully logged in to %s\n", host)
+		fmt.Printf("** successfully logged in to %s as %s (%s), Gitlab version %s\n", host, user.Username, user.Name, version)
 		return nil
 	},
 }
 
-func writeGolabConf(host string, token string) error {
-	conf := []byte(fmt.Sprintf("---\nurl: \"%s\"\ntoken: \"%s\"", host, token))
-	pwd, err := os.Getwd()
-	filename := path2.Join(pwd, ".golab.yml")
-	err = ioutil.WriteFile(filename, conf, 0600)
-	if err == nil {
-		fmt.Printf("** golab config written to %s\n", filename)
+// writeGolabConf stores url and token (or the OAuth tokens) in the selected context or at the top-level of the
+// config file, with --encrypt the token is written to an encrypted token file next to the config file
+func writeGolabConf(host string, token string, oauthTokens *oauthConfig) error {
+	filename, err := loginConfigPath()
+	if err != nil {
+		return err
+	}
+	conf, err := readGolabConfig(filename)
+	if err != nil {
+		return err
+	}
+	name := selectedContextName(conf)
+	tokenFile := ""
+	if encrypt {
+		tokenFile = filepath.Join(filepath.Dir(filename), tokenFileName(name))
+		if err := writeTokenFile(tokenFile, token); err != nil {
+			return err
+		}
+		token = ""
+	}
+	if name == "" {
+		conf.Url, conf.Token, conf.TokenCommand, conf.TokenFile, conf.OAuth = host, token, "", tokenFile, oauthTokens
+	} else {
+		if conf.Contexts == nil {
+			conf.Contexts = map[string]*configContext{}
+		}
+		context, ok := conf.Contexts[name]
+		if !ok {
+			context = &configContext{}
+			conf.Contexts[name] = context
+		}
+		context.Url, context.Token, context.TokenCommand, context.TokenFile, context.OAuth = host, token, "", tokenFile, oauthTokens
+	}
+	if err := writeGolabConfig(conf, filename); err != nil {
+		return err
+	}
+	fmt.Printf("** golab config written to %s\n", filename)
+	return nil
+}
+
+// loginConfigPath returns the config file given with --config or the one in use, a config file in the
+// working directory is not written to, to not end up with a token in a repository
+func loginConfigPath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	if used := viper.ConfigFileUsed(); used != "" {
+		if pwd, err := os.Getwd(); err == nil && filepath.Dir(used) != pwd {
+			return used, nil
+		}
+	}
+	return userConfigFile()
+}
+
+func tokenFileName(context string) string {
+	if context == "" {
+		return "token.asc"
+	}
+	return context + ".token.asc"
+}
+
+func writeTokenFile(path string, token string) error {
+	pass, err := passphrase("Enter passphrase for the token file: ")
+	if err != nil {
+		return err
+	}
+	content, err := encryptToken(token, pass)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		return err
 	}
-	return err
+	fmt.Printf("** encrypted token written to %s\n", path)
+	return nil
 }
 
 // see https://stackoverflow.com/questions/2137357/getpasswd-functionality-in-go
@@ -85,7 +195,7 @@ func getPrivateToken(host string, username string, password string) (string, err
 		Login: &username,
 		Password: &password,
 	}
-	loginClient, err := getLoginClient(host)
+	loginClient, err := getLoginClient(host, "", false)
 	if err != nil { return "", err }
 	session, _, err := loginClient.Session.GetSession(opts)
 	if err != nil {
@@ -94,7 +204,35 @@ func getPrivateToken(host string, username string, password string) (string, err
 	return session.PrivateToken, nil
 }
 
-func getLoginClient(host string) (*gitlab.Client, error) {
+func getOAuthToken(host string, username string, password string) (*oauthConfig, error) {
+	client, err := initHttpClient()
+	if err != nil {
+		return nil, err
+	}
+	return oauthPasswordGrant(client, host, username, password, &oauthConfig{ClientId: oauthClientId, ClientSecret: oauthClientSecret})
+}
+
+// checkLogin returns the user the token belongs to and the version of the Gitlab server
+func checkLogin(host string, token string, oauthTokens *oauthConfig) (*gitlab.User, string, error) {
+	loginClient, err := getLoginClient(host, token, false)
+	if oauthTokens != nil {
+		loginClient, err = getLoginClient(host, oauthTokens.AccessToken, true)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	user, _, err := loginClient.Users.CurrentUser()
+	if err != nil {
+		return nil, "", fmt.Errorf("could not get the user of the token: %s", err)
+	}
+	version := "unknown"
+	if v, _, err := loginClient.Version.GetVersion(); err == nil {
+		version = v.Version
+	}
+	return user, version, nil
+}
+
+func getLoginClient(host string, token string, oauthToken bool) (*gitlab.Client, error) {
 	baseUrl, err := url.Parse(host)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("could not parse given host '%s': %s", baseUrl, err))
@@ -105,14 +243,22 @@ func getLoginClient(host string) (*gitlab.Client, error) {
 		return nil, err
 	}
 
-	loginClient := gitlab.NewClient(client, viper.GetString("token"))
+	loginClient := gitlab.NewClient(client, token)
+	if oauthToken {
+		loginClient = gitlab.NewOAuthClient(client, token)
+	}
 	loginClient.SetBaseURL(baseUrl.String() + "/api/v4")
 	return loginClient, nil
 }
 
 func init() {
 	loginCmd.PersistentFlags().StringVarP(&host, "host", "s", "", "(required) URL to Gitlab server eg. http://gitlab.org")
-	loginCmd.PersistentFlags().StringVarP(&username, "user", "u", "", "(required) username")
+	loginCmd.PersistentFlags().StringVarP(&username, "user", "u", "", "(required unless --token is given) username")
 	loginCmd.PersistentFlags().StringVarP(&password, "password", "p", "", "(optional) password, if not given, you'll be prompted interactively")
+	loginCmd.PersistentFlags().StringVar(&loginToken, "token", "", "(optional) personal access token to log in with instead of username and password")
+	loginCmd.PersistentFlags().BoolVar(&oauth, "oauth", false, "(optional) get an OAuth2 token with username and password instead of using the session API")
+	loginCmd.PersistentFlags().StringVar(&oauthClientId, "oauth-client-id", "", "(optional) application ID for --oauth, if Gitlab requires one")
+	loginCmd.PersistentFlags().StringVar(&oauthClientSecret, "oauth-client-secret", "", "(optional) application secret for --oauth, if Gitlab requires one")
+	loginCmd.PersistentFlags().BoolVar(&encrypt, "encrypt", false, "(optional) store the token in a file encrypted with a passphrase instead of the config file")
 	RootCmd.AddCommand(loginCmd)
 }
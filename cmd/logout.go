@@ -0,0 +1,78 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove stored credentials",
+	Long: `Removes the token or the OAuth2 tokens of the selected context (or the top-level settings) from the
+config file and deletes its encrypted token file. Tokens provided by a token_command are not touched.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, path, err := loadGolabConfig()
+		if err != nil {
+			return err
+		}
+		url, token, tokenCommand, tokenFile, oauthTokens := &conf.Url, &conf.Token, conf.TokenCommand, &conf.TokenFile, &conf.OAuth
+		if name := selectedContextName(conf); name != "" {
+			context, ok := conf.Contexts[name]
+			if !ok {
+				return fmt.Errorf("context '%s' does not exist in %s", name, path)
+			}
+			url, token, tokenCommand, tokenFile, oauthTokens = &context.Url, &context.Token, context.TokenCommand, &context.TokenFile, &context.OAuth
+		}
+		if *token == "" && *tokenFile == "" && *oauthTokens == nil {
+			if tokenCommand != "" {
+				fmt.Printf("** the token for %s is provided by token_command '%s', nothing to remove\n", *url, tokenCommand)
+				return nil
+			}
+			fmt.Printf("** no stored credentials for %s found in %s\n", *url, path)
+			return nil
+		}
+		if *tokenFile != "" {
+			file, err := homedir.Expand(*tokenFile)
+			if err != nil {
+				return err
+			}
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			fmt.Printf("** token file %s deleted\n", file)
+		}
+		*token, *tokenFile, *oauthTokens = "", "", nil
+		if err := writeGolabConfig(conf, path); err != nil {
+			return err
+		}
+		fmt.Printf("** removed credentials for %s from %s\n", *url, path)
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(logoutCmd)
+}
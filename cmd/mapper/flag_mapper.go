@@ -22,35 +22,75 @@ package mapper
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"reflect"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/xanzy/go-gitlab"
 	"time"
 	"strings"
 	"strconv"
 )
 
+// ValidationError is returned if the value of a flag cannot be mapped to the options of a command,
+// Source tells where a value that was not given on the command line comes from
+type ValidationError struct {
+	Flag     string
+	Value    string
+	Accepted string
+	Source   string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("invalid value '%s' for --%s (from %s): expected %s", e.Value, e.Flag, e.Source, e.Accepted)
+	}
+	return fmt.Sprintf("invalid value '%s' for --%s: expected %s", e.Value, e.Flag, e.Accepted)
+}
+
+// AccessLevels describes the values accepted for access levels
+const AccessLevels = "10 (guest), 20 (reporter), 30 (developer), 40 (master) or 50 (owner)"
+
+const isoDate = "a date like 2017-12-31"
+
+// ChoicesAnnotation is the annotation of flags with a `choices` tag, it holds the accepted values, e.g. for completion
+const ChoicesAnnotation = "golab_choices"
+
+// EnvAnnotation is the annotation of flags with an `env` tag, it holds the name of the environment variable
+const EnvAnnotation = "golab_env"
+
+// Stdin is read for values of flags with a `file` tag that are given as `-`
+var Stdin io.Reader = os.Stdin
+
+// ConfigDefault returns the default value of a flag of the command from the configuration, ok is false if there is none
+type ConfigDefault func(cmd *cobra.Command, flagName string) (values []string, source string, ok bool)
+
 type FlagMapper struct {
 	cmd   *cobra.Command
 	flags interface{}
 	opts  interface{}
+	err   error
 }
 
 func New(cmd *cobra.Command) FlagMapper {
 	return FlagMapper{cmd: cmd}
 }
 
+// InitializedMapper sets the flags on the command, errors in the flags struct are returned when mapping
 func InitializedMapper(cmd *cobra.Command, flags interface{}, opts interface{}) FlagMapper {
 	mapper := FlagMapper{
 		cmd:   cmd,
 		flags: flags,
 		opts:  opts,
 	}
-	mapper.SetFlags(flags)
+	mapper.err = mapper.SetFlags(flags)
 	return mapper
 }
 
-func (m FlagMapper) SetFlags(flags interface{}) {
+func (m FlagMapper) SetFlags(flags interface{}) error {
 	if flags != nil {
 		v := reflect.ValueOf(flags).Elem()
 		for i := 0; i < v.NumField(); i++ {
@@ -69,12 +109,19 @@ func (m FlagMapper) SetFlags(flags interface{}) {
 			case "*[]string":
 				m.cmd.PersistentFlags().StringArrayP(flagName, shortHand, nil, flagUsage(tag))
 			case "[]int":
-			    m.cmd.PersistentFlags().StringArrayP(flagName, shortHand, nil, flagUsage(tag))
+				m.cmd.PersistentFlags().StringArrayP(flagName, shortHand, nil, flagUsage(tag))
 			default:
-				panic("Unknown type " + f.Type().String())
+				return fmt.Errorf("flag --%s has unknown type %s", flagName, f.Type().String())
+			}
+			if choices := tagChoices(tag); choices != nil {
+				m.cmd.PersistentFlags().SetAnnotation(flagName, ChoicesAnnotation, choices)
+			}
+			if env := tag.Get("env"); env != "" {
+				m.cmd.PersistentFlags().SetAnnotation(flagName, EnvAnnotation, []string{env})
 			}
 		}
 	}
+	return nil
 }
 
 func flagUsage(tag reflect.StructTag) string {
@@ -86,9 +133,161 @@ func flagUsage(tag reflect.StructTag) string {
 	} else {
 		usage = "(optional) "
 	}
+	if choices := tagChoices(tag); choices != nil {
+		description += " (one of: " + strings.Join(choices, ", ") + ")"
+	}
+	if env := tag.Get("env"); env != "" {
+		description += " (default from $" + env + ")"
+	}
+	if tag.Get("file") != "" {
+		description += " (@file to read from a file, - from stdin)"
+	}
 	return usage + description
 }
 
+// ApplyDefaults sets all flags of the command that were not given on the command line to the value of their
+// environment variable (see the `env` tag) or to their default from the configuration, in this order.
+// Afterwards these flags count as given, so they also satisfy `required:"yes"`.
+func ApplyDefaults(cmd *cobra.Command, configDefault ConfigDefault) error {
+	var err error
+	cmd.InheritedFlags() // merges persistent flags into cmd.Flags() if the command was not executed yet
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed {
+			return
+		}
+		var values []string
+		var source string
+		if env := flag.Annotations[EnvAnnotation]; len(env) > 0 && os.Getenv(env[0]) != "" {
+			values, source = []string{os.Getenv(env[0])}, "$"+env[0]
+		} else if configDefault != nil {
+			var ok bool
+			if values, source, ok = configDefault(cmd, flag.Name); !ok {
+				return
+			}
+		} else {
+			return
+		}
+		for _, value := range values {
+			if setErr := cmd.Flags().Set(flag.Name, value); setErr != nil {
+				err = &ValidationError{Flag: flag.Name, Value: value, Accepted: acceptedValues(flag), Source: source}
+				return
+			}
+		}
+		cmd.Flags().SetAnnotation(flag.Name, sourceAnnotation, []string{source})
+	})
+	return err
+}
+
+// sourceAnnotation holds where ApplyDefaults took the value of a flag from
+const sourceAnnotation = "golab_source"
+
+// withSource adds the source of a default value to validation errors
+func withSource(err error, flag *pflag.Flag) error {
+	if validationErr, ok := err.(*ValidationError); ok && validationErr.Source == "" && flag != nil {
+		if source := flag.Annotations[sourceAnnotation]; len(source) > 0 {
+			validationErr.Source = source[0]
+		}
+	}
+	return err
+}
+
+func acceptedValues(flag *pflag.Flag) string {
+	switch flag.Value.Type() {
+	case "int":
+		return "a number"
+	case "bool":
+		return "true or false"
+	}
+	return "a " + flag.Value.Type()
+}
+
+// tagChoices returns the values of the `choices` tag, e.g. `choices:"asc,desc"`, nil if there is none
+func tagChoices(tag reflect.StructTag) []string {
+	if tag.Get("choices") == "" {
+		return nil
+	}
+	return strings.Split(tag.Get("choices"), ",")
+}
+
+// validateChoices returns a *ValidationError if a value of the flag is not one of the choices of its tag
+func (m FlagMapper) validateChoices(flagName string, tag reflect.StructTag) error {
+	choices := tagChoices(tag)
+	if choices == nil {
+		return nil
+	}
+	flag := m.cmd.PersistentFlags().Lookup(flagName)
+	values := []string{flag.Value.String()}
+	if flag.Value.Type() == "stringArray" {
+		values, _ = m.cmd.PersistentFlags().GetStringArray(flagName)
+	}
+	for _, value := range values {
+		if !contains(choices, value) {
+			return &ValidationError{Flag: flagName, Value: value, Accepted: "one of " + strings.Join(choices, ", ")}
+		}
+	}
+	return nil
+}
+
+// readFileValue replaces the value of a flag with a `file` tag by the content of the file for `@path` or of stdin
+// for `-`, `@@` escapes values that start with `@`. A trailing newline is removed unless the tag is `file:"raw"`.
+func (m FlagMapper) readFileValue(flagName string, tag reflect.StructTag, stdinFlag *string) error {
+	mode := tag.Get("file")
+	if mode == "" {
+		return nil
+	}
+	flag := m.cmd.PersistentFlags().Lookup(flagName)
+	value := flag.Value.String()
+	if strings.HasPrefix(value, "@@") {
+		return flag.Value.Set(value[1:])
+	}
+	if value != "-" && !strings.HasPrefix(value, "@") {
+		return nil
+	}
+	if value == "-" {
+		if *stdinFlag != "" {
+			return fmt.Errorf("--%s and --%s cannot both be read from stdin", *stdinFlag, flagName)
+		}
+		*stdinFlag = flagName
+	}
+	content, err := ReadValue(value)
+	if err != nil {
+		return fmt.Errorf("cannot read value of --%s: %s", flagName, err)
+	}
+	if mode != "raw" {
+		content = TrimNewline(content)
+	}
+	return flag.Value.Set(string(content))
+}
+
+// ReadValue returns the content of stdin for `-` and the content of the file for `@path` or `path`, the content is
+// returned unchanged to be usable for binary uploads
+func ReadValue(value string) ([]byte, error) {
+	if value == "-" {
+		return ioutil.ReadAll(Stdin)
+	}
+	return ioutil.ReadFile(strings.TrimPrefix(value, "@"))
+}
+
+// TrimNewline removes a single trailing `\n` or `\r\n`, as written by most editors and by `echo`
+func TrimNewline(content []byte) []byte {
+	if len(content) > 0 && content[len(content)-1] == '\n' {
+		content = content[:len(content)-1]
+		if len(content) > 0 && content[len(content)-1] == '\r' {
+			content = content[:len(content)-1]
+		}
+	}
+	return content
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func (m FlagMapper) AutoMap() (interface{}, interface{}, error) {
 	err := m.Map(m.flags, m.opts)
 	return m.flags, m.opts, err
@@ -103,10 +302,14 @@ func (m FlagMapper) MappedFlags() interface{} {
 }
 
 func (m FlagMapper) Map(flags interface{}, opts interface{}) error {
+	if m.err != nil {
+		return m.err
+	}
 	if flags == nil {
 		return nil
 	}
 	var optsReflected reflect.Value
+	var stdinFlag string
 	flagsReflected := reflect.ValueOf(flags).Elem()
 	if opts != nil {
 		optsReflected = reflect.ValueOf(opts).Elem()
@@ -122,12 +325,22 @@ func (m FlagMapper) Map(flags interface{}, opts interface{}) error {
 		// see https://stackoverflow.com/questions/6395076/using-reflect-how-do-you-set-the-value-of-a-struct-field
 		// see https://stackoverflow.com/questions/40060131/reflect-assign-a-pointer-struct-value
 		if flagChanged {
+			if err := m.readFileValue(flagName, tag, &stdinFlag); err != nil {
+				return err
+			}
+			if err := m.validateChoices(flagName, tag); err != nil {
+				return withSource(err, m.cmd.PersistentFlags().Lookup(flagName))
+			}
 			fieldName := flagsReflected.Type().Field(i).Name
 			if opts != nil {
 				opt := optsReflected.FieldByName(fieldName)
-				mapOpt(opt, tag, m, flagName, flag, fieldName)
+				if err := mapOpt(opt, tag, m, flagName, flag, fieldName); err != nil {
+					return withSource(err, m.cmd.PersistentFlags().Lookup(flagName))
+				}
+			}
+			if err := mapFlag(flag, m, flagName); err != nil {
+				return err
 			}
-			mapFlag(flag, m, flagName)
 		} else {
 			if required := tag.Get("required"); required == "yes" {
 				return errors.New("required flag --" + flagName + " was empty")
@@ -137,190 +350,214 @@ func (m FlagMapper) Map(flags interface{}, opts interface{}) error {
 	return nil
 }
 
-func mapFlag(value reflect.Value, mapper FlagMapper, tagName string) {
-	mapValue(value, mapper, tagName, value)
+func mapFlag(value reflect.Value, mapper FlagMapper, tagName string) error {
+	return mapValue(value, mapper, tagName, value)
 }
 
-func mapOpt(opt reflect.Value, tag reflect.StructTag, mapper FlagMapper, flagName string, value reflect.Value, fieldName string) {
-	if opt.IsValid() {
-		// A Value can be changed only if it is addressable and was not obtained by the use of unexported struct fields.
-		if opt.CanSet() {
-			if transform := tag.Get("transform"); transform != "" {
-				value, err := mapper.cmd.PersistentFlags().GetString(flagName)
-				if err != nil {
-					panic(err.Error())
-				}
-				transformAndSet(transform, opt, value)
-			} else {
-				mapValue(value, mapper, flagName, opt)
-			}
-		} else {
-			panic(fieldName + " can not be set")
-		}
-	} else {
+func mapOpt(opt reflect.Value, tag reflect.StructTag, mapper FlagMapper, flagName string, value reflect.Value, fieldName string) error {
+	if !opt.IsValid() {
 		// for the moment, we want to ignore flags, that are not available in opts
-		// panic(fieldName + " is not valid")
+		return nil
 	}
+	// A Value can be changed only if it is addressable and was not obtained by the use of unexported struct fields.
+	if !opt.CanSet() {
+		return errors.New(fieldName + " can not be set")
+	}
+	if transform := tag.Get("transform"); transform != "" {
+		value, err := mapper.cmd.PersistentFlags().GetString(flagName)
+		if err != nil {
+			return err
+		}
+		return transformAndSet(transform, opt, flagName, value)
+	}
+	return mapValue(value, mapper, flagName, opt)
 }
 
-func mapValue(value reflect.Value, mapper FlagMapper, flagName string, opt reflect.Value) {
+func mapValue(value reflect.Value, mapper FlagMapper, flagName string, opt reflect.Value) error {
 	switch value.Type().String() {
 	case "*int":
-		mapInt(mapper, flagName, opt)
+		return mapInt(mapper, flagName, opt)
 	case "*string":
-		mapString(mapper, flagName, opt)
+		return mapString(mapper, flagName, opt)
 	case "*bool":
-		mapBool(mapper, flagName, opt)
+		return mapBool(mapper, flagName, opt)
 	case "*[]string":
-		mapStringArray(mapper, flagName, opt)
+		return mapStringArray(mapper, flagName, opt)
 	case "[]int":
-		mapIntArray(mapper, flagName, opt)
+		return mapIntArray(mapper, flagName, opt)
 	default:
-		panic("Unknown type " + value.Type().String())
+		return fmt.Errorf("flag --%s has unknown type %s", flagName, value.Type().String())
 	}
 }
 
-func mapInt(m FlagMapper, flagName string, opt reflect.Value) {
+func mapInt(m FlagMapper, flagName string, opt reflect.Value) error {
 	value, err := m.cmd.PersistentFlags().GetInt(flagName)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 	if typesMatch(opt, &value) {
 		opt.Set(reflect.ValueOf(&value))
 	}
+	return nil
 }
 
-func mapString(m FlagMapper, flagName string, opt reflect.Value) {
+func mapString(m FlagMapper, flagName string, opt reflect.Value) error {
 	value, err := m.cmd.PersistentFlags().GetString(flagName)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 	if typesMatch(opt, &value) {
 		opt.Set(reflect.ValueOf(&value))
 	}
+	return nil
 }
 
-func mapStringArray(m FlagMapper, flagName string, opt reflect.Value) {
+func mapStringArray(m FlagMapper, flagName string, opt reflect.Value) error {
 	value, err := m.cmd.PersistentFlags().GetStringArray(flagName)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 	if typesMatch(opt, &value) {
 		opt.Set(reflect.ValueOf(&value))
 	}
+	return nil
 }
 
-func mapIntArray(m FlagMapper, flagName string, opt reflect.Value) {
+func mapIntArray(m FlagMapper, flagName string, opt reflect.Value) error {
 	value, err := m.cmd.PersistentFlags().GetStringArray(flagName)
 	if err != nil {
-		panic(err.Error())
+		return err
+	}
+	// cobra does not parse "1,2,3,4" into an array, so the flag may be given several times and with comma separated values
+	arr, err := stringArray2IntArray(strings.Split(strings.Join(value, ","), ","))
+	if err != nil {
+		return &ValidationError{Flag: flagName, Value: strings.Join(value, ","), Accepted: "a comma separated list of numbers"}
 	}
-	// TODO cobra does not parse "1,2,3,4" into an array
-	sarr := strings.Split(value[0], ",")
-	arr := stringArray2IntArray(sarr)
 	if typesMatch(opt, arr) {
 		opt.Set(reflect.ValueOf(arr))
 	}
+	return nil
 }
 
-func stringArray2IntArray(s []string) []int {
+func stringArray2IntArray(s []string) ([]int, error) {
 	var result = []int{}
 	for _, i := range s {
-		j, err := strconv.Atoi(i)
+		j, err := strconv.Atoi(strings.TrimSpace(i))
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 		result = append(result, j)
 	}
-	return result
+	return result, nil
 }
 
-func mapBool(m FlagMapper, flagName string, opt reflect.Value) {
+func mapBool(m FlagMapper, flagName string, opt reflect.Value) error {
 	value, err := m.cmd.PersistentFlags().GetBool(flagName)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 	if typesMatch(opt, &value) {
 		opt.Set(reflect.ValueOf(&value))
 	}
+	return nil
 }
 
-func transformAndSet(transform string, opt reflect.Value, value string) {
+// transformAndSet calls the transform function, a *ValidationError returned by it is completed with the flag name
+func transformAndSet(transform string, opt reflect.Value, flagName string, value string) error {
 	fieldType := opt.Type()
 
 	transformedValue, err := call(funcs, transform, value)
 	if err != nil {
-		panic(err.Error())
+		return err
+	}
+	if err, ok := transformedValue[1].Interface().(error); ok && err != nil {
+		if validationErr, ok := err.(*ValidationError); ok {
+			validationErr.Flag = flagName
+		}
+		return err
 	}
 
 	opt.Set(transformedValue[0].Convert(fieldType))
+	return nil
 }
 
-func str2Visibility(s string) *gitlab.VisibilityValue {
+func str2Visibility(s string) (*gitlab.VisibilityValue, error) {
 	if s == "private" {
-		return gitlab.Visibility(gitlab.PrivateVisibility)
+		return gitlab.Visibility(gitlab.PrivateVisibility), nil
 	}
 	if s == "internal" {
-		return gitlab.Visibility(gitlab.InternalVisibility)
+		return gitlab.Visibility(gitlab.InternalVisibility), nil
 	}
 	if s == "public" {
-		return gitlab.Visibility(gitlab.PublicVisibility)
+		return gitlab.Visibility(gitlab.PublicVisibility), nil
 	}
-	return nil
+	return nil, &ValidationError{Value: s, Accepted: "private, internal or public"}
 }
 
-func string2IsoTime(s string) *gitlab.ISOTime {
+func string2IsoTime(s string) (*gitlab.ISOTime, error) {
 	iso8601 := "2006-01-02"
-	isotime, err := time.Parse(`"`+iso8601+`"`, string(s))
+	isotime, err := time.Parse(iso8601, s)
 	if err != nil {
-		panic(err.Error())
+		return nil, &ValidationError{Value: s, Accepted: isoDate}
 	}
 	t := gitlab.ISOTime(isotime)
-	return &t
+	return &t, nil
 }
 
-func str2AccessLevel(s string) *gitlab.AccessLevelValue {
-	if s == "10" {
-		return gitlab.AccessLevel(gitlab.GuestPermissions)
-	}
-	if s == "20" {
-		return gitlab.AccessLevel(gitlab.ReporterPermissions)
-	}
-	if s == "30" {
-		return gitlab.AccessLevel(gitlab.DeveloperPermissions)
-	}
-	if s == "40" {
-		return gitlab.AccessLevel(gitlab.MasterPermissions)
-	}
-	if s == "50" {
-		return gitlab.AccessLevel(gitlab.OwnerPermission)
+// AccessLevel returns the access level of the given number, e.g. 30 for developers
+func AccessLevel(level int) (*gitlab.AccessLevelValue, error) {
+	switch level {
+	case 10:
+		return gitlab.AccessLevel(gitlab.GuestPermissions), nil
+	case 20:
+		return gitlab.AccessLevel(gitlab.ReporterPermissions), nil
+	case 30:
+		return gitlab.AccessLevel(gitlab.DeveloperPermissions), nil
+	case 40:
+		return gitlab.AccessLevel(gitlab.MasterPermissions), nil
+	case 50:
+		return gitlab.AccessLevel(gitlab.OwnerPermission), nil
+	}
+	return nil, &ValidationError{Value: strconv.Itoa(level), Accepted: AccessLevels}
+}
+
+func str2AccessLevel(s string) (*gitlab.AccessLevelValue, error) {
+	level, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, &ValidationError{Value: s, Accepted: AccessLevels}
 	}
-	panic("Unknown access level: " + s)
+	return AccessLevel(level)
 }
 
-func string2Time(s string) *time.Time {
+func string2Time(s string) (*time.Time, error) {
 	t, err := time.Parse("2006-01-02", s)
 	if err != nil {
-		panic(err.Error())
+		return nil, &ValidationError{Value: s, Accepted: isoDate}
 	}
-	return &t
+	return &t, nil
 }
 
-func string2Labels(s string) gitlab.Labels {
+func string2Labels(s string) (gitlab.Labels, error) {
 	stringSlice := strings.Split(s, ",")
-	return stringSlice
+	return stringSlice, nil
 }
 
 var funcs = map[string]interface{}{
 	"string2Labels":     string2Labels,
 	"string2visibility": str2Visibility,
+	"str2Visibility":    str2Visibility,
 	"string2IsoTime":    string2IsoTime,
 	"string2Time":       string2Time,
 	"str2AccessLevel":   str2AccessLevel,
 }
 
 func call(m map[string]interface{}, name string, params ... interface{}) (result []reflect.Value, err error) {
-	f := reflect.ValueOf(m[name])
+	function, ok := m[name]
+	if !ok {
+		err = errors.New("unknown transform function " + name)
+		return
+	}
+	f := reflect.ValueOf(function)
 	if len(params) != f.Type().NumIn() {
 		err = errors.New("the number of params is not adapted")
 		return
@@ -26,6 +26,8 @@ import (
 	"bytes"
 	"os"
 	"io"
+	"io/ioutil"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -234,6 +236,243 @@ var _ = Describe("FlagMapper", func() {
 		Expect(opts.Labels).Should(ConsistOf("label1", "label2", "label3"))
 	})
 
+	It("transforms string to gitlab.ISOTime as expected", func() {
+		type isoTimeFlags struct {
+			DueDate *string `flag_name:"due_date" type:"string" transform:"string2IsoTime" required:"no" description:"due date"`
+		}
+		type isoTimeOpts struct {
+			DueDate *gitlab.ISOTime
+		}
+		flags := &isoTimeFlags{}
+		opts := &isoTimeOpts{}
+		cmd := mockCmd()
+		var mapper = InitializedMapper(cmd, flags, opts)
+
+		executeCommand(cmd, "mock", "--due_date", "2018-03-31")
+		mapper.AutoMap()
+
+		Expect(opts.DueDate.MarshalJSON()).To(Equal([]byte(`"2018-03-31"`)))
+	})
+
+	It("returns validation errors for values that cannot be transformed", func() {
+		type transformFlags struct {
+			AccessLevel *string `flag_name:"access_level" type:"string" transform:"str2AccessLevel" required:"no" description:"access level"`
+			ExpiresAt   *string `flag_name:"expires_at" type:"string" transform:"string2IsoTime" required:"no" description:"expiry date"`
+			Visibility  *string `flag_name:"visibility" type:"string" transform:"string2visibility" required:"no" description:"visibility"`
+		}
+		type transformOpts struct {
+			AccessLevel *gitlab.AccessLevelValue
+			ExpiresAt   *gitlab.ISOTime
+			Visibility  *gitlab.VisibilityValue
+		}
+		examples := map[string]string{
+			"--access_level=35":         "invalid value '35' for --access_level: expected " + AccessLevels,
+			"--access_level=master":     "invalid value 'master' for --access_level: expected " + AccessLevels,
+			"--expires_at=2017/01/01":   "invalid value '2017/01/01' for --expires_at: expected a date like 2017-12-31",
+			"--visibility=confidential": "invalid value 'confidential' for --visibility: expected private, internal or public",
+		}
+		for arg, message := range examples {
+			cmd := mockCmd()
+			mapper := InitializedMapper(cmd, &transformFlags{}, &transformOpts{})
+			executeCommand(cmd, "mock", arg)
+			_, _, err := mapper.AutoMap()
+
+			Expect(err).To(BeAssignableToTypeOf(&ValidationError{}))
+			Expect(err).To(MatchError(message))
+		}
+	})
+
+	It("returns a validation error for lists with non-numeric values", func() {
+		cmd := mockCmd()
+		mapper := InitializedMapper(cmd, &testFlags{}, &testOpts{})
+		executeCommand(cmd, "mock", "--flag1", "--flag5", "1,two")
+		_, _, err := mapper.AutoMap()
+
+		Expect(err).To(MatchError("invalid value '1,two' for --flag5: expected a comma separated list of numbers"))
+	})
+
+	It("returns an error for flags of unknown types", func() {
+		type unknownFlags struct {
+			Float *float64 `flag_name:"float" type:"float" required:"no" description:"float"`
+		}
+		mapper := InitializedMapper(mockCmd(), &unknownFlags{}, nil)
+		_, _, err := mapper.AutoMap()
+
+		Expect(err).To(MatchError("flag --float has unknown type *float64"))
+	})
+
+	It("validates flags with choices and shows the choices in the usage", func() {
+		type choiceFlags struct {
+			Sort   *string   `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"sort order"`
+			Scopes *[]string `flag_name:"scope" type:"[]string" choices:"running,pending" required:"no" description:"scopes"`
+		}
+		type choiceOpts struct {
+			Sort   *string
+			Scopes *[]string
+		}
+		cmd := mockCmd()
+		opts := &choiceOpts{}
+		mapper := InitializedMapper(cmd, &choiceFlags{}, opts)
+		flag := cmd.PersistentFlags().Lookup("sort")
+		Expect(flag.Usage).To(Equal("(optional) sort order (one of: asc, desc)"))
+		Expect(flag.Annotations[ChoicesAnnotation]).To(Equal([]string{"asc", "desc"}))
+
+		executeCommand(cmd, "mock", "--sort", "desc", "--scope", "running", "--scope", "pending")
+		_, _, err := mapper.AutoMap()
+		Expect(err).To(BeNil())
+		Expect(*opts.Sort).To(Equal("desc"))
+
+		cmd = mockCmd()
+		mapper = InitializedMapper(cmd, &choiceFlags{}, &choiceOpts{})
+		executeCommand(cmd, "mock", "--sort", "ascending")
+		_, _, err = mapper.AutoMap()
+		Expect(err).To(MatchError("invalid value 'ascending' for --sort: expected one of asc, desc"))
+
+		cmd = mockCmd()
+		mapper = InitializedMapper(cmd, &choiceFlags{}, &choiceOpts{})
+		executeCommand(cmd, "mock", "--scope", "running", "--scope", "finished")
+		_, _, err = mapper.AutoMap()
+		Expect(err).To(MatchError("invalid value 'finished' for --scope: expected one of running, pending"))
+	})
+
+	Describe("defaults", func() {
+
+		type envFlags struct {
+			Project *string `flag_name:"project" type:"string" env:"GOLAB_TEST_PROJECT" required:"yes" description:"project"`
+			Count   *int    `flag_name:"count" type:"int" env:"GOLAB_TEST_COUNT" required:"no" description:"count"`
+			Sort    *string `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"sort"`
+		}
+
+		AfterEach(func() {
+			os.Unsetenv("GOLAB_TEST_PROJECT")
+			os.Unsetenv("GOLAB_TEST_COUNT")
+		})
+
+		It("takes required flags from the environment", func() {
+			os.Setenv("GOLAB_TEST_PROJECT", "group/project")
+			cmd := mockCmd()
+			flags := &envFlags{}
+			mapper := InitializedMapper(cmd, flags, nil)
+			Expect(cmd.PersistentFlags().Lookup("project").Usage).To(Equal("(required) project (default from $GOLAB_TEST_PROJECT)"))
+
+			executeCommand(cmd, "mock")
+			Expect(ApplyDefaults(cmd, nil)).To(Succeed())
+			_, _, err := mapper.AutoMap()
+
+			Expect(err).To(BeNil())
+			Expect(*flags.Project).To(Equal("group/project"))
+		})
+
+		It("prefers the command line over the environment", func() {
+			os.Setenv("GOLAB_TEST_PROJECT", "group/project")
+			cmd := mockCmd()
+			flags := &envFlags{}
+			mapper := InitializedMapper(cmd, flags, nil)
+
+			executeCommand(cmd, "mock", "--project", "other/project")
+			_, _, err := mapper.AutoMap()
+
+			Expect(err).To(BeNil())
+			Expect(*flags.Project).To(Equal("other/project"))
+		})
+
+		It("prefers the environment over the configuration", func() {
+			os.Setenv("GOLAB_TEST_PROJECT", "group/project")
+			cmd := mockCmd()
+			InitializedMapper(cmd, &envFlags{}, nil)
+			configDefault := func(cmd *Command, flagName string) ([]string, string, bool) {
+				return []string{"from-config"}, "config", flagName != "count"
+			}
+
+			Expect(ApplyDefaults(cmd, configDefault)).To(Succeed())
+
+			Expect(cmd.Flags().Lookup("project").Value.String()).To(Equal("group/project"))
+			Expect(cmd.Flags().Lookup("sort").Value.String()).To(Equal("from-config"))
+			Expect(cmd.Flags().Lookup("count").Changed).To(BeFalse())
+		})
+
+		It("tells where invalid default values come from", func() {
+			os.Setenv("GOLAB_TEST_PROJECT", "group/project")
+			os.Setenv("GOLAB_TEST_COUNT", "many")
+			cmd := mockCmd()
+			InitializedMapper(cmd, &envFlags{}, nil)
+			Expect(ApplyDefaults(cmd, nil)).To(MatchError("invalid value 'many' for --count (from $GOLAB_TEST_COUNT): expected a number"))
+
+			os.Unsetenv("GOLAB_TEST_COUNT")
+			cmd = mockCmd()
+			mapper := InitializedMapper(cmd, &envFlags{}, nil)
+			Expect(ApplyDefaults(cmd, func(cmd *Command, flagName string) ([]string, string, bool) {
+				return []string{"random"}, "config default ls.sort", flagName == "sort"
+			})).To(Succeed())
+			_, _, err := mapper.AutoMap()
+			Expect(err).To(MatchError("invalid value 'random' for --sort (from config default ls.sort): expected one of asc, desc"))
+		})
+	})
+
+	Describe("file values", func() {
+
+		type fileFlags struct {
+			Key         *string `flag_name:"key" type:"string" file:"yes" required:"no" description:"key"`
+			Description *string `flag_name:"description" type:"string" file:"raw" required:"no" description:"description"`
+			Title       *string `flag_name:"title" type:"string" required:"no" description:"title"`
+		}
+
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "golab-mapper")
+			Expect(err).To(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "value.txt"), []byte("ssh-rsa AAAA\r\n"), 0600)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+			Stdin = os.Stdin
+		})
+
+		mapFileFlags := func(args ...string) (*fileFlags, error) {
+			cmd := mockCmd()
+			mapper := InitializedMapper(cmd, &fileFlags{}, nil)
+			executeCommand(cmd, append([]string{"mock"}, args...)...)
+			flags, _, err := mapper.AutoMap()
+			return flags.(*fileFlags), err
+		}
+
+		It("reads values from files and removes a trailing newline", func() {
+			flags, err := mapFileFlags("--key", "@"+filepath.Join(dir, "value.txt"), "--description", "@"+filepath.Join(dir, "value.txt"))
+			Expect(err).To(BeNil())
+			Expect(*flags.Key).To(Equal("ssh-rsa AAAA"))
+			Expect(*flags.Description).To(Equal("ssh-rsa AAAA\r\n"))
+		})
+
+		It("reads values from stdin", func() {
+			Stdin = strings.NewReader("line 1\nline 2\n")
+			flags, err := mapFileFlags("--description", "-")
+			Expect(err).To(BeNil())
+			Expect(*flags.Description).To(Equal("line 1\nline 2\n"))
+		})
+
+		It("reads stdin for one flag only", func() {
+			Stdin = strings.NewReader("value")
+			_, err := mapFileFlags("--key", "-", "--description", "-")
+			Expect(err).To(MatchError("--key and --description cannot both be read from stdin"))
+		})
+
+		It("keeps values that start with @@ or flags without file tag", func() {
+			flags, err := mapFileFlags("--description", "@@john please review", "--title", "@john")
+			Expect(err).To(BeNil())
+			Expect(*flags.Description).To(Equal("@john please review"))
+			Expect(*flags.Title).To(Equal("@john"))
+		})
+
+		It("returns an error for files that cannot be read", func() {
+			_, err := mapFileFlags("--key", "@"+filepath.Join(dir, "missing.txt"))
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(HavePrefix("cannot read value of --key: open "))
+		})
+	})
+
 	It("silently ignores properties in flags that are not available in opts", func() {
 		flags := &testFlagsWithPropertyNotInOpts{}
 		opts := &testOptsWithMissingProperty{}
@@ -0,0 +1,134 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// memberSyncChange is a change of a target member made by `group-members sync` and `project-members sync`
+type memberSyncChange struct {
+	Action              string `json:"action"`
+	Id                  int    `json:"id"`
+	Username            string `json:"username"`
+	AccessLevel         int    `json:"access_level"`
+	ExpiresAt           string `json:"expires_at"`
+	PreviousAccessLevel int    `json:"previous_access_level,omitempty"`
+	PreviousExpiresAt   string `json:"previous_expires_at,omitempty"`
+	Status              string `json:"status,omitempty"` // applied, failed, skipped or, with --dry-run, planned
+	Error               string `json:"error,omitempty"`
+}
+
+// memberSyncOptions select which differences between source and target members are synchronized
+type memberSyncOptions struct {
+	update         bool // update access level and expiry date of members in source and target
+	remove         bool // remove target members that are no source members
+	maxAccessLevel int  // access level given to source members with a higher access level, 0 for no limit
+}
+
+// planMemberSync compares source and target members and returns the changes that sync the target, sorted by action
+// and username. Members are matched by username.
+func planMemberSync(source map[string]*liveMember, target map[string]*liveMember, options memberSyncOptions) []*memberSyncChange {
+	changes := []*memberSyncChange{}
+	var updates []*memberSyncChange
+	for _, username := range sortedMemberNames(source) {
+		member := source[username]
+		accessLevel := member.accessLevel
+		if options.maxAccessLevel > 0 && accessLevel > options.maxAccessLevel {
+			accessLevel = options.maxAccessLevel
+		}
+		current, exists := target[username]
+		if !exists {
+			changes = append(changes, &memberSyncChange{Action: "add", Id: member.id, Username: username, AccessLevel: accessLevel, ExpiresAt: member.expiresAt})
+		} else if options.update && (current.accessLevel != accessLevel || current.expiresAt != member.expiresAt) {
+			updates = append(updates, &memberSyncChange{Action: "update", Id: current.id, Username: username, AccessLevel: accessLevel, ExpiresAt: member.expiresAt,
+				PreviousAccessLevel: current.accessLevel, PreviousExpiresAt: current.expiresAt})
+		}
+	}
+	changes = append(changes, updates...)
+	if options.remove {
+		for _, username := range sortedMemberNames(target) {
+			if _, exists := source[username]; !exists {
+				member := target[username]
+				changes = append(changes, &memberSyncChange{Action: "remove", Id: member.id, Username: username, AccessLevel: member.accessLevel, ExpiresAt: member.expiresAt})
+			}
+		}
+	}
+	return changes
+}
+
+// applyMemberSync makes the changes with ops and records their status, it stops at the first change that fails and
+// marks the remaining changes as skipped
+func applyMemberSync(ops memberOps, changes []*memberSyncChange) error {
+	var failure error
+	for _, c := range changes {
+		if failure != nil {
+			c.Status = "skipped"
+			continue
+		}
+		var err error
+		member := &memberManifest{Username: c.Username, AccessLevel: c.AccessLevel, ExpiresAt: c.ExpiresAt}
+		switch c.Action {
+		case "add":
+			err = ops.add(c.Id, member)
+		case "update":
+			err = ops.edit(c.Id, member)
+		case "remove":
+			err = ops.remove(c.Id)
+		}
+		switch {
+		case err != nil:
+			c.Status, c.Error = "failed", err.Error()
+			failure = fmt.Errorf("could not %s member %s of %s: %s", c.Action, c.Username, ops.resource, err)
+		case dryRun:
+			c.Status = "planned"
+		default:
+			c.Status = "applied"
+		}
+	}
+	return failure
+}
+
+// syncMembers syncs the target members with the source members and prints the changes with their status, also if a
+// change failed. With --dry-run the changing requests are printed instead of sent.
+func syncMembers(source map[string]*liveMember, target map[string]*liveMember, ops memberOps, options memberSyncOptions) error {
+	changes := planMemberSync(source, target, options)
+	err := applyMemberSync(ops, changes)
+	if outputErr := Output(changes); err == nil {
+		return outputErr
+	}
+	return err
+}
+
+func sortedMemberNames(members map[string]*liveMember) []string {
+	var usernames []string
+	for username := range members {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+	return usernames
+}
+
+// projectMaxAccessLevel is the highest access level of members of projects in groups
+const projectMaxAccessLevel = int(gitlab.MasterPermissions)
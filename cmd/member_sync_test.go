@@ -0,0 +1,90 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("member sync", func() {
+
+	var source, target map[string]*liveMember
+
+	BeforeEach(func() {
+		source = map[string]*liveMember{
+			"alice": {id: 1, accessLevel: 50, expiresAt: "2030-12-31"},
+			"bob":   {id: 2, accessLevel: 30},
+			"carol": {id: 3, accessLevel: 20},
+		}
+		target = map[string]*liveMember{
+			"bob":   {id: 2, accessLevel: 40},
+			"carol": {id: 3, accessLevel: 20},
+			"dave":  {id: 4, accessLevel: 30, expiresAt: "2029-01-01"},
+		}
+	})
+
+	It("only adds missing members by default", func() {
+		changes := planMemberSync(source, target, memberSyncOptions{})
+		Expect(changes).To(Equal([]*memberSyncChange{
+			{Action: "add", Id: 1, Username: "alice", AccessLevel: 50, ExpiresAt: "2030-12-31"},
+		}))
+	})
+
+	It("updates access levels and removes members that are not in source", func() {
+		changes := planMemberSync(source, target, memberSyncOptions{update: true, remove: true, maxAccessLevel: 40})
+		Expect(changes).To(Equal([]*memberSyncChange{
+			{Action: "add", Id: 1, Username: "alice", AccessLevel: 40, ExpiresAt: "2030-12-31"},
+			{Action: "update", Id: 2, Username: "bob", AccessLevel: 30, PreviousAccessLevel: 40},
+			{Action: "remove", Id: 4, Username: "dave", AccessLevel: 30, ExpiresAt: "2029-01-01"},
+		}))
+	})
+
+	It("returns an empty list if source and target match", func() {
+		Expect(planMemberSync(target, target, memberSyncOptions{update: true, remove: true})).To(BeEmpty())
+	})
+
+	It("stops at the first change that fails and records the status of every change", func() {
+		var added []int
+		ops := memberOps{
+			resource: "group g",
+			add: func(userId int, member *memberManifest) error {
+				added = append(added, userId)
+				return nil
+			},
+			edit: func(userId int, member *memberManifest) error {
+				return errors.New("403 Forbidden")
+			},
+		}
+		changes := planMemberSync(source, target, memberSyncOptions{update: true, remove: true})
+		err := applyMemberSync(ops, changes)
+		Expect(err).To(MatchError("could not update member bob of group g: 403 Forbidden"))
+		Expect(added).To(Equal([]int{1}))
+		var statuses []string
+		for _, c := range changes {
+			statuses = append(statuses, c.Status)
+		}
+		Expect(statuses).To(Equal([]string{"applied", "failed", "skipped"}))
+		Expect(changes[1].Error).To(Equal("403 Forbidden"))
+	})
+})
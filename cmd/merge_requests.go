@@ -29,10 +29,11 @@ import (
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#merge-requests-api
 var mergeRequestsCmd = &cobra.Command{
-	Use:     "merge-requests",
-	Aliases: []string{"mr"},
-	Short:   "Manage Merge Requests",
-	Long:    `Show, create, edit and delte Merge Requests`,
+	Use:         "merge-requests",
+	Aliases:     []string{"mr"},
+	Short:       "Manage Merge Requests",
+	Long:        `Show, create, edit and delte Merge Requests`,
+	Annotations: map[string]string{idResourceAnnotation: "project"},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return errors.New("this command cannot be run without a sub-command")
 	},
@@ -40,15 +41,15 @@ var mergeRequestsCmd = &cobra.Command{
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#list-merge-requests
 type mergeRequestsListFlags struct {
-	State           *string `flag_name:"state" type:"string" required:"no" description:"Return all merge requests or just those that are opened, closed, or merged"`
-	OrderBy         *string `flag_name:"order_by" type:"string" required:"no" description:"Return requests ordered by created_at or updated_at fields. Default is created_at"`
-	Sort            *string `flag_name:"sort" type:"string" required:"no" description:"Return requests sorted in asc or desc order. Default is desc"`
+	State           *string `flag_name:"state" type:"string" choices:"opened,closed,merged" required:"no" description:"Return all merge requests or just those that are opened, closed, or merged"`
+	OrderBy         *string `flag_name:"order_by" type:"string" choices:"created_at,updated_at" required:"no" description:"Return requests ordered by created_at or updated_at fields. Default is created_at"`
+	Sort            *string `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"Return requests sorted in asc or desc order. Default is desc"`
 	Milestone       *string `flag_name:"milestone" type:"string" required:"no" description:"Return merge requests for a specific milestone"`
-	View            *string `flag_name:"view" type:"string" required:"no" description:"If simple, returns the iid, URL, title, description, and basic state of merge request"`
+	View            *string `flag_name:"view" type:"string" choices:"simple" required:"no" description:"If simple, returns the iid, URL, title, description, and basic state of merge request"`
 	Labels          *string `flag_name:"labels" type:"string" required:"no" description:"Return merge requests matching a comma separated list of labels"`
 	CreatedAfter    *string `flag_name:"created_after" type:"datetime" required:"no" description:"Return merge requests created after the given time (inclusive)"`
 	CreatedBefore   *string `flag_name:"created_before" type:"datetime" required:"no" description:"Return merge requests created before the given time (inclusive)"`
-	Scope           *string `flag_name:"scope" type:"string" required:"no" description:"Return merge requests for the given scope: created-by-me, assigned-to-me or all. Defaults to created-by-me"`
+	Scope           *string `flag_name:"scope" type:"string" choices:"created-by-me,assigned-to-me,all" required:"no" description:"Return merge requests for the given scope: created-by-me, assigned-to-me or all. Defaults to created-by-me"`
 	AuthorId        *int    `flag_name:"author_id" type:"integer" required:"no" description:"Returns merge requests created by the given user id. Combine with scope=all or scope=assigned-to-me"`
 	AssigneeId      *int    `flag_name:"assignee_id" type:"integer" required:"no" description:"Returns merge requests assigned to the given user id"`
 	MyReactionEmoji *string `flag_name:"my_reaction_emoji" type:"string" required:"no" description:"Return merge requests reacted by the authenticated user by the given emoji (Introduced in GitLab 10.0)"`
@@ -69,11 +70,9 @@ Note: the changes_count value in the response is a string, not an integer. This
 	},
 	Run: func(cmd golabCommand) error {
 		opts := cmd.Opts.(*gitlab.ListMergeRequestsOptions)
-		mergeRequests, _, err := gitlabClient.MergeRequests.ListMergeRequests(opts)
-		if err != nil {
-			return err
-		}
-		return OutputJson(mergeRequests)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.MergeRequests.ListMergeRequests(opts, page)
+		})
 	},
 }
 
@@ -81,15 +80,15 @@ Note: the changes_count value in the response is a string, not an integer. This
 type mergeRequestsListForProjectFlags struct {
 	Id              *int    `flag_name:"id" type:"integer" required:"yes" description:"The ID of a project"`
 	IIDs            []int   `flag_name:"iids" type:"Array[integer]" required:"no" description:"Return the request having the given iid"`
-	State           *string `flag_name:"state" type:"string" required:"no" description:"Return all merge requests or just those that are opened, closed, or merged"`
-	OrderBy         *string `flag_name:"order_by" type:"string" required:"no" description:"Return requests ordered by created_at or updated_at fields. Default is created_at"`
-	Sort            *string `flag_name:"sort" type:"string" required:"no" description:"Return requests sorted in asc or desc order. Default is desc"`
+	State           *string `flag_name:"state" type:"string" choices:"opened,closed,merged" required:"no" description:"Return all merge requests or just those that are opened, closed, or merged"`
+	OrderBy         *string `flag_name:"order_by" type:"string" choices:"created_at,updated_at" required:"no" description:"Return requests ordered by created_at or updated_at fields. Default is created_at"`
+	Sort            *string `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"Return requests sorted in asc or desc order. Default is desc"`
 	Milestone       *string `flag_name:"milestone" type:"string" required:"no" description:"Return merge requests for a specific milestone"`
-	View            *string `flag_name:"view" type:"string" required:"no" description:"If simple, returns the iid, URL, title, description, and basic state of merge request"`
+	View            *string `flag_name:"view" type:"string" choices:"simple" required:"no" description:"If simple, returns the iid, URL, title, description, and basic state of merge request"`
 	Labels          *string `flag_name:"labels" type:"[]string" transform:"string2Labels" required:"no" description:"Return merge requests matching a comma separated list of labels"`
 	CreatedAfter    *string `flag_name:"created_after" type:"datetime" required:"no" description:"Return merge requests created after the given time (inclusive)"`
 	CreatedBefore   *string `flag_name:"created_before" type:"datetime" required:"no" description:"Return merge requests created before the given time (inclusive)"`
-	Scope           *string `flag_name:"scope" type:"string" required:"no" description:"Return merge requests for the given scope: created-by-me, assigned-to-me or all (Introduced in GitLab 9.5)"`
+	Scope           *string `flag_name:"scope" type:"string" choices:"created-by-me,assigned-to-me,all" required:"no" description:"Return merge requests for the given scope: created-by-me, assigned-to-me or all (Introduced in GitLab 9.5)"`
 	AuthorID        *int    `flag_name:"author_id" type:"integer" required:"no" description:"Returns merge requests created by the given user id (Introduced in GitLab 9.5)"`
 	AssigneeID      *int    `flag_name:"assignee_id" type:"integer" required:"no" description:"Returns merge requests assigned to the given user id (Introduced in GitLab 9.5)"`
 	MyReactionEmoji *string `flag_name:"my_reaction_emoji" type:"string" required:"no" description:"Return merge requests reacted by the authenticated user by the given emoji (Introduced in GitLab 10.0)"`
@@ -107,17 +106,15 @@ var mergeRequestsListForProjectCmd = &golabCommand{
 	Run: func(cmd golabCommand) error {
 		flags := cmd.Flags.(*mergeRequestsListForProjectFlags)
 		opts := cmd.Opts.(*gitlab.ListProjectMergeRequestsOptions)
-		mrs, _, err := gitlabClient.MergeRequests.ListProjectMergeRequests(*flags.Id, opts)
-		if err != nil {
-			return err
-		}
-		return OutputJson(mrs)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.MergeRequests.ListProjectMergeRequests(*flags.Id, opts, page)
+		})
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#get-single-mr
 type mergeRequestGetFlags struct {
-	Id  *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id  *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	Iid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -135,13 +132,13 @@ var mergeRequestGetCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(mr)
+		return Output(mr)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#get-single-mr-commits
 type mergeRequestGetCommitsFlags struct {
-	Id  *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id  *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	Iid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -159,13 +156,13 @@ var mergeRequestsGetCommitsCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(commits)
+		return Output(commits)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#get-single-mr-changes
 type mergeRequestsGetChangesFlags struct {
-	Id  *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id  *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	Iid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -183,18 +180,18 @@ var mergeRequestsGetChangesCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(changes)
+		return Output(changes)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#create-mr
 type mergeRequestsCreateFlags struct {
-	Id                 *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Id                 *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
 	SourceBranch       *string `flag_name:"source_branch" short:"s" type:"string" required:"yes" description:"The source branch"`
 	TargetBranch       *string `flag_name:"target_branch" short:"t" type:"string" required:"yes" description:"The target branch"`
 	Title              *string `flag_name:"title" short:"n" type:"string" required:"yes" description:"Title of MR"`
 	AssigneeId         *int    `flag_name:"assignee_id" short:"a" type:"integer" required:"no" description:"Assignee user ID"`
-	Description        *string `flag_name:"description" short:"d" type:"string" required:"no" description:"Description of MR"`
+	Description        *string `flag_name:"description" short:"d" type:"string" file:"raw" required:"no" description:"Description of MR"`
 	TargetProjectId    *int    `flag_name:"target_project_id" type:"integer" required:"no" description:"The target project (numeric id)"`
 	Labels             *string `flag_name:"labels" type:"[]string" transform:"string2Labels" required:"no" description:"Labels for MR as a comma-separated list"`
 	MilestoneId        *int    `flag_name:"milestone_id" type:"integer" required:"no" description:"The ID of a milestone"`
@@ -217,19 +214,19 @@ var mergeRequestsCreateCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(mr)
+		return Output(mr)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#update-mr
 type mergeRequestUpdateFlags struct {
-	Id                 *string `flag_name:"id" short:"i" type:"integer/string" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Id                 *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
 	MergeRequestIid    *int    `flag_name:"merge_request_iid" short:"m" type:"integer" required:"yes" description:"The ID of a merge request"`
 	TargetBranch       *string `flag_name:"target_branch" type:"string" required:"no" description:"The target branch"`
 	Title              *string `flag_name:"title" type:"string" required:"no" description:"Title of MR"`
 	AssigneeId         *int    `flag_name:"assignee_id" type:"integer" required:"no" description:"Assignee user ID"`
-	Description        *string `flag_name:"description" type:"string" required:"no" description:"Description of MR"`
-	StateEvent         *string `flag_name:"state_event" type:"string" required:"no" description:"New state (close/reopen)"`
+	Description        *string `flag_name:"description" type:"string" file:"raw" required:"no" description:"Description of MR"`
+	StateEvent         *string `flag_name:"state_event" type:"string" choices:"close,reopen" required:"no" description:"New state (close/reopen)"`
 	Labels             *string `flag_name:"labels" type:"[]string" transform:"string2Labels" required:"no" description:"Labels for MR as a comma-separated list"`
 	MilestoneId        *int    `flag_name:"milestone_id" type:"integer" required:"no" description:"The ID of a milestone"`
 	RemoveSourceBranch *bool   `flag_name:"remove_source_branch" type:"boolean" required:"no" description:"Flag indicating if a merge request should remove the source branch when merging"`
@@ -252,13 +249,13 @@ var mergeRequestUpdateCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(mr)
+		return Output(mr)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#delete-a-merge-request
 type mergeRequestsDeleteFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -279,7 +276,7 @@ var mergeRequestsDeleteCmd = &golabCommand{
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#accept-mr
 type mergeRequestAcceptFlags struct {
-	Id                        *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Id                        *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
 	MergeRequestIid           *int    `flag_name:"merge_request_iid" short:"m" type:"int" required:"yes" description:"Internal ID of MR"`
 	MergeCommitMessage        *string `flag_name:"merge_commit_message" type:"string" required:"no" description:"Custom merge commit message"`
 	ShouldRemoveSourceBranch  *bool   `flag_name:"should_remove_source_branch" short:"d" type:"bool" required:"no" description:"if true removes the source branch"`
@@ -311,13 +308,13 @@ If you don't have permissions to accept this merge request - you'll get a 401`,
 		if err != nil {
 			return err
 		}
-		return OutputJson(mr)
+		return Output(mr)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#cancel-merge-when-pipeline-succeeds
 type mergeRequestsCancelPipelineSucceedsFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -339,13 +336,13 @@ In case the merge request is not set to be merged when the pipeline succeeds, yo
 		if err != nil {
 			return err
 		}
-		return OutputJson(mr)
+		return Output(mr)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#list-issues-that-will-close-on-merge
 type mergeRequestsClosedIssuesUponMergeFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -363,13 +360,13 @@ var mergeRequestsClosedIssuesUponMergeCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(issues)
+		return Output(issues)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#subscribe-to-a-merge-request
 type mergeRequestsSubscribeFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -390,13 +387,13 @@ var mergeRequestsSubscribeCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(mr)
+		return Output(mr)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#unsubscribe-from-a-merge-request
 type mergeRequestsUnsubscribeFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -417,13 +414,13 @@ var mergeRequestsUnsubscribeCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(mr)
+		return Output(mr)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#create-a-todo
 type mergeRequestsCreateTodoFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -445,13 +442,13 @@ var mergeRequestsCreateTodoCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(mr)
+		return Output(mr)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#get-mr-diff-versions
 type mergeRequestListDiffVersionsFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -469,13 +466,13 @@ var mergeRequestListDiffVersionsCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(versions)
+		return Output(versions)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#get-a-single-mr-diff-version
 type mergeRequestsGetSingleDiffVersionFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 	VersionId       *int    `flag_name:"version_id" short:"v" type:"integer" required:"yes" description:"The ID of the merge request diff version"`
 }
@@ -494,13 +491,13 @@ var mergeRequestGetSingleDiffVersionCmd = &golabCommand{
 		if err != nil {
 		    return err
 		}
-		return OutputJson(version)
+		return Output(version)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#set-a-time-estimate-for-a-merge-request
 type mergeRequestsSetTimeEstimateFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 	Duration        *string `flag_name:"duration" short:"d" type:"string" required:"yes" description:"The duration in human format. e.g: 3h30m"`
 }
@@ -521,13 +518,13 @@ var mergeRequestsSetTimeEstimateCmd = &golabCommand{
 		if err != nil {
 		    return err
 		}
-		return OutputJson(timeStats)
+		return Output(timeStats)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#reset-the-time-estimate-for-a-merge-request
 type mergeRequestResetTimeEstimateFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -545,13 +542,13 @@ var mergeRequestsResetTimeEstimateCmd = &golabCommand{
 		if err != nil {
 		    return err
 		}
-		return OutputJson(timeStats)
+		return Output(timeStats)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#add-spent-time-for-a-merge-request
 type mergeRequestsAddSpentTimeFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 	Duration        *string `flag_name:"duration" short:"d" type:"string" required:"yes" description:"The duration in human format. e.g: 3h30m"`
 }
@@ -572,13 +569,13 @@ var mergeRequestsAddSpentTimeCmd = &golabCommand{
 		if err != nil {
 		    return err
 		}
-		return OutputJson(timeStats)
+		return Output(timeStats)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#reset-spent-time-for-a-merge-request
 type mergeRequestsResetSpentTimeFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -596,13 +593,13 @@ var mergeRequestsResetSpentTimeCmd = &golabCommand{
 		if err != nil {
 		    return err
 		}
-		return OutputJson(timeStats)
+		return Output(timeStats)
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/merge_requests.html#get-time-tracking-stats
 type mergeRequestsGetTimeTrackingStatsFlags struct {
-	Id              *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL encoded path of a project"`
+	Id              *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL encoded path of a project"`
 	MergeRequestIid *int    `flag_name:"iid" short:"m" type:"integer" required:"yes" description:"The internal ID of the merge request"`
 }
 
@@ -620,13 +617,15 @@ var mergeRequestsGetTimeTrackingStatsCmd = &golabCommand{
 		if err != nil {
 		    return err
 		}
-		return OutputJson(stats)
+		return Output(stats)
 	},
 }
 
 func init() {
 	mergeRequestsListCmd.Init()
+	initPaginationFlags(mergeRequestsListCmd.Cmd)
 	mergeRequestsListForProjectCmd.Init()
+	initPaginationFlags(mergeRequestsListForProjectCmd.Cmd)
 	mergeRequestGetCmd.Init()
 	mergeRequestsGetCommitsCmd.Init()
 	mergeRequestsGetChangesCmd.Init()
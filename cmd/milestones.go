@@ -0,0 +1,222 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/xanzy/go-gitlab"
+)
+
+// see https://docs.gitlab.com/ce/api/milestones.html
+var milestonesCmd = &golabCommand{
+	Parent: RootCmd,
+	Cmd: &cobra.Command{
+		Use:         "milestones",
+		Aliases:     []string{"milestone"},
+		Short:       "Milestones",
+		Long:        `Manage project milestones and list their issues`,
+		Annotations: map[string]string{idResourceAnnotation: "project"},
+	},
+	Run: func(cmd golabCommand) error {
+		return errors.New("cannot use this command without further sub-commands")
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/milestones.html#list-project-milestones
+type milestonesListFlags struct {
+	Id     *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	IIDs   []int   `flag_name:"iids" type:"Array[integer]" required:"no" description:"Return only the milestones having the given iids"`
+	State  *string `flag_name:"state" short:"s" type:"string" choices:"active,closed" required:"no" description:"Return only active or closed milestones"`
+	Search *string `flag_name:"search" type:"string" required:"no" description:"Return only milestones with a title or description matching the provided string"`
+}
+
+var milestonesListCmd = &golabCommand{
+	Parent: milestonesCmd.Cmd,
+	Flags:  &milestonesListFlags{},
+	Opts:   &gitlab.ListMilestonesOptions{},
+	Cmd: &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List project milestones",
+		Long:    `Returns a list of project milestones.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*milestonesListFlags)
+		opts := cmd.Opts.(*gitlab.ListMilestonesOptions)
+		// go-gitlab does not support state and search
+		query := map[string]string{}
+		setQuery(query, "state", flags.State)
+		setQuery(query, "search", flags.Search)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Milestones.ListMilestones(parsePid(*flags.Id), opts, withQuery(query), page)
+		})
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/milestones.html#get-single-milestone
+type milestonesGetFlags struct {
+	Id          *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	MilestoneId *int    `flag_name:"milestone_id" short:"m" type:"integer" required:"yes" description:"The ID of the project's milestone"`
+}
+
+var milestonesGetCmd = &golabCommand{
+	Parent: milestonesCmd.Cmd,
+	Flags:  &milestonesGetFlags{},
+	Cmd: &cobra.Command{
+		Use:   "get",
+		Short: "Get single milestone",
+		Long:  `Gets a single project milestone.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*milestonesGetFlags)
+		milestone, _, err := gitlabClient.Milestones.GetMilestone(parsePid(*flags.Id), *flags.MilestoneId)
+		if err != nil {
+			return err
+		}
+		return Output(milestone)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/milestones.html#create-new-milestone
+type milestonesCreateFlags struct {
+	Id          *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Title       *string `flag_name:"title" short:"t" type:"string" required:"yes" description:"The title of a milestone"`
+	Description *string `flag_name:"description" short:"d" type:"string" file:"raw" required:"no" description:"The description of the milestone"`
+	StartDate   *string `flag_name:"start_date" type:"string" required:"no" description:"The start date of the milestone (YYYY-MM-DD)"`
+	DueDate     *string `flag_name:"due_date" type:"string" required:"no" description:"The due date of the milestone (YYYY-MM-DD)"`
+}
+
+var milestonesCreateCmd = &golabCommand{
+	Parent: milestonesCmd.Cmd,
+	Flags:  &milestonesCreateFlags{},
+	Opts:   &gitlab.CreateMilestoneOptions{},
+	Cmd: &cobra.Command{
+		Use:   "create",
+		Short: "Create new milestone",
+		Long:  `Creates a new project milestone.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*milestonesCreateFlags)
+		opts := cmd.Opts.(*gitlab.CreateMilestoneOptions)
+		milestone, _, err := gitlabClient.Milestones.CreateMilestone(parsePid(*flags.Id), opts)
+		if err != nil {
+			return err
+		}
+		return Output(milestone)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/milestones.html#edit-milestone
+type milestonesUpdateFlags struct {
+	Id          *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	MilestoneId *int    `flag_name:"milestone_id" short:"m" type:"integer" required:"yes" description:"The ID of the project's milestone"`
+	Title       *string `flag_name:"title" short:"t" type:"string" required:"no" description:"The title of a milestone"`
+	Description *string `flag_name:"description" short:"d" type:"string" file:"raw" required:"no" description:"The description of the milestone"`
+	StartDate   *string `flag_name:"start_date" type:"string" required:"no" description:"The start date of the milestone (YYYY-MM-DD)"`
+	DueDate     *string `flag_name:"due_date" type:"string" required:"no" description:"The due date of the milestone (YYYY-MM-DD)"`
+	StateEvent  *string `flag_name:"state_event" type:"string" choices:"close,activate" required:"no" description:"The state event of the milestone"`
+}
+
+var milestonesUpdateCmd = &golabCommand{
+	Parent: milestonesCmd.Cmd,
+	Flags:  &milestonesUpdateFlags{},
+	Opts:   &gitlab.UpdateMilestoneOptions{},
+	Cmd: &cobra.Command{
+		Use:   "update",
+		Short: "Edit milestone",
+		Long:  `Updates an existing project milestone, closes it with '--state_event close'.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*milestonesUpdateFlags)
+		opts := cmd.Opts.(*gitlab.UpdateMilestoneOptions)
+		milestone, _, err := gitlabClient.Milestones.UpdateMilestone(parsePid(*flags.Id), *flags.MilestoneId, opts)
+		if err != nil {
+			return err
+		}
+		return Output(milestone)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/milestones.html#delete-project-milestone
+type milestonesDeleteFlags struct {
+	Id          *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	MilestoneId *int    `flag_name:"milestone_id" short:"m" type:"integer" required:"yes" description:"The ID of the project's milestone"`
+}
+
+var milestonesDeleteCmd = &golabCommand{
+	Parent: milestonesCmd.Cmd,
+	Flags:  &milestonesDeleteFlags{},
+	Cmd: &cobra.Command{
+		Use:   "delete",
+		Short: "Delete project milestone",
+		Long:  `Deletes a project milestone, only for users with developer access to the project.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*milestonesDeleteFlags)
+		// go-gitlab cannot delete milestones
+		u := fmt.Sprintf("projects/%s/milestones/%s", url.QueryEscape(*flags.Id), strconv.Itoa(*flags.MilestoneId))
+		req, err := gitlabClient.NewRequest("DELETE", u, nil, nil)
+		if err != nil {
+			return err
+		}
+		_, err = gitlabClient.Do(req, nil)
+		return err
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/milestones.html#get-all-issues-assigned-to-a-single-milestone
+type milestonesIssuesFlags struct {
+	Id          *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	MilestoneId *int    `flag_name:"milestone_id" short:"m" type:"integer" required:"yes" description:"The ID of the project's milestone"`
+}
+
+var milestonesIssuesCmd = &golabCommand{
+	Parent: milestonesCmd.Cmd,
+	Flags:  &milestonesIssuesFlags{},
+	Cmd: &cobra.Command{
+		Use:   "issues",
+		Short: "Get all issues assigned to a single milestone",
+		Long:  `Gets all issues assigned to a single project milestone.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*milestonesIssuesFlags)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Milestones.GetMilestoneIssues(parsePid(*flags.Id), *flags.MilestoneId, &gitlab.GetMilestoneIssuesOptions{}, page)
+		})
+	},
+}
+
+func init() {
+	milestonesCmd.Init()
+	milestonesListCmd.Init()
+	initPaginationFlags(milestonesListCmd.Cmd)
+	milestonesGetCmd.Init()
+	milestonesCreateCmd.Init()
+	milestonesUpdateCmd.Init()
+	milestonesDeleteCmd.Init()
+	milestonesIssuesCmd.Init()
+	initPaginationFlags(milestonesIssuesCmd.Cmd)
+}
@@ -0,0 +1,218 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oauthConfig holds the tokens of a login with `golab login --oauth`
+type oauthConfig struct {
+	AccessToken  string `yaml:"access_token"`
+	RefreshToken string `yaml:"refresh_token,omitempty"`
+	ExpiresAt    string `yaml:"expires_at,omitempty"`
+	ClientId     string `yaml:"client_id,omitempty"`
+	ClientSecret string `yaml:"client_secret,omitempty"`
+}
+
+// see https://docs.gitlab.com/ce/api/oauth2.html
+type oauthTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	CreatedAt        int64  `json:"created_at"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestOAuthToken posts the grant to /oauth/token of the Gitlab server, client id and secret of the given
+// config are sent along and kept in the returned config
+func requestOAuthToken(client *http.Client, host string, grant url.Values, conf *oauthConfig) (*oauthConfig, error) {
+	if conf.ClientId != "" {
+		grant.Set("client_id", conf.ClientId)
+	}
+	if conf.ClientSecret != "" {
+		grant.Set("client_secret", conf.ClientSecret)
+	}
+	resp, err := client.PostForm(strings.TrimSuffix(host, "/")+"/oauth/token", grant)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	token := &oauthTokenResponse{}
+	if err := json.Unmarshal(content, token); err != nil || resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		if token.ErrorDescription != "" {
+			return nil, fmt.Errorf("could not get OAuth token: %s", token.ErrorDescription)
+		}
+		return nil, fmt.Errorf("could not get OAuth token: %s", resp.Status)
+	}
+
+	result := &oauthConfig{AccessToken: token.AccessToken, RefreshToken: token.RefreshToken, ClientId: conf.ClientId, ClientSecret: conf.ClientSecret}
+	if token.ExpiresIn > 0 {
+		createdAt := time.Now()
+		if token.CreatedAt > 0 {
+			createdAt = time.Unix(token.CreatedAt, 0)
+		}
+		result.ExpiresAt = createdAt.Add(time.Duration(token.ExpiresIn) * time.Second).UTC().Format(time.RFC3339)
+	}
+	return result, nil
+}
+
+func oauthPasswordGrant(client *http.Client, host string, username string, password string, conf *oauthConfig) (*oauthConfig, error) {
+	return requestOAuthToken(client, host, url.Values{"grant_type": {"password"}, "username": {username}, "password": {password}}, conf)
+}
+
+func refreshOAuthToken(client *http.Client, host string, conf *oauthConfig) (*oauthConfig, error) {
+	return requestOAuthToken(client, host, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {conf.RefreshToken}}, conf)
+}
+
+// expiresSoon is true if the access token expires within the next minute, tokens without expiry never expire
+func (c *oauthConfig) expiresSoon(now time.Time) bool {
+	if c.ExpiresAt == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, c.ExpiresAt)
+	return err != nil || now.Add(time.Minute).After(expiresAt)
+}
+
+// oauthTransport sends the OAuth access token with every request. It refreshes the token before it expires and
+// once more if Gitlab rejects it, refreshed tokens are saved to the config file.
+type oauthTransport struct {
+	next   http.RoundTripper
+	tokens http.RoundTripper // sends the refresh requests, they are sent even with --dry-run
+	host   string
+	oauth  *oauthConfig
+	save   func(*oauthConfig) error
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+// withoutDryRun returns the transport for token requests, a dry run must not intercept them since the
+// following requests need a valid token
+func withoutDryRun(transport http.RoundTripper) http.RoundTripper {
+	if dryRun, ok := transport.(*dryRunTransport); ok {
+		return dryRun.next
+	}
+	return transport
+}
+
+func (t *oauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	if t.oauth.RefreshToken != "" && t.oauth.expiresSoon(t.now()) {
+		if err := t.refresh(); err != nil {
+			t.mu.Unlock()
+			return nil, err
+		}
+	}
+	accessToken, canRefresh := t.oauth.AccessToken, t.oauth.RefreshToken != ""
+	t.mu.Unlock()
+
+	resp, err := t.next.RoundTrip(withBearerToken(req, accessToken))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !canRefresh || (req.Body != nil && req.GetBody == nil) {
+		return resp, err
+	}
+
+	t.mu.Lock()
+	// another request may have refreshed the token in the meantime
+	if t.oauth.AccessToken == accessToken {
+		if err := t.refresh(); err != nil {
+			t.mu.Unlock()
+			return resp, nil
+		}
+	}
+	accessToken = t.oauth.AccessToken
+	t.mu.Unlock()
+
+	resp.Body.Close()
+	retry := withBearerToken(req, accessToken)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.next.RoundTrip(retry)
+}
+
+func (t *oauthTransport) refresh() error {
+	refreshed, err := refreshOAuthToken(&http.Client{Transport: t.tokens}, t.host, t.oauth)
+	if err != nil {
+		return err
+	}
+	t.oauth = refreshed
+	return t.save(refreshed)
+}
+
+// withBearerToken returns a copy of the request that authenticates with the token instead of a private token
+func withBearerToken(req *http.Request, token string) *http.Request {
+	clone := new(http.Request)
+	*clone = *req
+	clone.Header = http.Header{}
+	for name, values := range req.Header {
+		clone.Header[name] = values
+	}
+	clone.Header.Del("Private-Token")
+	clone.Header.Set("Authorization", "Bearer "+token)
+	return clone
+}
+
+// configuredOAuth returns the OAuth tokens of the selected context or the top-level settings, nil if there are none
+func configuredOAuth() (*oauthConfig, error) {
+	conf, _, err := loadGolabConfig()
+	if err != nil {
+		return nil, err
+	}
+	if name := selectedContextName(conf); name != "" {
+		if context, ok := conf.Contexts[name]; ok {
+			return context.OAuth, nil
+		}
+		return nil, nil
+	}
+	return conf.OAuth, nil
+}
+
+// saveOAuth writes refreshed OAuth tokens to the selected context or the top-level settings of the config file
+func saveOAuth(oauth *oauthConfig) error {
+	conf, path, err := loadGolabConfig()
+	if err != nil {
+		return err
+	}
+	if name := selectedContextName(conf); name != "" {
+		context, ok := conf.Contexts[name]
+		if !ok {
+			return fmt.Errorf("context '%s' does not exist in %s", name, path)
+		}
+		context.OAuth = oauth
+	} else {
+		conf.OAuth = oauth
+	}
+	return writeGolabConfig(conf, path)
+}
@@ -0,0 +1,132 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("oauth transport", func() {
+
+	var (
+		server    *httptest.Server
+		valid     string
+		refreshes int
+		bodies    []string
+		saved     *oauthConfig
+		transport *oauthTransport
+		now       time.Time
+	)
+
+	BeforeEach(func() {
+		valid, refreshes, bodies, saved = "access-1", 0, nil, nil
+		now = time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/oauth/token" {
+				r.ParseForm()
+				if r.PostForm.Get("grant_type") != "refresh_token" || r.PostForm.Get("refresh_token") != "refresh-1" || r.PostForm.Get("client_id") != "app" {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`{"error": "invalid_grant", "error_description": "The refresh token is invalid."}`))
+					return
+				}
+				refreshes++
+				valid = "access-2"
+				w.Write([]byte(`{"access_token": "access-2", "refresh_token": "refresh-2", "expires_in": 7200, "created_at": 1506859200}`))
+				return
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			if r.Header.Get("Authorization") != "Bearer "+valid || r.Header.Get("Private-Token") != "" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}))
+		transport = &oauthTransport{
+			next:   http.DefaultTransport,
+			tokens: http.DefaultTransport,
+			host:   server.URL,
+			oauth:  &oauthConfig{AccessToken: "access-1", RefreshToken: "refresh-1", ExpiresAt: "2017-10-01T14:00:00Z", ClientId: "app"},
+			save:   func(c *oauthConfig) error { saved = c; return nil },
+			now:    func() time.Time { return now },
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	post := func() *http.Response {
+		req, err := http.NewRequest("POST", server.URL+"/api/v4/projects", strings.NewReader(`{"name": "p"}`))
+		Expect(err).To(BeNil())
+		req.Header.Set("Private-Token", "")
+		resp, err := (&http.Client{Transport: transport}).Do(req)
+		Expect(err).To(BeNil())
+		return resp
+	}
+
+	It("sends the access token as bearer token", func() {
+		Expect(post().StatusCode).To(Equal(http.StatusOK))
+		Expect(refreshes).To(Equal(0))
+	})
+
+	It("refreshes the token before it expires and saves it", func() {
+		now = now.Add(2 * time.Hour)
+		Expect(post().StatusCode).To(Equal(http.StatusOK))
+		Expect(refreshes).To(Equal(1))
+		Expect(saved).To(Equal(&oauthConfig{AccessToken: "access-2", RefreshToken: "refresh-2", ExpiresAt: "2017-10-01T14:00:00Z", ClientId: "app"}))
+	})
+
+	It("refreshes a rejected token and repeats the request with its body", func() {
+		valid = "revoked"
+		transport.oauth.AccessToken = "revoked-by-gitlab"
+		Expect(post().StatusCode).To(Equal(http.StatusOK))
+		Expect(refreshes).To(Equal(1))
+		Expect(bodies).To(Equal([]string{`{"name": "p"}`, `{"name": "p"}`}))
+	})
+
+	It("fails with the error of Gitlab if the token cannot be refreshed", func() {
+		now = now.Add(2 * time.Hour)
+		transport.oauth.RefreshToken = "unknown"
+		_, err := (&http.Client{Transport: transport}).Get(server.URL + "/api/v4/user")
+		Expect(err).To(MatchError(ContainSubstring("could not get OAuth token: The refresh token is invalid.")))
+		Expect(saved).To(BeNil())
+	})
+
+	It("refreshes the token with --dry-run and only prints the request", func() {
+		out := new(bytes.Buffer)
+		dryRun := &dryRunTransport{next: http.DefaultTransport, out: out}
+		transport.next, transport.tokens = dryRun, withoutDryRun(dryRun)
+		now = now.Add(2 * time.Hour)
+		Expect(post().StatusCode).To(Equal(http.StatusCreated))
+		Expect(refreshes).To(Equal(1))
+		Expect(saved.AccessToken).To(Equal("access-2"))
+		Expect(bodies).To(BeEmpty())
+		Expect(out.String()).To(HavePrefix("DRY RUN: POST " + server.URL + "/api/v4/projects\n"))
+		Expect(out.String()).NotTo(ContainSubstring("/oauth/token"))
+	})
+})
@@ -0,0 +1,411 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// outputFormat holds the value of the global `--output` flag, e.g. `json`, `table=id,name` or `go-template={{.id}}`
+var outputFormat = "json"
+
+// a renderer writes object to w, arg is everything after the `=` in `--output format=arg`
+type renderer func(w io.Writer, object interface{}, arg string) error
+
+var renderers = map[string]renderer{
+	"json":        renderJson,
+	"ndjson":      renderNdjson,
+	"yaml":        renderYaml,
+	"table":       renderTable,
+	"csv":         renderCsv,
+	"tsv":         renderTsv,
+	"go-template": renderGoTemplate,
+	"ids":         renderIds,
+}
+
+// streamingFormats are rendered line by line, so lists can be printed page by page while they are fetched
+var streamingFormats = []string{"ndjson", "ids", "go-template"}
+
+// defaultColumns are the columns shown for a resource in table, csv and tsv output, if no columns are given explicitly
+var defaultColumns = map[string][]string{
+	"gitlab.Project":       {"id", "path_with_namespace", "visibility", "default_branch", "web_url"},
+	"gitlab.User":          {"id", "username", "name", "state", "email"},
+	"gitlab.Group":         {"id", "full_path", "name", "visibility"},
+	"gitlab.MergeRequest":  {"iid", "title", "state", "source_branch", "target_branch", "author.username"},
+	"gitlab.Issue":         {"iid", "title", "state", "assignee.username", "labels", "web_url"},
+	"gitlab.Job":           {"id", "stage", "name", "status", "ref"},
+	"gitlab.Branch":        {"name", "protected", "merged", "commit.short_id"},
+	"gitlab.Commit":        {"short_id", "title", "author_name", "created_at"},
+	"gitlab.Tag":           {"name", "commit.short_id", "message"},
+	"gitlab.Label":         {"name", "color", "description"},
+	"gitlab.Milestone":     {"id", "iid", "title", "state", "due_date"},
+	"gitlab.Wiki":          {"slug", "title", "format"},
+	"gitlab.File":          {"file_path", "size", "ref", "blob_id"},
+	"gitlab.Diff":          {"old_path", "new_path", "new_file", "renamed_file", "deleted_file"},
+	"gitlab.CommitComment": {"author.username", "path", "line", "line_type", "note"},
+	"gitlab.CommitStatus":  {"id", "name", "status", "ref", "target_url"},
+	"gitlab.GroupMember":   {"id", "username", "name", "access_level", "expires_at"},
+	"gitlab.ProjectMember": {"id", "username", "name", "access_level", "expires_at"},
+	"cmd.projectMember":    {"id", "username", "name", "access_level", "expires_at"},
+	"cmd.memberSyncChange": {"action", "username", "access_level", "expires_at", "previous_access_level", "previous_expires_at", "status"},
+	"cmd.contextInfo":      {"name", "current", "url", "default_group", "default_project"},
+}
+
+// idColumns are tried in this order when rendering with `--output ids`
+var idColumns = []string{"id", "name", "slug"}
+
+// Output renders object to stdout in the format selected with the global `--output` flag.
+func Output(object interface{}) error {
+	return render(os.Stdout, outputFormat, object)
+}
+
+func render(w io.Writer, format string, object interface{}) error {
+	if err := checkOutputFormat(format); err != nil {
+		return err
+	}
+	name, arg := splitFormat(format)
+	return renderers[name](w, object, arg)
+}
+
+// checkOutputFormat returns an error for unknown formats and invalid templates, it is called before a command
+// sends any request, so e.g. a delete is not sent if its result cannot be rendered
+func checkOutputFormat(format string) error {
+	name, arg := splitFormat(format)
+	if _, ok := renderers[name]; !ok {
+		return fmt.Errorf("unknown output format '%s', use one of %s", name, strings.Join(outputFormats(), ", "))
+	}
+	if name == "go-template" {
+		_, err := outputTemplate(arg)
+		return err
+	}
+	return nil
+}
+
+func splitFormat(format string) (string, string) {
+	if i := strings.Index(format, "="); i >= 0 {
+		return format[:i], format[i+1:]
+	}
+	return format, ""
+}
+
+func isStreamingFormat(format string) bool {
+	name, _ := splitFormat(format)
+	for _, streamingFormat := range streamingFormats {
+		if name == streamingFormat {
+			return true
+		}
+	}
+	return false
+}
+
+func outputFormats() []string {
+	var formats []string
+	for name := range renderers {
+		formats = append(formats, name)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+func renderJson(w io.Writer, object interface{}, arg string) error {
+	result, err := json.MarshalIndent(object, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, string(result))
+	return nil
+}
+
+// renderNdjson writes every item of a list as compact JSON on a line of its own
+func renderNdjson(w io.Writer, object interface{}, arg string) error {
+	generic, err := toGeneric(object)
+	if err != nil {
+		return err
+	}
+	for _, item := range items(generic) {
+		line, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(line))
+	}
+	return nil
+}
+
+func renderYaml(w io.Writer, object interface{}, arg string) error {
+	generic, err := toGeneric(object)
+	if err != nil {
+		return err
+	}
+	result, err := yaml.Marshal(yamlNumbers(generic))
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(w, string(result))
+	return nil
+}
+
+func renderTable(w io.Writer, object interface{}, arg string) error {
+	columns, rows, err := tabulate(object, arg)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func renderCsv(w io.Writer, object interface{}, arg string) error {
+	return renderSeparated(w, object, arg, ',')
+}
+
+func renderTsv(w io.Writer, object interface{}, arg string) error {
+	return renderSeparated(w, object, arg, '\t')
+}
+
+func renderSeparated(w io.Writer, object interface{}, arg string, separator rune) error {
+	columns, rows, err := tabulate(object, arg)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = separator
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// renderGoTemplate executes the template given as arg for every item of a list (or once for a single object).
+// Fields are accessed by their JSON names, e.g. `go-template={{.id}} {{.path_with_namespace}}`
+func renderGoTemplate(w io.Writer, object interface{}, arg string) error {
+	tmpl, err := outputTemplate(arg)
+	if err != nil {
+		return err
+	}
+	generic, err := toGeneric(object)
+	if err != nil {
+		return err
+	}
+	for _, item := range items(generic) {
+		if err := tmpl.Execute(w, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputTemplate parses the template of the go-template format, every item is rendered on a line of its own
+func outputTemplate(arg string) (*template.Template, error) {
+	if arg == "" {
+		return nil, errors.New("output format go-template requires a template, e.g. --output 'go-template={{.id}}'")
+	}
+	if !strings.HasSuffix(arg, "\n") {
+		arg += "\n"
+	}
+	return template.New("output").Parse(arg)
+}
+
+func renderIds(w io.Writer, object interface{}, arg string) error {
+	generic, err := toGeneric(object)
+	if err != nil {
+		return err
+	}
+	for _, item := range items(generic) {
+		for _, column := range idColumns {
+			if value, ok := lookup(item, column); ok {
+				fmt.Fprintln(w, formatValue(value))
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// tabulate turns object into rows of strings for the given (comma separated) columns or the default columns of the resource
+func tabulate(object interface{}, columnList string) ([]string, [][]string, error) {
+	generic, err := toGeneric(object)
+	if err != nil {
+		return nil, nil, err
+	}
+	records := items(generic)
+
+	var columns []string
+	if columnList != "" {
+		columns = strings.Split(columnList, ",")
+	} else if defaults, ok := defaultColumns[resourceType(object)]; ok {
+		columns = defaults
+	} else {
+		columns = scalarKeys(records)
+	}
+
+	var rows [][]string
+	for _, record := range records {
+		if _, ok := record.(map[string]interface{}); !ok {
+			rows = append(rows, []string{formatValue(record)})
+			continue
+		}
+		var row []string
+		for _, column := range columns {
+			value, _ := lookup(record, column)
+			row = append(row, formatValue(value))
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows, nil
+}
+
+// toGeneric converts object into maps, slices and scalars keyed by the JSON field names
+func toGeneric(object interface{}) (interface{}, error) {
+	bytes, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	// numbers are kept as json.Number, float64 would print large IDs in exponent form
+	decoder := json.NewDecoder(strings.NewReader(string(bytes)))
+	decoder.UseNumber()
+	var generic interface{}
+	err = decoder.Decode(&generic)
+	return generic, err
+}
+
+// yamlNumbers replaces the json.Numbers of a generic object by integers or floats, yaml would quote them as strings
+func yamlNumbers(generic interface{}) interface{} {
+	switch v := generic.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			converted[key] = yamlNumbers(value)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, value := range v {
+			converted[i] = yamlNumbers(value)
+		}
+		return converted
+	}
+	return generic
+}
+
+func items(generic interface{}) []interface{} {
+	if list, ok := generic.([]interface{}); ok {
+		return list
+	}
+	if generic == nil {
+		return nil
+	}
+	return []interface{}{generic}
+}
+
+// lookup resolves a dotted path like `author.username` in a generic object
+func lookup(item interface{}, path string) (interface{}, bool) {
+	current := item
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(bytes)
+	}
+}
+
+// scalarKeys returns the sorted keys of the first record that hold no nested objects or lists
+func scalarKeys(records []interface{}) []string {
+	if len(records) == 0 {
+		return []string{}
+	}
+	m, ok := records[0].(map[string]interface{})
+	if !ok {
+		return []string{"value"}
+	}
+	var keys []string
+	for key, value := range m {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resourceType returns the name of the (element) type of object, e.g. `gitlab.Project` for `[]*gitlab.Project`
+func resourceType(object interface{}) string {
+	if object == nil {
+		return ""
+	}
+	t := reflect.TypeOf(object)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t.String()
+}
@@ -0,0 +1,98 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("output renderers", func() {
+
+	var projects []*gitlab.Project
+
+	BeforeEach(func() {
+		projects = []*gitlab.Project{}
+		err := json.Unmarshal([]byte(readFixture("project-ls")), &projects)
+		Expect(err).To(BeNil())
+	})
+
+	renderToString := func(format string, object interface{}) string {
+		buf := new(bytes.Buffer)
+		err := render(buf, format, object)
+		Expect(err).To(BeNil())
+		return buf.String()
+	}
+
+	It("renders indented json by default", func() {
+		Expect(renderToString("json", projects)).To(Equal(readFixture("project-ls") + "\n"))
+	})
+
+	It("renders a table with the default columns of a resource", func() {
+		Expect(renderToString("table", projects)).To(Equal(
+			"ID  PATH_WITH_NAMESPACE  VISIBILITY  DEFAULT_BRANCH  WEB_URL\n" +
+				"3   test2/testproject    private                     http://gitlab.localhost/test2/testproject\n"))
+	})
+
+	It("renders csv and tsv with the given columns", func() {
+		Expect(renderToString("csv=id,path_with_namespace", projects)).To(Equal("id,path_with_namespace\n3,test2/testproject\n"))
+		Expect(renderToString("tsv=id,name", projects)).To(Equal("id\tname\n3\ttestproject\n"))
+	})
+
+	It("resolves nested columns", func() {
+		branch := &gitlab.Branch{Name: "master", Commit: &gitlab.Commit{ShortID: "7b5c3cc"}}
+		Expect(renderToString("csv=name,commit.short_id", branch)).To(Equal("name,commit.short_id\nmaster,7b5c3cc\n"))
+	})
+
+	It("renders yaml with json field names", func() {
+		Expect(renderToString("yaml", projects)).To(ContainSubstring("path_with_namespace: test2/testproject"))
+	})
+
+	It("executes a go template for every item", func() {
+		Expect(renderToString("go-template={{.id}}:{{.path}}", projects)).To(Equal("3:testproject\n"))
+	})
+
+	It("renders only ids in ids mode", func() {
+		Expect(renderToString("ids", projects)).To(Equal("3\n"))
+	})
+
+	It("renders large ids without exponent in every format", func() {
+		project := &gitlab.Project{ID: 12345678, Name: "big"}
+		Expect(renderToString("json", project)).To(ContainSubstring(`"id": 12345678,`))
+		Expect(renderToString("ndjson", project)).To(ContainSubstring(`,"id":12345678,`))
+		Expect(renderToString("yaml", project)).To(ContainSubstring("\nid: 12345678\n"))
+		Expect(renderToString("table=id,name", project)).To(Equal("ID        NAME\n12345678  big\n"))
+		Expect(renderToString("csv=id,name", project)).To(Equal("id,name\n12345678,big\n"))
+		Expect(renderToString("tsv=id,name", project)).To(Equal("id\tname\n12345678\tbig\n"))
+		Expect(renderToString("go-template={{.id}}", project)).To(Equal("12345678\n"))
+		Expect(renderToString("ids", project)).To(Equal("12345678\n"))
+	})
+
+	It("returns an error for unknown formats", func() {
+		err := render(new(bytes.Buffer), "xml", projects)
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("unknown output format 'xml'"))
+	})
+})
@@ -0,0 +1,208 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/xanzy/go-gitlab"
+)
+
+// maxPerPage is the largest page size Gitlab accepts, it is used when fetching all pages
+const maxPerPage = 100
+
+var allPages bool
+var page, perPage, limit int
+
+// a pageFetcher fetches a single page of a list, the given option sets the page parameters of the request
+type pageFetcher func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error)
+
+func initPaginationFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&allPages, "all", false, "(optional) fetch all pages of the list")
+	cmd.PersistentFlags().IntVar(&page, "page", 0, "(optional) page of the list to fetch (starting with 1)")
+	cmd.PersistentFlags().IntVar(&perPage, "per-page", 0, "(optional) number of items per page (max. 100)")
+	cmd.PersistentFlags().IntVar(&limit, "limit", 0, "(optional) maximum number of items to fetch, following further pages if necessary")
+}
+
+// withPage sets the `page` and `per_page` query parameters of a request, values of 0 are left out
+func withPage(page int, perPage int) gitlab.OptionFunc {
+	return func(req *http.Request) error {
+		query := req.URL.Query()
+		if page > 0 {
+			query.Set("page", strconv.Itoa(page))
+		}
+		if perPage > 0 {
+			query.Set("per_page", strconv.Itoa(perPage))
+		}
+		req.URL.RawQuery = query.Encode()
+		return nil
+	}
+}
+
+// OutputList fetches the pages of a list as requested by the pagination flags and renders the items.
+// For line based output formats (e.g. ndjson) each page is rendered as soon as it arrives.
+func OutputList(fetch pageFetcher) error {
+	if page < 0 || perPage < 0 || limit < 0 {
+		return errors.New("--page, --per-page and --limit must not be negative")
+	}
+	if allPages && page > 0 {
+		return errors.New("--all cannot be combined with --page")
+	}
+	streaming := isStreamingFormat(outputFormat)
+
+	var collected reflect.Value
+	err := eachPage(fetch, func(items reflect.Value) error {
+		if streaming {
+			return Output(items.Interface())
+		}
+		if !collected.IsValid() {
+			collected = reflect.MakeSlice(items.Type(), 0, items.Len())
+		}
+		collected = reflect.AppendSlice(collected, items)
+		return nil
+	})
+	if err != nil || streaming {
+		return err
+	}
+	if !collected.IsValid() {
+		return Output([]interface{}{})
+	}
+	return Output(collected.Interface())
+}
+
+// eachPage calls handle with the items of every page requested by the pagination flags, items are truncated to --limit
+func eachPage(fetch pageFetcher, handle func(items reflect.Value) error) error {
+	size := perPage
+	if size == 0 && (allPages || limit > 0) {
+		size = maxPerPage
+	}
+	return fetchPages(fetch, page, size, allPages, limit, handle)
+}
+
+// fetchPages calls handle with the items of the given page and - if all is set or until max items are
+// handled - of the following pages, a max of 0 means no limit
+func fetchPages(fetch pageFetcher, current int, size int, all bool, max int, handle func(items reflect.Value) error) error {
+	count := 0
+	for {
+		result, resp, err := fetch(withPage(current, size))
+		if err != nil {
+			return err
+		}
+		items := reflect.ValueOf(result)
+		if items.Kind() != reflect.Slice {
+			return errors.New("paginated request did not return a list")
+		}
+		if max > 0 && count+items.Len() > max {
+			items = items.Slice(0, max-count)
+		}
+		count += items.Len()
+		if err := handle(items); err != nil {
+			return err
+		}
+		if resp == nil || resp.NextPage == 0 || !(all || (max > 0 && count < max)) {
+			return nil
+		}
+		current = resp.NextPage
+	}
+}
+
+// fetchAll appends the items of all pages to the slice list points to, regardless of the pagination flags
+func fetchAll(list interface{}, fetch pageFetcher) error {
+	target := reflect.ValueOf(list).Elem()
+	return fetchPages(fetch, 1, maxPerPage, true, 0, func(items reflect.Value) error {
+		target.Set(reflect.AppendSlice(target, items))
+		return nil
+	})
+}
+
+// allGroupMembers fetches the members of a group from all pages, regardless of the pagination flags
+func allGroupMembers(gid interface{}) ([]*gitlab.GroupMember, error) {
+	var members []*gitlab.GroupMember
+	err := fetchAll(&members, func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+		return gitlabClient.Groups.ListGroupMembers(gid, &gitlab.ListGroupMembersOptions{}, page)
+	})
+	return members, err
+}
+
+// allProjectMembers fetches the members of a project from all pages, regardless of the pagination flags
+func allProjectMembers(pid string) ([]*projectMember, error) {
+	var members []*projectMember
+	err := fetchAll(&members, func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+		return listProjectMembers(pid, &gitlab.ListProjectMembersOptions{}, page)
+	})
+	return members, err
+}
+
+// allProjectHooks fetches the hooks of a project from all pages, regardless of the pagination flags
+func allProjectHooks(pid interface{}) ([]*gitlab.ProjectHook, error) {
+	var hooks []*gitlab.ProjectHook
+	err := fetchAll(&hooks, func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+		return gitlabClient.Projects.ListProjectHooks(pid, &gitlab.ListProjectHooksOptions{}, page)
+	})
+	return hooks, err
+}
+
+// allTreeNodes fetches the nodes of a repository tree from all pages, regardless of the pagination flags,
+// the response of the last request is returned to tell a missing tree from other errors
+func allTreeNodes(pid interface{}, opts *gitlab.ListTreeOptions) ([]*gitlab.TreeNode, *gitlab.Response, error) {
+	var nodes []*gitlab.TreeNode
+	var last *gitlab.Response
+	err := fetchAll(&nodes, func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+		result, resp, err := gitlabClient.Repositories.ListTree(pid, opts, page)
+		last = resp
+		return result, resp, err
+	})
+	if err != nil {
+		return nil, last, err
+	}
+	return nodes, last, nil
+}
+
+// allCommitDiffs fetches the diffs of all files changed by a commit, regardless of the pagination flags
+func allCommitDiffs(pid interface{}, sha string) ([]*gitlab.Diff, error) {
+	var diffs []*gitlab.Diff
+	err := fetchAll(&diffs, func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+		return gitlabClient.Commits.GetCommitDiff(pid, sha, page)
+	})
+	return diffs, err
+}
+
+// allLabels fetches the labels of a project from all pages, regardless of the pagination flags
+func allLabels(pid interface{}) ([]*gitlab.Label, error) {
+	var labels []*gitlab.Label
+	err := fetchAll(&labels, func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+		return gitlabClient.Labels.ListLabels(pid, page)
+	})
+	return labels, err
+}
+
+// allGroupProjects fetches the projects of a group from all pages, regardless of the pagination flags
+func allGroupProjects(gid interface{}) ([]*gitlab.Project, error) {
+	var projects []*gitlab.Project
+	err := fetchAll(&projects, func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+		return gitlabClient.Groups.ListGroupProjects(gid, &gitlab.ListGroupProjectsOptions{}, page)
+	})
+	return projects, err
+}
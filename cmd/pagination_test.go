@@ -0,0 +1,119 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("pagination", func() {
+
+	var (
+		mux      *http.ServeMux
+		server   *httptest.Server
+		requests []string
+	)
+
+	resetPaginationFlags := func() {
+		allPages, page, perPage, limit = false, 0, 0, 0
+		outputFormat = "json"
+	}
+
+	BeforeEach(func() {
+		resetCommandLineFlagSet()
+		resetPaginationFlags()
+		requests = []string{}
+
+		mux = http.NewServeMux()
+		server = httptest.NewServer(mux)
+		gitlabClient = gitlab.NewClient(nil, "")
+		gitlabClient.SetBaseURL(server.URL + "/api/v4")
+
+		// serves 3 pages with 2 users each
+		mux.HandleFunc("/api/v4/users", func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.URL.RawQuery)
+			current, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if current == 0 {
+				current = 1
+			}
+			if current < 3 {
+				w.Header().Set("Link", fmt.Sprintf(`<%s/api/v4/users?page=%d>; rel="next"`, server.URL, current+1))
+			}
+			fmt.Fprintf(w, `[{"id":%d,"username":"user%d"},{"id":%d,"username":"user%d"}]`, 2*current-1, 2*current-1, 2*current, 2*current)
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+		resetPaginationFlags()
+	})
+
+	It("fetches only the first page by default", func() {
+		_, _, err := executeCommand(RootCmd, "user", "ls")
+		Expect(err).To(BeNil())
+		Expect(requests).To(Equal([]string{""}))
+	})
+
+	It("follows all pages with --all", func() {
+		outputFormat = "ids"
+		stdout, _, err := executeCommand(RootCmd, "user", "ls", "--all")
+		Expect(err).To(BeNil())
+		Expect(requests).To(Equal([]string{"per_page=100", "page=2&per_page=100", "page=3&per_page=100"}))
+		Expect(stdout).To(Equal("1\n2\n3\n4\n5\n6"))
+	})
+
+	It("fetches a single page with --page and --per-page", func() {
+		outputFormat = "ids"
+		stdout, _, err := executeCommand(RootCmd, "user", "ls", "--page", "2", "--per-page", "2")
+		Expect(err).To(BeNil())
+		Expect(requests).To(Equal([]string{"page=2&per_page=2"}))
+		Expect(stdout).To(Equal("3\n4"))
+	})
+
+	It("stops following pages when --limit is reached", func() {
+		outputFormat = "ndjson"
+		stdout, _, err := executeCommand(RootCmd, "user", "ls", "--limit", "3")
+		Expect(err).To(BeNil())
+		Expect(requests).To(HaveLen(2))
+		lines := strings.Split(stdout, "\n")
+		Expect(lines).To(HaveLen(3))
+		Expect(lines[2]).To(ContainSubstring(`"username":"user3"`))
+	})
+	It("fetches all pages regardless of the pagination flags", func() {
+		page, perPage, limit = 2, 1, 1
+		var users []*gitlab.User
+		err := fetchAll(&users, func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Users.ListUsers(&gitlab.ListUsersOptions{}, page)
+		})
+		Expect(err).To(BeNil())
+		Expect(requests).To(Equal([]string{"page=1&per_page=100", "page=2&per_page=100", "page=3&per_page=100"}))
+		Expect(users).To(HaveLen(6))
+		Expect(users[5].Username).To(Equal("user6"))
+	})
+})
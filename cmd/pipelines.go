@@ -0,0 +1,359 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xanzy/go-gitlab"
+)
+
+// exit codes of `pipelines watch`, they are not used by any other error of golab (e.g. 1 for a bad configuration,
+// 2 for a panic, 255 for errors of the Gitlab API), so scripts can tell the result of the pipeline from failures of golab
+const (
+	exitPipelineFailed   = 10
+	exitPipelineCanceled = 11
+	exitWatchTimeout     = 12
+)
+
+// pipelineWatchInterval is the default time between two polls of `pipelines watch`
+var pipelineWatchInterval = 5 * time.Second
+
+// pipelineProgress receives the status changes reported by `pipelines watch`
+var pipelineProgress io.Writer = os.Stderr
+
+// see https://docs.gitlab.com/ce/api/pipelines.html
+var pipelinesCmd = &cobra.Command{
+	Use:         "pipelines",
+	Aliases:     []string{"pipeline"},
+	Short:       "Manage pipelines",
+	Long:        `List, create, retry, cancel and watch pipelines`,
+	Annotations: map[string]string{idResourceAnnotation: "project"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("this command cannot be run without a sub-command")
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/pipelines.html#list-project-pipelines
+type pipelinesListFlags struct {
+	Id         *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Scope      *string `flag_name:"scope" type:"string" choices:"running,pending,finished,branches,tags" required:"no" description:"The scope of pipelines, one of: running, pending, finished, branches, tags"`
+	Status     *string `flag_name:"status" type:"string" choices:"running,pending,success,failed,canceled,skipped" required:"no" description:"The status of pipelines, one of: running, pending, success, failed, canceled, skipped"`
+	Ref        *string `flag_name:"ref" type:"string" required:"no" description:"The ref of pipelines"`
+	Sha        *string `flag_name:"sha" type:"string" required:"no" description:"The sha of pipelines"`
+	YamlErrors *bool   `flag_name:"yaml_errors" type:"boolean" required:"no" description:"Returns pipelines with invalid configurations"`
+	Name       *string `flag_name:"name" type:"string" required:"no" description:"The name of the user who triggered pipelines"`
+	Username   *string `flag_name:"username" type:"string" required:"no" description:"The username of the user who triggered pipelines"`
+	OrderBy    *string `flag_name:"order_by" type:"string" choices:"id,status,ref,user_id" required:"no" description:"Order pipelines by id, status, ref, or user_id (default: id)"`
+	Sort       *string `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"Sort pipelines in asc or desc order (default: desc)"`
+}
+
+var pipelinesListCmd = &golabCommand{
+	Parent: pipelinesCmd,
+	Flags:  &pipelinesListFlags{},
+	Cmd: &cobra.Command{
+		Use:   "ls",
+		Short: "List project pipelines",
+		Long:  `Get a list of the pipelines of a project, latest first.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*pipelinesListFlags)
+		query := map[string]string{}
+		setQuery(query, "scope", flags.Scope)
+		setQuery(query, "status", flags.Status)
+		setQuery(query, "ref", flags.Ref)
+		setQuery(query, "sha", flags.Sha)
+		setQuery(query, "name", flags.Name)
+		setQuery(query, "username", flags.Username)
+		setQuery(query, "order_by", flags.OrderBy)
+		setQuery(query, "sort", flags.Sort)
+		if flags.YamlErrors != nil {
+			query["yaml_errors"] = strconv.FormatBool(*flags.YamlErrors)
+		}
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Pipelines.ListProjectPipelines(parsePid(*flags.Id), withQuery(query), page)
+		})
+	},
+}
+
+// pipelineFlags are used by all commands that work on a single pipeline
+type pipelineFlags struct {
+	Id         *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	PipelineId *int    `flag_name:"pipeline_id" short:"p" type:"integer" required:"yes" description:"The ID of a pipeline"`
+}
+
+// see https://docs.gitlab.com/ce/api/pipelines.html#get-a-single-pipeline
+var pipelinesGetCmd = &golabCommand{
+	Parent: pipelinesCmd,
+	Flags:  &pipelineFlags{},
+	Cmd: &cobra.Command{
+		Use:   "get",
+		Short: "Get a single pipeline",
+		Long:  `Get a single pipeline of a project.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*pipelineFlags)
+		pipeline, _, err := gitlabClient.Pipelines.GetPipeline(parsePid(*flags.Id), *flags.PipelineId)
+		if err != nil {
+			return err
+		}
+		return Output(pipeline)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/pipelines.html#create-a-new-pipeline
+type pipelinesCreateFlags struct {
+	Id  *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Ref *string `flag_name:"ref" short:"r" type:"string" required:"yes" description:"Reference to commit"`
+}
+
+var pipelinesCreateCmd = &golabCommand{
+	Parent: pipelinesCmd,
+	Flags:  &pipelinesCreateFlags{},
+	Opts:   &gitlab.CreatePipelineOptions{},
+	Cmd: &cobra.Command{
+		Use:   "create",
+		Short: "Create a new pipeline",
+		Long:  `Creates a new pipeline for the given ref.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*pipelinesCreateFlags)
+		opts := cmd.Opts.(*gitlab.CreatePipelineOptions)
+		pipeline, _, err := gitlabClient.Pipelines.CreatePipeline(parsePid(*flags.Id), opts)
+		if err != nil {
+			return err
+		}
+		return Output(pipeline)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/pipelines.html#retry-failed-jobs-in-a-pipeline
+var pipelinesRetryCmd = &golabCommand{
+	Parent: pipelinesCmd,
+	Flags:  &pipelineFlags{},
+	Cmd: &cobra.Command{
+		Use:   "retry",
+		Short: "Retry failed jobs in a pipeline",
+		Long:  `Retries the failed jobs of a pipeline.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*pipelineFlags)
+		pipeline, _, err := gitlabClient.Pipelines.RetryPipelineBuild(parsePid(*flags.Id), *flags.PipelineId)
+		if err != nil {
+			return err
+		}
+		return Output(pipeline)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/pipelines.html#cancel-a-pipelines-jobs
+var pipelinesCancelCmd = &golabCommand{
+	Parent: pipelinesCmd,
+	Flags:  &pipelineFlags{},
+	Cmd: &cobra.Command{
+		Use:   "cancel",
+		Short: "Cancel a pipeline's jobs",
+		Long:  `Cancels all running and pending jobs of a pipeline.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*pipelineFlags)
+		pipeline, _, err := gitlabClient.Pipelines.CancelPipelineBuild(parsePid(*flags.Id), *flags.PipelineId)
+		if err != nil {
+			return err
+		}
+		return Output(pipeline)
+	},
+}
+
+type pipelinesWatchFlags struct {
+	Id         *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	PipelineId *int    `flag_name:"pipeline_id" short:"p" type:"integer" required:"no" description:"The ID of the pipeline to watch, either this or --ref is required"`
+	Ref        *string `flag_name:"ref" short:"r" type:"string" required:"no" description:"Watch the latest pipeline of this ref"`
+	Interval   *int    `flag_name:"interval" type:"integer" required:"no" description:"Seconds between two polls (default: 5)"`
+	Timeout    *int    `flag_name:"timeout" type:"integer" required:"no" description:"Stop watching after this number of seconds and exit with 3 (default: no timeout)"`
+}
+
+var pipelinesWatchCmd = &golabCommand{
+	Parent: pipelinesCmd,
+	Flags:  &pipelinesWatchFlags{},
+	Cmd: &cobra.Command{
+		Use:   "watch",
+		Short: "Wait for a pipeline to finish",
+		Long: `Polls a pipeline until it reaches a terminal state. Status changes of jobs are printed to stderr, the finished pipeline is printed to stdout.
+
+Exit codes:
+
+     0 - pipeline succeeded, was skipped or waits for a manual action
+    10 - pipeline failed
+    11 - pipeline was canceled
+    12 - --timeout was reached
+
+Any other exit code means that golab itself failed, e.g. because of a bad configuration or an error of the Gitlab API.`,
+		SilenceUsage: true,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*pipelinesWatchFlags)
+		pid := parsePid(*flags.Id)
+		interval, timeout := pipelineWatchInterval, time.Duration(0)
+		if flags.Interval != nil && *flags.Interval > 0 {
+			interval = time.Duration(*flags.Interval) * time.Second
+		}
+		if flags.Timeout != nil && *flags.Timeout > 0 {
+			timeout = time.Duration(*flags.Timeout) * time.Second
+		}
+
+		var pipelineId int
+		if flags.PipelineId != nil {
+			pipelineId = *flags.PipelineId
+		} else if flags.Ref != nil {
+			latest, err := latestPipeline(pid, *flags.Ref)
+			if err != nil {
+				return err
+			}
+			pipelineId = latest
+		} else {
+			return errors.New("either --pipeline_id or --ref is required")
+		}
+
+		pipeline, err := watchPipeline(pid, pipelineId, interval, timeout, pipelineProgress)
+		if pipeline != nil {
+			if outputErr := Output(pipeline); outputErr != nil {
+				return outputErr
+			}
+		}
+		return err
+	},
+}
+
+// exitCodeError is returned by commands that need to exit with a specific exit code
+type exitCodeError struct {
+	code    int
+	message string
+}
+
+func (e *exitCodeError) Error() string {
+	return e.message
+}
+
+// latestPipeline returns the ID of the most recent pipeline of a ref
+func latestPipeline(pid interface{}, ref string) (int, error) {
+	pipelines, _, err := gitlabClient.Pipelines.ListProjectPipelines(pid, withQuery(map[string]string{"ref": ref}), withPage(0, 1))
+	if err != nil {
+		return 0, err
+	}
+	if len(pipelines) == 0 {
+		return 0, fmt.Errorf("no pipeline found for ref '%s'", ref)
+	}
+	return pipelines[0].ID, nil
+}
+
+// watchPipeline polls a pipeline until it reaches a terminal state and reports changes of its jobs to progress.
+// An exitCodeError is returned if the pipeline failed, was canceled or the timeout was reached.
+func watchPipeline(pid interface{}, pipelineId int, interval time.Duration, timeout time.Duration, progress io.Writer) (*gitlab.Pipeline, error) {
+	started := time.Now()
+	jobStates := map[int]string{}
+	pipelineState := ""
+	for {
+		pipeline, _, err := gitlabClient.Pipelines.GetPipeline(pid, pipelineId)
+		if err != nil {
+			return nil, err
+		}
+		if pipeline.Status != pipelineState {
+			fmt.Fprintf(progress, "pipeline %d (%s): %s\n", pipeline.ID, pipeline.Ref, pipeline.Status)
+			pipelineState = pipeline.Status
+		}
+		if err := reportJobProgress(pid, pipelineId, jobStates, progress); err != nil {
+			return nil, err
+		}
+
+		switch pipeline.Status {
+		case "success", "skipped", "manual":
+			return pipeline, nil
+		case "failed":
+			return pipeline, &exitCodeError{exitPipelineFailed, fmt.Sprintf("pipeline %d failed", pipeline.ID)}
+		case "canceled":
+			return pipeline, &exitCodeError{exitPipelineCanceled, fmt.Sprintf("pipeline %d was canceled", pipeline.ID)}
+		}
+		if timeout > 0 && time.Since(started)+interval > timeout {
+			return pipeline, &exitCodeError{exitWatchTimeout, fmt.Sprintf("timeout while waiting for pipeline %d (%s)", pipeline.ID, pipeline.Status)}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// reportJobProgress prints all jobs of a pipeline whose status changed since the last call
+func reportJobProgress(pid interface{}, pipelineId int, jobStates map[int]string, progress io.Writer) error {
+	opts := &gitlab.ListJobsOptions{ListOptions: gitlab.ListOptions{PerPage: maxPerPage}}
+	for {
+		jobs, resp, err := gitlabClient.Jobs.ListPipelineJobs(pid, pipelineId, opts)
+		if err != nil {
+			return err
+		}
+		// jobs are returned latest first
+		for i := len(jobs) - 1; i >= 0; i-- {
+			job := jobs[i]
+			if jobStates[job.ID] != job.Status {
+				fmt.Fprintf(progress, "  %-15s %-30s %s\n", job.Stage, job.Name, job.Status)
+				jobStates[job.ID] = job.Status
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// withQuery adds the given parameters to the query of a request, used for API parameters not supported by go-gitlab
+func withQuery(params map[string]string) gitlab.OptionFunc {
+	return func(req *http.Request) error {
+		query := req.URL.Query()
+		for key, value := range params {
+			query.Set(key, value)
+		}
+		req.URL.RawQuery = query.Encode()
+		return nil
+	}
+}
+
+func setQuery(query map[string]string, key string, value *string) {
+	if value != nil {
+		query[key] = *value
+	}
+}
+
+func init() {
+	pipelinesListCmd.Init()
+	initPaginationFlags(pipelinesListCmd.Cmd)
+	pipelinesGetCmd.Init()
+	pipelinesCreateCmd.Init()
+	pipelinesRetryCmd.Init()
+	pipelinesCancelCmd.Init()
+	pipelinesWatchCmd.Init()
+	RootCmd.AddCommand(pipelinesCmd)
+}
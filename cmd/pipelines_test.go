@@ -0,0 +1,110 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("pipelines command", func() {
+
+	var (
+		mux      *http.ServeMux
+		server   *httptest.Server
+		progress *bytes.Buffer
+	)
+
+	// servePipeline serves a pipeline that runs for two polls and then reaches the given final status
+	servePipeline := func(id int, finalStatus string) {
+		polls := 0
+		mux.HandleFunc(fmt.Sprintf("/api/v4/projects/12/pipelines/%d", id), func(w http.ResponseWriter, r *http.Request) {
+			polls++
+			status := "running"
+			if polls > 2 {
+				status = finalStatus
+			}
+			fmt.Fprintf(w, `{"id":%d,"ref":"master","status":"%s"}`, id, status)
+		})
+		mux.HandleFunc(fmt.Sprintf("/api/v4/projects/12/pipelines/%d/jobs", id), func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[{"id":2,"name":"test","stage":"test","status":"running"},{"id":1,"name":"compile","stage":"build","status":"success"}]`)
+		})
+	}
+
+	BeforeEach(func() {
+		resetCommandLineFlagSet()
+		mux = http.NewServeMux()
+		server = httptest.NewServer(mux)
+		gitlabClient = gitlab.NewClient(nil, "")
+		gitlabClient.SetBaseURL(server.URL + "/api/v4")
+		pipelineWatchInterval = time.Millisecond
+		progress = new(bytes.Buffer)
+		pipelineProgress = progress
+	})
+
+	AfterEach(func() {
+		server.Close()
+		pipelineWatchInterval = 5 * time.Second
+		pipelineProgress = os.Stderr
+	})
+
+	It("watches a pipeline until it succeeds", func() {
+		servePipeline(41, "success")
+		stdout, _, err := executeCommand(RootCmd, "pipelines", "watch", "-i", "12", "-p", "41")
+		Expect(err).To(BeNil())
+		Expect(stdout).To(ContainSubstring(`"status": "success"`))
+		Expect(progress.String()).To(Equal("pipeline 41 (master): running\n" +
+			"  build           compile                        success\n" +
+			"  test            test                           running\n" +
+			"pipeline 41 (master): success\n"))
+	})
+
+	It("returns exit code 10 for failed pipelines", func() {
+		servePipeline(42, "failed")
+		_, _, err := executeCommand(RootCmd, "pipelines", "watch", "-i", "12", "-p", "42")
+		Expect(err).To(BeAssignableToTypeOf(&exitCodeError{}))
+		Expect(err.(*exitCodeError).code).To(Equal(10))
+	})
+
+	It("returns exit code 11 for canceled pipelines", func() {
+		servePipeline(43, "canceled")
+		_, _, err := executeCommand(RootCmd, "pipelines", "watch", "-i", "12", "-p", "43")
+		Expect(err).To(BeAssignableToTypeOf(&exitCodeError{}))
+		Expect(err.(*exitCodeError).code).To(Equal(11))
+	})
+
+	It("finds the latest pipeline of a ref", func() {
+		mux.HandleFunc("/api/v4/projects/12/pipelines", func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Query().Get("ref")).To(Equal("release"))
+			Expect(r.URL.Query().Get("per_page")).To(Equal("1"))
+			fmt.Fprint(w, `[{"id":44,"ref":"release","status":"pending"}]`)
+		})
+		Expect(latestPipeline(12, "release")).To(Equal(44))
+	})
+})
@@ -32,9 +32,10 @@ import (
 var createOptsMapper, listOptsMapper, getOptsMapper, editOptsMapper, forkOptsMapper, listForksOptsMapper, shareOptsMapper, addHookOptsMapper, editHookOptsMapper, projectSearchOptsMapper mapper.FlagMapper
 
 var projectCmd = &cobra.Command{
-	Use:   "project",
-	Short: "Manage projects",
-	Long:  `List, create, edit and delete projects`,
+	Use:         "project",
+	Short:       "Manage projects",
+	Long:        `List, create, edit and delete projects`,
+	Annotations: map[string]string{idResourceAnnotation: "project"},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return errors.New("cannot run this command without further sub-commands")
 	},
@@ -42,9 +43,9 @@ var projectCmd = &cobra.Command{
 
 type listFlags struct {
 	Archived                 *bool   `flag_name:"archived" type:"bool" required:"no" description:"Limit by archived status"`
-	Visibility               *string `flag_name:"visibility" type:"string" required:"no" description:"Limit by visibility public, internal, or private"`
-	OrderBy                  *string `flag_name:"order_by" type:"string" required:"no" description:"Return projects ordered by id, name, path, created_at, updated_at, or last_activity_at fields. Default is created_at"`
-	Sort                     *string `flag_name:"sort" type:"string" required:"no" description:"Return projects sorted in asc or desc order. Default is desc"`
+	Visibility               *string `flag_name:"visibility" type:"string" choices:"private,internal,public" required:"no" description:"Limit by visibility public, internal, or private"`
+	OrderBy                  *string `flag_name:"order_by" type:"string" choices:"id,name,path,created_at,updated_at,last_activity_at" required:"no" description:"Return projects ordered by id, name, path, created_at, updated_at, or last_activity_at fields. Default is created_at"`
+	Sort                     *string `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"Return projects sorted in asc or desc order. Default is desc"`
 	Search                   *string `flag_name:"search" type:"string" required:"no" description:"Return list of projects matching the search criteria"`
 	Simple                   *bool   `flag_name:"simple" type:"bool" required:"no" description:"Return only the ID, URL, name, and path of each project"`
 	Owned                    *bool   `flag_name:"owned" type:"bool" required:"no" description:"Limit by projects owned by the current user"`
@@ -62,17 +63,18 @@ var projectListCmd = &cobra.Command{
 	Long:  `Get a list of all visible projects across GitLab for the authenticated user.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		_, _, err := listOptsMapper.AutoMap()
-		opts := listOptsMapper.MappedOpts().(*gitlab.ListProjectsOptions)
-		projects, _, err := gitlabClient.Projects.ListProjects(opts)
 		if err != nil {
 			return err
 		}
-		return OutputJson(projects)
+		opts := listOptsMapper.MappedOpts().(*gitlab.ListProjectsOptions)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Projects.ListProjects(opts, page)
+		})
 	},
 }
 
 type getFlags struct {
-	Id *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"either the project ID (numeric) or 'namespace/project-name'"`
+	Id *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"either the project ID (numeric) or 'namespace/project-name'"`
 	// TODO currently not supported by go-gitlab
 	Statistics *bool `flag_name:"statistics" short:"s" required:"no" description:"include project statistics"`
 }
@@ -91,7 +93,7 @@ var projectGetCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return OutputJson(project)
+		return Output(project)
 	},
 }
 
@@ -100,7 +102,7 @@ type createFlags struct {
 	Path                                      *string   `flag_name:"path" type:"string" required:"no" description:"Custom repository name for new project.By default generated based on name"`
 	DefaultBranch                             *string   `flag_name:"default_branch" type:"string" required:"no" description:"master by default"`
 	NamespaceID                               *int      `flag_name:"namespace_id" type:"integer" required:"no" description:"Namespace ID (Group ID) for the new project (defaults to the current user's namespace)"`
-	Description                               *string   `flag_name:"description" type:"string" required:"no" description:"Short project description"`
+	Description                               *string   `flag_name:"description" type:"string" file:"raw" required:"no" description:"Short project description"`
 	IssuesEnabled                             *bool     `flag_name:"issues_enabled" type:"bool" required:"no" description:"Enable issues for this project"`
 	MergeRequestsEnabled                      *bool     `flag_name:"merge_requests_enabled" type:"bool" required:"no" description:"Enable merge requests for this project"`
 	JobsEnabled                               *bool     `flag_name:"jobs_enabled" type:"bool" required:"no" description:"Enable jobs for this project"`
@@ -109,7 +111,7 @@ type createFlags struct {
 	ResolveOutdatedDiffDiscussions            *bool     `flag_name:"resolve_outdated_diff_discussions" type:"bool" required:"no" description:"Automatically resolve merge request diffs discussions on lines changed with a push"`
 	ContainerRegistryEnabled                  *bool     `flag_name:"container_registry_enabled" type:"bool" required:"no" description:"Enable container registry for this project"`
 	SharedRunnersEnabled                      *bool     `flag_name:"shared_runners_enabled" type:"bool" required:"no" description:"Enable shared runners for this project"`
-	Visibility                                *string   `flag_name:"visibility" type:"string" required:"no" description:"See project visibility level"`
+	Visibility                                *string   `flag_name:"visibility" type:"string" choices:"private,internal,public" required:"no" description:"See project visibility level"`
 	ImportUrl                                 *string   `flag_name:"import_url" type:"string" required:"no" description:"URL to import repository from"`
 	PublicJobs                                *bool     `flag_name:"public_jobs" type:"bool" required:"no" description:"If true, jobs can be viewed by non-project-members"`
 	OnlyAllowMergeIfPipelineSucceeds          *bool     `flag_name:"only_allow_merge_if_pipeline_succeeds" type:"bool" required:"no" description:"Set whether merge requests can only be merged with successful jobs"`
@@ -117,7 +119,7 @@ type createFlags struct {
 	LfsEnabled                                *bool     `flag_name:"lfs_enabled" type:"bool" required:"no" description:"Enable LFS"`
 	RequestAccessEnabled                      *bool     `flag_name:"request_access_enabled" type:"bool" required:"no" description:"Allow users to request member access"`
 	TagList                                   *[]string `flag_name:"tag_list" type:"array" required:"no" description:"The list of tags for a project; put array of tags, that should be finally assigned to a project"`
-	Avatar                                    *string   `flag_name:"avatar" type:"mixed" required:"no" description:"Image file for avatar of the project"`
+	Avatar                                    *string   `flag_name:"avatar" type:"mixed" required:"no" description:"Image file for avatar of the project, - to read it from stdin"`
 	PrintingMergeRequestLinkEnabled           *bool     `flag_name:"printing_merge_request_link_enabled" type:"bool" required:"no" description:"Show link to create/view merge request when pushing from the command line"`
 	CiConfigPath                              *string   `flag_name:"ci_config_path" type:"string" required:"no" description:"The path to CI config file"`
 }
@@ -151,16 +153,21 @@ var projectCreateCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return OutputJson(project)
+		if flags := createOptsMapper.MappedFlags().(*createFlags); flags.Avatar != nil {
+			if project, err = uploadAvatar(strconv.Itoa(project.ID), *flags.Avatar); err != nil {
+				return err
+			}
+		}
+		return Output(project)
 	},
 }
 
 type editFlags struct {
-	Id                                        *string   `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL-encoded path of the project"`
+	Id                                        *string   `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
 	Name                                      *string   `flag_name:"name" short:"n" type:"string" required:"yes" description:"The name of the project"`
 	Path                                      *string   `flag_name:"path" type:"string" required:"no" description:"Custom repository name for the project. By default generated based on name"`
 	DefaultBranch                             *string   `flag_name:"default_branch" type:"string" required:"no" description:"master by default"`
-	Description                               *string   `flag_name:"description" type:"string" required:"no" description:"Short project description"`
+	Description                               *string   `flag_name:"description" type:"string" file:"raw" required:"no" description:"Short project description"`
 	IssuesEnabled                             *bool     `flag_name:"issues_enabled" type:"bool" required:"no" description:"Enable issues for this project"`
 	MergeRequestsEnabled                      *bool     `flag_name:"merge_requests_enabled" type:"bool" required:"no" description:"Enable merge requests for this project"`
 	JobsEnabled                               *bool     `flag_name:"jobs_enabled" type:"bool" required:"no" description:"Enable jobs for this project"`
@@ -169,7 +176,7 @@ type editFlags struct {
 	ResolveOutdatedDiffDiscussions            *bool     `flag_name:"resolve_outdated_diff_discussions" type:"bool" required:"no" description:"Automatically resolve merge request diffs discussions on lines changed with a push"`
 	ContainerRegistryEnabled                  *bool     `flag_name:"container_registry_enabled" type:"bool" required:"no" description:"Enable container registry for this project"`
 	SharedRunnersEnabled                      *bool     `flag_name:"shared_runners_enabled" type:"bool" required:"no" description:"Enable shared runners for this project"`
-	Visibility                                *string   `flag_name:"visibility" type:"string" required:"no" description:"See project visibility level"`
+	Visibility                                *string   `flag_name:"visibility" type:"string" choices:"private,internal,public" required:"no" description:"See project visibility level"`
 	ImportUrl                                 *string   `flag_name:"import_url" type:"string" required:"no" description:"URL to import repository from"`
 	PublicJobs                                *bool     `flag_name:"public_jobs" type:"bool" required:"no" description:"If true, jobs can be viewed by non-project-members"`
 	OnlyAllowMergeIfPipelineSucceeds          *bool     `flag_name:"only_allow_merge_if_pipeline_succeeds" type:"bool" required:"no" description:"Set whether merge requests can only be merged with successful jobs"`
@@ -178,6 +185,7 @@ type editFlags struct {
 	RequestAccessEnabled                      *bool     `flag_name:"request_access_enabled" type:"bool" required:"no" description:"Allow users to request member access"`
 	TagList                                   *[]string `flag_name:"tag_list" type:"array" required:"no" description:"The list of tags for a project; put array of tags, that should be finally assigned to a project"`
 	CiConfigPath                              *string   `flag_name:"ci_config_path" type:"string" required:"no" description:"The path to CI config file"`
+	Avatar                                    *string   `flag_name:"avatar" type:"mixed" required:"no" description:"Image file for avatar of the project, - to read it from stdin"`
 }
 
 var projectEditCmd = &cobra.Command{
@@ -185,19 +193,27 @@ var projectEditCmd = &cobra.Command{
 	Short: "Edit project",
 	Long:  `Updates an existing project.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		editOptsMapper.AutoMap()
+		_, _, err := editOptsMapper.AutoMap()
+		if err != nil {
+			return err
+		}
 		opts := editOptsMapper.MappedOpts().(*gitlab.EditProjectOptions)
 		flags := editOptsMapper.MappedFlags().(*editFlags)
 		project, _, err := gitlabClient.Projects.EditProject(*flags.Id, opts)
 		if err != nil {
 			return err
 		}
-		return OutputJson(project)
+		if flags.Avatar != nil {
+			if project, err = uploadAvatar(*flags.Id, *flags.Avatar); err != nil {
+				return err
+			}
+		}
+		return Output(project)
 	},
 }
 
 type forkFlags struct {
-	Id        *string `flag_name:"id" type:"integer/string" required:"yes" description:"The ID or URL-encoded path of the project"`
+	Id        *string `flag_name:"id" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
 	Namespace *string `flag_name:"namespace" type:"integer/string" required:"yes" description:"The ID or path of the namespace that the project will be forked to"`
 }
 
@@ -208,23 +224,26 @@ var projectForkCmd = &cobra.Command{
 
 The forking operation for a project is asynchronous and is completed in a background job. The request will return immediately. To determine whether the fork of the project has completed, query the import_status for the new project.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		forkOptsMapper.AutoMap()
+		_, _, err := forkOptsMapper.AutoMap()
+		if err != nil {
+			return err
+		}
 		flags := forkOptsMapper.MappedFlags().(*forkFlags)
 		// TODO target namespace is currently not supported by go-gitlab
 		project, _, err := gitlabClient.Projects.ForkProject(*flags.Id)
 		if err != nil {
 			return err
 		}
-		return OutputJson(project)
+		return Output(project)
 	},
 }
 
 type listForksFlags struct {
-	Id                       *string `flag_name:"id" type:"integer/string" required:"yes" description:"The ID or URL-encoded path of the project"`
+	Id                       *string `flag_name:"id" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
 	Archived                 *bool   `flag_name:"archived" type:"bool" required:"no" description:"Limit by archived status"`
-	Visibility               *string `flag_name:"visibility" type:"string" required:"no" description:"Limit by visibility public, internal, or private"`
-	OrderBy                  *string `flag_name:"order_by" type:"string" required:"no" description:"Return projects ordered by id, name, path, created_at, updated_at, or last_activity_at fields. Default is created_at"`
-	Sort                     *string `flag_name:"sort" type:"string" required:"no" description:"Return projects sorted in asc or desc order. Default is desc"`
+	Visibility               *string `flag_name:"visibility" type:"string" choices:"private,internal,public" required:"no" description:"Limit by visibility public, internal, or private"`
+	OrderBy                  *string `flag_name:"order_by" type:"string" choices:"id,name,path,created_at,updated_at,last_activity_at" required:"no" description:"Return projects ordered by id, name, path, created_at, updated_at, or last_activity_at fields. Default is created_at"`
+	Sort                     *string `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"Return projects sorted in asc or desc order. Default is desc"`
 	Search                   *string `flag_name:"search" type:"string" required:"no" description:"Return list of projects matching the search criteria"`
 	Simple                   *bool   `flag_name:"simple" type:"bool" required:"no" description:"Return only the ID, URL, name, and path of each project"`
 	Owned                    *bool   `flag_name:"owned" type:"bool" required:"no" description:"Limit by projects owned by the current user"`
@@ -259,7 +278,7 @@ var projectStarCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return OutputJson(project)
+		return Output(project)
 	},
 }
 
@@ -276,14 +295,15 @@ var projectUnstarCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return OutputJson(project)
+		return Output(project)
 	},
 }
 
 var projectArchiveCmd = &cobra.Command{
-	Use:   "archive",
-	Short: "Archive a project",
-	Long:  `Archives the project if the user is either admin or the project owner of this project. This action is idempotent, thus archiving an already archived project will not change the project.`,
+	Use:         "archive",
+	Short:       "Archive a project",
+	Annotations: map[string]string{explicitIdAnnotation: ""},
+	Long:        `Archives the project if the user is either admin or the project owner of this project. This action is idempotent, thus archiving an already archived project will not change the project. The project is not taken from the git remote or the context, --id (or $GOLAB_PROJECT) must be given.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		pid, err := cmd.Flags().GetString("id")
 		if err != nil {
@@ -293,7 +313,7 @@ var projectArchiveCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return OutputJson(project)
+		return Output(project)
 	},
 }
 
@@ -310,14 +330,15 @@ var projectUnarchiveCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return OutputJson(project)
+		return Output(project)
 	},
 }
 
 var projectDeleteCmd = &cobra.Command{
-	Use:   "delete",
-	Short: "Remove project",
-	Long:  `Removes a project including all associated resources (issues, merge requests etc.)`,
+	Use:         "delete",
+	Short:       "Remove project",
+	Annotations: map[string]string{explicitIdAnnotation: ""},
+	Long:        `Removes a project including all associated resources (issues, merge requests etc.). The project is not taken from the git remote or the context, --id (or $GOLAB_PROJECT) must be given.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		pid, err := cmd.Flags().GetString("id")
 		if err != nil {
@@ -331,7 +352,11 @@ var projectDeleteCmd = &cobra.Command{
 var projectUploadFileCmd = &cobra.Command{
 	Use:   "upload-file",
 	Short: "Upload a file",
-	Long:  `Uploads a file to the specified project to be used in an issue or merge request description, or a comment.`,
+	Long: `Uploads a file to the specified project to be used in an issue or merge request description, or a comment.
+
+The file is read from stdin with '--file -', its name must then be given with '--name':
+
+    convert diagram.svg png:- | golab project upload-file -i my-group/my-project -f - --name diagram.png`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		pid, err := cmd.Flags().GetString("id")
 		if err != nil {
@@ -341,18 +366,29 @@ var projectUploadFileCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		projectFile, _, err := gitlabClient.Projects.UploadFile(pid, file)
+		if file == "" {
+			return errors.New("required parameter `-f` or `--file` not given - exiting")
+		}
+		name, err := cmd.Flags().GetString("name")
 		if err != nil {
 			return err
 		}
-		return OutputJson(projectFile)
+		name, content, err := readUpload(file, name)
+		if err != nil {
+			return err
+		}
+		projectFile, err := uploadProjectFile(pid, name, content)
+		if err != nil {
+			return err
+		}
+		return Output(projectFile)
 	},
 }
 
 type shareFlags struct {
-	Id          *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"The ID or URL-encoded path of the project"`
+	Id          *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
 	GroupID     *int    `flag_name:"group_id" short:"g" type:"integer" required:"yes" description:"The ID of the group to share with"`
-	GroupAccess *string `flag_name:"group_access" short:"a" type:"integer" transform:"str2AccessLevel" required:"yes" description:"The permissions level to grant the group"`
+	GroupAccess *string `flag_name:"group_access" short:"a" type:"integer" choices:"10,20,30,40,50" transform:"str2AccessLevel" required:"yes" description:"The permissions level to grant the group"`
 	ExpiresAt   *string `flag_name:"expires_at" short:"e" type:"string" required:"no" description:"Share expiration date in ISO 8601 format: 2016-09-26"`
 	// gitlab opts should use ISOTime instead of string, then this line is valid:
 	//ExpiresAt   *string  `flag_name:"expires_at" short:"e" type:"string" transform:"string2IsoTime" required:"no" description:"Share expiration date in ISO 8601 format: 2016-09-26"`
@@ -363,10 +399,13 @@ var projectShareWithGroupCmd = &cobra.Command{
 	Short: "Share project with group",
 	Long:  `Allow to share project with group.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		shareOptsMapper.AutoMap()
+		_, _, err := shareOptsMapper.AutoMap()
+		if err != nil {
+			return err
+		}
 		opts := shareOptsMapper.MappedOpts().(*gitlab.ShareWithGroupOptions)
 		flags := shareOptsMapper.MappedFlags().(*shareFlags)
-		_, err := gitlabClient.Projects.ShareProjectWithGroup(*flags.Id, opts)
+		_, err = gitlabClient.Projects.ShareProjectWithGroup(*flags.Id, opts)
 		return err
 	},
 }
@@ -386,8 +425,8 @@ var projectUnshareWithGroupCmd = &cobra.Command{
 		}
 		// TODO delete a share is currently missing in go-gitlab
 		// gitlabClient.Projects...
-		OutputJson(pid)
-		OutputJson(gid)
+		Output(pid)
+		Output(gid)
 		return errors.New("not implemented...")
 	},
 }
@@ -410,11 +449,9 @@ var projectHooksListCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		hooks, _, err := gitlabClient.Projects.ListProjectHooks(pid, &gitlab.ListProjectHooksOptions{})
-		if err != nil {
-			return err
-		}
-		return OutputJson(hooks)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Projects.ListProjectHooks(pid, &gitlab.ListProjectHooksOptions{}, page)
+		})
 	},
 }
 
@@ -438,13 +475,13 @@ var projectHooksGetCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return OutputJson(hook)
+		return Output(hook)
 	},
 }
 
 type addHookFlags struct {
-	Id                    *string `flag_name:"id" short:"i" type:"integer/string" required:"yes" description:"The ID or URL-encoded path of the project"`
-	URL                   *string `flag_name:"url" short:"u" type:"string" required:"yes" description:"The hook URL"`
+	Id                    *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
+	URL                   *string `flag_name:"url" short:"u" type:"string" file:"yes" required:"yes" description:"The hook URL"`
 	PushEvents            *bool   `flag_name:"push_events" type:"bool" required:"no" description:"Trigger hook on push events"`
 	IssuesEvents          *bool   `flag_name:"issues_events" type:"bool" required:"no" description:"Trigger hook on issues events"`
 	MergeRequestsEvents   *bool   `flag_name:"merge_requests_events" type:"bool" required:"no" description:"Trigger hook on merge requests events"`
@@ -454,7 +491,7 @@ type addHookFlags struct {
 	PipelineEvents        *bool   `flag_name:"pipeline_events" type:"bool" required:"no" description:"Trigger hook on pipeline events"`
 	WikiEvents            *bool   `flag_name:"wiki_events" type:"bool" required:"no" description:"Trigger hook on wiki events"`
 	EnableSslVerification *bool   `flag_name:"enable_ssl_verification" type:"bool" required:"no" description:"Do SSL verification when triggering the hook"`
-	Token                 *string `flag_name:"token" type:"string" required:"no" description:"Secret token to validate received payloads; this will not be returned in the response"`
+	Token                 *string `flag_name:"token" type:"string" file:"yes" required:"no" description:"Secret token to validate received payloads; this will not be returned in the response"`
 }
 
 var projectAddHookCmd = &cobra.Command{
@@ -462,21 +499,24 @@ var projectAddHookCmd = &cobra.Command{
 	Short: "Add project hook",
 	Long:  `Adds a hook to a specified project.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		addHookOptsMapper.AutoMap()
+		_, _, err := addHookOptsMapper.AutoMap()
+		if err != nil {
+			return err
+		}
 		flags := addHookOptsMapper.MappedFlags().(*addHookFlags)
 		opts := addHookOptsMapper.MappedOpts().(*gitlab.AddProjectHookOptions)
 		hook, _, err := gitlabClient.Projects.AddProjectHook(parsePid(*flags.Id), opts)
 		if err != nil {
 			return err
 		}
-		return OutputJson(hook)
+		return Output(hook)
 	},
 }
 
 type editHookFlags struct {
-	Id                    *string `flag_name:"id" short:"i" type:"integer/string" required:"yes" description:"The ID or URL-encoded path of the project"`
+	Id                    *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
 	HookId                *int    `flag_name:"hook_id" type:"integer" required:"yes" description:"The ID of the project hook"`
-	URL                   *string `flag_name:"url" short:"u" type:"string" required:"yes" description:"The hook URL"`
+	URL                   *string `flag_name:"url" short:"u" type:"string" file:"yes" required:"yes" description:"The hook URL"`
 	PushEvents            *bool   `flag_name:"push_events" type:"bool" required:"no" description:"Trigger hook on push events"`
 	IssuesEvents          *bool   `flag_name:"issues_events" type:"bool" required:"no" description:"Trigger hook on issues events"`
 	MergeRequestsEvents   *bool   `flag_name:"merge_requests_events" type:"bool" required:"no" description:"Trigger hook on merge requests events"`
@@ -486,7 +526,7 @@ type editHookFlags struct {
 	PipelineEvents        *bool   `flag_name:"pipeline_events" type:"bool" required:"no" description:"Trigger hook on pipeline events"`
 	WikiEvents            *bool   `flag_name:"wiki_events" type:"bool" required:"no" description:"Trigger hook on wiki events"`
 	EnableSslVerification *bool   `flag_name:"enable_ssl_verification" type:"bool" required:"no" description:"Do SSL verification when triggering the hook"`
-	Token                 *string `flag_name:"token" type:"string" required:"no" description:"Secret token to validate received payloads; this will not be returned in the response"`
+	Token                 *string `flag_name:"token" type:"string" file:"yes" required:"no" description:"Secret token to validate received payloads; this will not be returned in the response"`
 }
 
 var projectEditHookCmd = &cobra.Command{
@@ -494,21 +534,25 @@ var projectEditHookCmd = &cobra.Command{
 	Short: "Edit project hook",
 	Long:  `Edits a hook for a specified project.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		editHookOptsMapper.AutoMap()
+		_, _, err := editHookOptsMapper.AutoMap()
+		if err != nil {
+			return err
+		}
 		flags := editHookOptsMapper.MappedFlags().(*editHookFlags)
 		opts := editHookOptsMapper.MappedOpts().(*gitlab.EditProjectHookOptions)
 		hook, _, err := gitlabClient.Projects.EditProjectHook(parsePid(*flags.Id), *flags.HookId, opts)
 		if err != nil {
 			return err
 		}
-		return OutputJson(hook)
+		return Output(hook)
 	},
 }
 
 var projectDeleteHookCmd = &cobra.Command{
-	Use:   "delete",
-	Short: "Delete project hook",
-	Long:  `Removes a hook from a project. This is an idempotent method and can be called multiple times. Either the hook is available or not.`,
+	Use:         "delete",
+	Short:       "Delete project hook",
+	Annotations: map[string]string{explicitIdAnnotation: ""},
+	Long:        `Removes a hook from a project. This is an idempotent method and can be called multiple times. Either the hook is available or not. The project is not taken from the git remote or the context, --id (or $GOLAB_PROJECT) must be given.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		pid, err := cmd.Flags().GetString("id")
 		if err != nil {
@@ -576,8 +620,8 @@ var projectForksDeleteCmd = &cobra.Command{
 
 type projectSearchFlags struct {
 	Search  *string `flag_name:"search" short:"s" type:"string" required:"yes" description:"A string contained in the project name"`
-	OrderBy *string `flag_name:"order_by" type:"string" required:"no" description:"Return requests ordered by id, name, created_at or last_activity_at fields"`
-	Sort    *string `flag_name:"sort" type:"string" required:"no" description:"Return requests sorted in asc or desc order"`
+	OrderBy *string `flag_name:"order_by" type:"string" choices:"id,name,created_at,last_activity_at" required:"no" description:"Return requests ordered by id, name, created_at or last_activity_at fields"`
+	Sort    *string `flag_name:"sort" type:"string" choices:"asc,desc" required:"no" description:"Return requests sorted in asc or desc order"`
 }
 
 var projectSearchCmd = &cobra.Command{
@@ -628,6 +672,7 @@ func init() {
 	initProjectShareCmd()
 	initProjectUnshareCmd()
 	initCommandWithIdOnly(projectHooksListCmd, projectHooksCmd)
+	initPaginationFlags(projectHooksListCmd)
 	initProjectHooksGetCmd()
 	initProjectAddHookCmd()
 	initProjectEditHookCmd()
@@ -644,6 +689,7 @@ func init() {
 
 func initProjectListCmd() {
 	listOptsMapper = mapper.InitializedMapper(projectListCmd, &listFlags{}, &gitlab.ListProjectsOptions{})
+	initPaginationFlags(projectListCmd)
 	projectCmd.AddCommand(projectListCmd)
 }
 
@@ -674,8 +720,9 @@ func initProjectListForksCmd() {
 }
 
 func initProjectUploadFileCmd() {
-	projectUploadFileCmd.PersistentFlags().StringP("id", "i", "", "(required) The ID or URL-encoded path of the project")
-	projectUploadFileCmd.PersistentFlags().StringP("file", "f", "", "(required) Path to the file to be uploaded")
+	initProjectIdFlag(projectUploadFileCmd, "(required) The ID or URL-encoded path of the project")
+	projectUploadFileCmd.PersistentFlags().StringP("file", "f", "", "(required) Path to the file to be uploaded, - to read it from stdin")
+	projectUploadFileCmd.PersistentFlags().String("name", "", "(optional) Name of the uploaded file, defaults to the name of --file")
 	projectCmd.AddCommand(projectUploadFileCmd)
 }
 
@@ -685,13 +732,13 @@ func initProjectShareCmd() {
 }
 
 func initProjectUnshareCmd() {
-	projectUnshareWithGroupCmd.PersistentFlags().StringP("id", "i", "", "The ID or URL-encoded path of the project")
+	initProjectIdFlag(projectUnshareWithGroupCmd, "The ID or URL-encoded path of the project")
 	projectUnshareWithGroupCmd.PersistentFlags().StringP("group_id", "g", "", "The ID of the group")
 	projectCmd.AddCommand(projectUnshareWithGroupCmd)
 }
 
 func initProjectHooksGetCmd() {
-	projectHooksGetCmd.PersistentFlags().StringP("id", "i", "", "(required) The ID or URL-encoded path of the project")
+	initProjectIdFlag(projectHooksGetCmd, "(required) The ID or URL-encoded path of the project")
 	projectHooksGetCmd.PersistentFlags().IntP("hook_id", "", 0, "The ID of a project hook")
 	projectHooksCmd.AddCommand(projectHooksGetCmd)
 }
@@ -707,7 +754,7 @@ func initProjectEditHookCmd() {
 }
 
 func initProjectDeleteHookCmd() {
-	projectDeleteHookCmd.PersistentFlags().StringP("id", "i", "", "The ID or URL-encoded path of the project")
+	initProjectIdFlag(projectDeleteHookCmd, "The ID or URL-encoded path of the project")
 	projectDeleteHookCmd.PersistentFlags().Int("hook_id", 0, "The ID of the project hook")
 	projectHooksCmd.AddCommand(projectDeleteHookCmd)
 }
@@ -726,10 +773,17 @@ func initProjectSearchCmd() {
 }
 
 func initCommandWithIdOnly(cmd *cobra.Command, parent *cobra.Command) {
-	cmd.PersistentFlags().StringP("id", "i", "", "(required) The ID or URL-encoded path of the project")
+	initProjectIdFlag(cmd, "(required) The ID or URL-encoded path of the project")
 	parent.AddCommand(cmd)
 }
 
+// initProjectIdFlag adds the --id flag to a command without flag mapper, it defaults to $GOLAB_PROJECT like the
+// --id flags of mapped commands
+func initProjectIdFlag(cmd *cobra.Command, usage string) {
+	cmd.PersistentFlags().StringP("id", "i", "", usage+" (default from $GOLAB_PROJECT)")
+	cmd.PersistentFlags().SetAnnotation("id", mapper.EnvAnnotation, []string{"GOLAB_PROJECT"})
+}
+
 func initCommandWithIntIdOnly(cmd *cobra.Command, parent *cobra.Command) {
 	cmd.PersistentFlags().IntP("id", "i", 0, "(required) The ID of the project")
 	parent.AddCommand(cmd)
@@ -0,0 +1,303 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/xanzy/go-gitlab"
+)
+
+// projectMember is a member of a project as returned by the API, the vendored go-gitlab does not read expires_at
+type projectMember struct {
+	gitlab.ProjectMember
+	ExpiresAt *gitlab.ISOTime `json:"expires_at"`
+}
+
+// projectMemberOptions are the parameters for adding and editing project members, including expires_at
+type projectMemberOptions struct {
+	UserID      *int                     `url:"user_id,omitempty" json:"user_id,omitempty"`
+	AccessLevel *gitlab.AccessLevelValue `url:"access_level,omitempty" json:"access_level,omitempty"`
+	ExpiresAt   *string                  `url:"expires_at,omitempty" json:"expires_at"`
+}
+
+func projectMembersPath(pid string) string {
+	return "projects/" + url.QueryEscape(pid) + "/members"
+}
+
+func listProjectMembers(pid string, opts *gitlab.ListProjectMembersOptions, options ...gitlab.OptionFunc) ([]*projectMember, *gitlab.Response, error) {
+	req, err := gitlabClient.NewRequest("GET", projectMembersPath(pid), opts, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	var members []*projectMember
+	resp, err := gitlabClient.Do(req, &members)
+	return members, resp, err
+}
+
+// projectMemberRequest sends a request for a single project member, path is appended to the members of the project
+func projectMemberRequest(method string, pid string, path string, opts *projectMemberOptions) (*projectMember, *gitlab.Response, error) {
+	var opt interface{}
+	if opts != nil {
+		opt = opts
+	}
+	req, err := gitlabClient.NewRequest(method, projectMembersPath(pid)+path, opt, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	member := &projectMember{}
+	resp, err := gitlabClient.Do(req, member)
+	if err != nil {
+		return nil, resp, err
+	}
+	return member, resp, nil
+}
+
+// memberOptions returns the access level and expiry date given on the command line
+func memberOptions(accessLevel int, expiresAt *string) (*projectMemberOptions, error) {
+	level, err := int2AccessLevel(accessLevel)
+	if err != nil {
+		return nil, err
+	}
+	opts := &projectMemberOptions{AccessLevel: level}
+	if expiresAt != nil && *expiresAt != "" {
+		opts.ExpiresAt = expiresAt
+	}
+	return opts, nil
+}
+
+var projectMembersCmd = &cobra.Command{
+	Use:         "project-members",
+	Short:       "Access project members",
+	Long:        `Show and manage members and access levels of projects`,
+	Annotations: map[string]string{idResourceAnnotation: "project"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("check usage of `project-members` with `golab project-members -h`")
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/members.html#list-all-members-of-a-group-or-project
+type projectMembersLsFlags struct {
+	Id    *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
+	Query *string `flag_name:"query" short:"q" type:"string" required:"no" description:"A query string to search for members"`
+}
+
+var projectMembersLsCmd = &golabCommand{
+	Parent: projectMembersCmd,
+	Flags:  &projectMembersLsFlags{},
+	Opts:   &gitlab.ListProjectMembersOptions{},
+	Cmd: &cobra.Command{
+		Use:   "ls",
+		Short: "List all members of a project",
+		Long:  `Gets a list of project members viewable by the authenticated user`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*projectMembersLsFlags)
+		opts := cmd.Opts.(*gitlab.ListProjectMembersOptions)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return listProjectMembers(*flags.Id, opts, page)
+		})
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/members.html#get-a-member-of-a-group-or-project
+type projectMemberGetFlags struct {
+	Id     *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
+	UserId *int    `flag_name:"user_id" short:"u" type:"integer" required:"yes" description:"The user ID of the member"`
+}
+
+var projectMemberGetCmd = &golabCommand{
+	Parent: projectMembersCmd,
+	Flags:  &projectMemberGetFlags{},
+	Cmd: &cobra.Command{
+		Use:   "get",
+		Short: "Get a member of a project",
+		Long:  `Get a member of a project`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*projectMemberGetFlags)
+		member, _, err := projectMemberRequest("GET", *flags.Id, "/"+strconv.Itoa(*flags.UserId), nil)
+		if err != nil {
+			return err
+		}
+		return Output(member)
+	},
+}
+
+const accessLevelsHelp = `
+
+  Access Levels:
+
+	10 = Guest Permissions
+	20 = Reporter Permissions
+	30 = Developer Permissions
+	40 = Master Permissions
+	50 = Owner Permissions`
+
+// see https://docs.gitlab.com/ce/api/members.html#add-a-member-to-a-group-or-project
+type projectMemberAddFlags struct {
+	Id          *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
+	UserId      *int    `flag_name:"user_id" short:"u" type:"integer" required:"yes" description:"The user ID of the new member"`
+	AccessLevel *int    `flag_name:"access_level" short:"a" type:"integer" choices:"10,20,30,40,50" required:"yes" description:"A valid access level"`
+	ExpiresAt   *string `flag_name:"expires_at" short:"e" type:"string" required:"no" description:"Expiry date of the membership (yyyy-mm-dd)"`
+}
+
+var projectMemberAddCmd = &golabCommand{
+	Parent: projectMembersCmd,
+	Flags:  &projectMemberAddFlags{},
+	Cmd: &cobra.Command{
+		Use:   "add",
+		Short: "Add a member to a project",
+		Long:  `Add a member to a project` + accessLevelsHelp,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*projectMemberAddFlags)
+		opts, err := memberOptions(*flags.AccessLevel, flags.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		opts.UserID = flags.UserId
+		member, _, err := projectMemberRequest("POST", *flags.Id, "", opts)
+		if err != nil {
+			return err
+		}
+		return Output(member)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/members.html#edit-a-member-of-a-group-or-project
+type projectMemberEditFlags struct {
+	Id          *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
+	UserId      *int    `flag_name:"user_id" short:"u" type:"integer" required:"yes" description:"The user ID of the member"`
+	AccessLevel *int    `flag_name:"access_level" short:"a" type:"integer" choices:"10,20,30,40,50" required:"yes" description:"A valid access level"`
+	ExpiresAt   *string `flag_name:"expires_at" short:"e" type:"string" required:"no" description:"Expiry date of the membership (yyyy-mm-dd)"`
+}
+
+var projectMemberEditCmd = &golabCommand{
+	Parent: projectMembersCmd,
+	Flags:  &projectMemberEditFlags{},
+	Cmd: &cobra.Command{
+		Use:   "edit",
+		Short: "Edit a member of a project",
+		Long:  `Updates the access level and expiry date of a member of a project` + accessLevelsHelp,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*projectMemberEditFlags)
+		opts, err := memberOptions(*flags.AccessLevel, flags.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		member, _, err := projectMemberRequest("PUT", *flags.Id, "/"+strconv.Itoa(*flags.UserId), opts)
+		if err != nil {
+			return err
+		}
+		return Output(member)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/members.html#remove-a-member-from-a-group-or-project
+type projectMemberDeleteFlags struct {
+	Id     *string `flag_name:"id" short:"i" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project"`
+	UserId *int    `flag_name:"user_id" short:"u" type:"integer" required:"yes" description:"The user ID of the member"`
+}
+
+var projectMemberDeleteCmd = &golabCommand{
+	Parent: projectMembersCmd,
+	Flags:  &projectMemberDeleteFlags{},
+	Cmd: &cobra.Command{
+		Use:   "delete",
+		Short: "Remove a member from a project",
+		Long:  `Removes a user from a project.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*projectMemberDeleteFlags)
+		_, err := gitlabClient.ProjectMembers.DeleteProjectMember(*flags.Id, *flags.UserId)
+		return err
+	},
+}
+
+type projectMemberSyncFlags struct {
+	Source             *string `flag_name:"source" short:"s" type:"string" required:"no" description:"The ID or path of the project to copy members from"`
+	SourceGroup        *string `flag_name:"source_group" short:"g" type:"string" required:"no" description:"The ID or path of the group to copy members from"`
+	Target             *string `flag_name:"target" short:"t" type:"string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or path of the project to copy members to"`
+	UpdateAccessLevels *bool   `flag_name:"update-access-levels" type:"bool" required:"no" description:"Update access level and expiry date of members that exist in source and target"`
+	Remove             *bool   `flag_name:"remove" short:"r" type:"bool" required:"no" description:"Remove members of the target project that are no members of the source"`
+}
+
+var projectMemberSyncCmd = &golabCommand{
+	Parent: projectMembersCmd,
+	Flags:  &projectMemberSyncFlags{},
+	Cmd: &cobra.Command{
+		Use:   "sync",
+		Short: "Synchronizes the members of a project with another project or a group",
+		Long: `Copies the members of a project (--source) or a group (--source_group) to a project (--target), by either
+
+* merging them (default) - members that exist in the target project but not in the source are kept
+* removing them (--remove) - members that exist in the target project but not in the source are deleted
+
+New members get the access level and expiry date they have in the source. Owners of a source group become
+masters of the target project, as projects in groups have no owners. With --update-access-levels, members of
+source and target get the access level and expiry date of the source, otherwise they are not changed.
+
+The changes are printed as a list with the action (add, update or remove) and status (applied, failed or skipped)
+for every member, also if a change failed. With --dry-run they
+are shown without changing the target project.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*projectMemberSyncFlags)
+		if (flags.Source == nil) == (flags.SourceGroup == nil) {
+			return errors.New("either `--source` or `--source_group` has to be given - exiting")
+		}
+		var source map[string]*liveMember
+		var err error
+		if flags.Source != nil {
+			source, err = liveProjectMembers(*flags.Source)
+		} else {
+			source, err = liveGroupMembers(*flags.SourceGroup)
+		}
+		if err != nil {
+			return err
+		}
+		target, err := liveProjectMembers(*flags.Target)
+		if err != nil {
+			return err
+		}
+		return syncMembers(source, target, projectMemberOps(*flags.Target), memberSyncOptions{
+			update:         flags.UpdateAccessLevels != nil && *flags.UpdateAccessLevels,
+			remove:         flags.Remove != nil && *flags.Remove,
+			maxAccessLevel: projectMaxAccessLevel,
+		})
+	},
+}
+
+func init() {
+	projectMembersLsCmd.Init()
+	initPaginationFlags(projectMembersLsCmd.Cmd)
+	projectMemberGetCmd.Init()
+	projectMemberAddCmd.Init()
+	projectMemberEditCmd.Init()
+	projectMemberDeleteCmd.Init()
+	projectMemberSyncCmd.Init()
+	RootCmd.AddCommand(projectMembersCmd)
+}
@@ -0,0 +1,81 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/michaellihs/golab/cmd/mapper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("project-members command", func() {
+
+	var (
+		mux    *http.ServeMux
+		server *httptest.Server
+		body   map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		resetCommandLineFlagSet()
+		mux = http.NewServeMux()
+		server = httptest.NewServer(mux)
+		gitlabClient = gitlab.NewClient(nil, "")
+		gitlabClient.SetBaseURL(server.URL + "/api/v4")
+		body = map[string]interface{}{}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("adds a member with access level and expiry date", func() {
+		mux.HandleFunc("/api/v4/projects/group/project/members", func(w http.ResponseWriter, r *http.Request) {
+			testMethod(r, "POST")
+			content, _ := ioutil.ReadAll(r.Body)
+			Expect(json.Unmarshal(content, &body)).To(BeNil())
+			fmt.Fprint(w, `{"id":40,"username":"jdoe","access_level":30,"expires_at":"2030-12-31"}`)
+		})
+		stdout, _, err := executeCommand(RootCmd, "project-members", "add", "-i", "group/project", "-u", "40", "-a", "30", "-e", "2030-12-31")
+		Expect(err).To(BeNil())
+		Expect(body).To(Equal(map[string]interface{}{"user_id": 40.0, "access_level": 30.0, "expires_at": "2030-12-31"}))
+		Expect(stdout).To(ContainSubstring(`"expires_at": "2030-12-31"`))
+	})
+
+	It("rejects unknown access levels", func() {
+		_, _, err := executeCommand(RootCmd, "project-members", "edit", "-i", "group/project", "-u", "40", "-a", "35")
+		Expect(err).To(MatchError("invalid value '35' for --access_level: expected one of 10, 20, 30, 40, 50"))
+	})
+})
+
+var _ = Describe("memberOptions", func() {
+	It("names the flag of invalid access levels", func() {
+		_, err := memberOptions(60, nil)
+		Expect(err).To(MatchError("invalid value '60' for --access_level: expected " + mapper.AccessLevels))
+	})
+})
@@ -0,0 +1,172 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// verbose holds the value of the global `--verbose` flag
+var verbose bool
+
+const (
+	defaultMaxRetries   = 3
+	defaultMaxRetryWait = 30 * time.Second
+	retryBaseWait       = 500 * time.Millisecond
+)
+
+// retryTransport retries idempotent requests on connection errors and 5xx responses with exponential backoff
+// and all requests that are rate limited (429), waiting as long as the server asks for
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	maxWait    time.Duration
+	log        io.Writer
+	sleep      func(time.Duration)
+}
+
+func newRetryTransport(next http.RoundTripper) (*retryTransport, error) {
+	t := &retryTransport{next: next, maxRetries: defaultMaxRetries, maxWait: defaultMaxRetryWait, sleep: time.Sleep}
+	if viper.IsSet("max_retries") {
+		t.maxRetries = viper.GetInt("max_retries")
+	}
+	if viper.IsSet("max_retry_wait") {
+		wait, err := time.ParseDuration(viper.GetString("max_retry_wait"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_retry_wait '%s', use a duration like 30s", viper.GetString("max_retry_wait"))
+		}
+		t.maxWait = wait
+	}
+	if verbose {
+		t.log = os.Stderr
+	}
+	return t, nil
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.GetBody == nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.WithContext(req.Context())
+			attemptReq.Body = body
+		}
+		resp, err := t.next.RoundTrip(attemptReq)
+		wait, reason, retry := t.retryWait(req, resp, err, attempt)
+		if !retry || attempt >= t.maxRetries {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if t.log != nil {
+			fmt.Fprintf(t.log, "retrying %s %s in %s (%d/%d): %s\n", req.Method, redactedUrl(req), wait, attempt+1, t.maxRetries, reason)
+		}
+		t.sleep(wait)
+	}
+}
+
+// retryWait decides whether a request is retried and how long to wait before, reason is logged in verbose mode
+func (t *retryTransport) retryWait(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, string, bool) {
+	if err != nil {
+		return t.backoff(attempt), err.Error(), isIdempotent(req.Method)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if wait, ok := rateLimitWait(resp.Header, time.Now()); ok {
+			if wait > t.maxWait {
+				wait = t.maxWait
+			}
+			return wait, resp.Status, true
+		}
+		return t.backoff(attempt), resp.Status, true
+	}
+	if resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented {
+		return t.backoff(attempt), resp.Status, isIdempotent(req.Method)
+	}
+	return 0, "", false
+}
+
+// backoff doubles the wait with every attempt, up to maxWait, and randomizes it by up to 50%
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	wait := retryBaseWait << uint(attempt)
+	if wait > t.maxWait || wait <= 0 {
+		wait = t.maxWait
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// rateLimitWait returns the wait requested by a `Retry-After` (seconds or date) or Gitlab's `RateLimit-Reset` (Unix time) header
+func rateLimitWait(header http.Header, now time.Time) (time.Duration, bool) {
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return nonNegative(date.Sub(now)), true
+		}
+	}
+	if reset, err := strconv.ParseInt(header.Get("RateLimit-Reset"), 10, 64); err == nil {
+		return nonNegative(time.Unix(reset, 0).Sub(now)), true
+	}
+	return 0, false
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("retry transport", func() {
+
+	var (
+		server    *httptest.Server
+		responses []func(w http.ResponseWriter)
+		bodies    []string
+		waits     []time.Duration
+		log       *bytes.Buffer
+		client    *http.Client
+	)
+
+	BeforeEach(func() {
+		responses, bodies, waits = nil, nil, nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			respond := responses[0]
+			if len(responses) > 1 {
+				responses = responses[1:]
+			}
+			respond(w)
+		}))
+		log = &bytes.Buffer{}
+		client = &http.Client{Transport: &retryTransport{
+			next:       http.DefaultTransport,
+			maxRetries: 2,
+			maxWait:    10 * time.Second,
+			log:        log,
+			sleep:      func(d time.Duration) { waits = append(waits, d) },
+		}}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	status := func(code int) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) { w.WriteHeader(code) }
+	}
+
+	It("retries idempotent requests on server errors and replays their body", func() {
+		responses = append(responses, status(502), status(200))
+		req, _ := http.NewRequest("PUT", server.URL+"/projects/1", strings.NewReader(`{"name":"p"}`))
+
+		resp, err := client.Do(req)
+
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(bodies).To(Equal([]string{`{"name":"p"}`, `{"name":"p"}`}))
+		Expect(waits).To(HaveLen(1))
+		Expect(waits[0]).To(BeNumerically(">=", retryBaseWait/2))
+		Expect(waits[0]).To(BeNumerically("<=", retryBaseWait))
+		Expect(log.String()).To(Equal("retrying PUT " + server.URL + "/projects/1 in " + waits[0].String() + " (1/2): 502 Bad Gateway\n"))
+	})
+
+	It("does not retry POST requests on server errors", func() {
+		responses = append(responses, status(502), status(201))
+
+		resp, err := client.Post(server.URL+"/projects", "application/json", strings.NewReader("{}"))
+
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(502))
+		Expect(bodies).To(HaveLen(1))
+	})
+
+	It("retries rate limited requests after the time given in Retry-After, up to the maximum wait", func() {
+		responses = append(responses, func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(429)
+		}, func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(429)
+		}, status(201))
+
+		resp, err := client.Post(server.URL+"/projects", "application/json", strings.NewReader("{}"))
+
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(201))
+		Expect(waits).To(Equal([]time.Duration{7 * time.Second, 10 * time.Second}))
+	})
+
+	It("gives up after the maximum number of retries", func() {
+		responses = append(responses, status(503))
+
+		resp, err := client.Get(server.URL + "/projects")
+
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(503))
+		Expect(bodies).To(HaveLen(3))
+	})
+
+	It("reads the wait from Gitlab's RateLimit-Reset header", func() {
+		now := time.Unix(1500000000, 0)
+		wait, ok := rateLimitWait(http.Header{"Ratelimit-Reset": {"1500000042"}}, now)
+
+		Expect(ok).To(BeTrue())
+		Expect(wait).To(Equal(42 * time.Second))
+	})
+
+})
@@ -24,7 +24,6 @@ import (
 	"fmt"
 	"os"
 	"net/url"
-	"encoding/json"
 	"net/http"
 	"crypto/tls"
 
@@ -40,6 +39,9 @@ var cfgFile, caFile, caPath string
 
 var gitlabClient *gitlab.Client
 
+// httpClient is the client used by gitlabClient, it sends the requests go-gitlab cannot handle (e.g. ranges)
+var httpClient *http.Client
+
 type golabCommand struct {
 	Parent *cobra.Command
 	Flags  interface{}
@@ -78,19 +80,13 @@ var RootCmd = &cobra.Command{
 func Execute() {
 	initRootCommand()
 	if err := RootCmd.Execute(); err != nil {
+		if exitErr, ok := err.(*exitCodeError); ok {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(-1)
 	}
 }
 
-func OutputJson(object interface{}) error {
-	result, err := json.MarshalIndent(object, "", "  ")
-	if err != nil {
-		return err
-	}
-	fmt.Println(string(result))
-	return nil
-}
-
 func initRootCommand() {
 	// TODO this is an ugly hack to prevent re-initialization when mocked in testing
 	if gitlabClient == nil {
@@ -98,37 +94,54 @@ func initRootCommand() {
 		cobra.OnInitialize(initGitlabClient)
 	}
 
-	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "(optional) CURRENTLY NOT SUPPORTED config file (default is ./.golab.yml and $HOME/.golab.yml)")
+	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "(optional) config file (default is ~/.config/golab/config.yml, $HOME/.golab.yml or ./.golab.yml)")
+	RootCmd.PersistentFlags().StringVar(&contextName, "context", "", "(optional) name of the context from the config file to use (default is $GOLAB_CONTEXT or current_context)")
+	RootCmd.PersistentFlags().StringVar(&remoteName, "remote", "", "(optional) git remote used to determine the project if no --id is given (default is origin)")
 	RootCmd.PersistentFlags().StringVar(&caFile, "ca-file", "", "(optional) provides a .pem file to be used in certificates pool for SSL connection")
 	RootCmd.PersistentFlags().StringVar(&caPath, "ca-path", "", "(optional) provides a directory with .pem certificates to be used for SSL connection")
+	RootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "(optional) log retried requests to stderr")
+	RootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "(optional) log method, URL, status and duration of every request to stderr")
+	RootCmd.PersistentFlags().BoolVar(&trace, "trace", false, "(optional) like --debug, but also log headers and bodies of requests and responses")
+	RootCmd.PersistentFlags().BoolVar(&curl, "curl", false, "(optional) log an equivalent curl command for every request to stderr")
+	RootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "(optional) print all requests that would change data (everything but GET) instead of sending them")
+	RootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "json", "(optional) output format: json, ndjson, yaml, table, csv, tsv, ids or go-template=<template>; table, csv and tsv accept a list of columns, e.g. table=id,name")
 }
 
 func initConfig() {
 	if cfgFile != "" { // enable ability to specify config file via flag
 		viper.SetConfigFile(cfgFile)
+	} else if found := findConfigFile(); found != "" {
+		viper.SetConfigFile(found)
 	}
+	viper.AutomaticEnv() // read in environment variables that match
 
-	viper.SetConfigName(".golab") // name of config file (without extension)
-	viper.AddConfigPath("$HOME")  // adding home directory as first search path
-	viper.AddConfigPath(".")      // adding current directory as first search path
-	viper.AutomaticEnv()          // read in environment variables that match
-
-	if err := viper.ReadInConfig(); err != nil {
-		fmt.Println(err)
+	if viper.ConfigFileUsed() != "" {
+		if err := viper.ReadInConfig(); err != nil {
+			configError = fmt.Errorf("cannot read config file %s: %s", viper.ConfigFileUsed(), err)
+			return
+		}
 	}
+	contextError = applyContext()
 }
 
 func initGitlabClient() {
+	if configError != nil {
+		// the command fails with the error before the client is used
+		return
+	}
 	baseUrl, err := url.Parse(viper.GetString("url"))
 	if err != nil {
 		fmt.Printf("Could not parse given URL '%s': %s", baseUrl, err)
 	}
 
-	httpClient, err := initHttpClient()
+	httpClient, err = initHttpClient()
 	if err != nil {
 		panic("Error in initializing http client " + err.Error())
 	}
 
+	if err := withConfiguredToken(httpClient); err != nil {
+		panic("Error in initializing http client " + err.Error())
+	}
 	gitlabClient = gitlab.NewClient(httpClient, viper.GetString("token"))
 	gitlabClient.SetBaseURL(baseUrl.String() + "/api/v4")
 }
@@ -152,7 +165,18 @@ func initHttpClient() (*http.Client, error) {
 	//fmt.Println(tlsConfig.RootCAs)
 
 	t.TLSClientConfig = tlsConfig
-	c.Transport = t
+	var transport http.RoundTripper = t
+	if debug || trace || curl {
+		transport = &debugTransport{next: t, out: os.Stderr, debug: debug, trace: trace, curl: curl}
+	}
+	retry, err := newRetryTransport(transport)
+	if err != nil {
+		return nil, err
+	}
+	c.Transport = retry
+	if dryRun {
+		c.Transport = &dryRunTransport{next: retry, out: os.Stderr}
+	}
 	return c, nil
 
 	// TODO this is an ugly hack to prevent SSL verification... see https://github.com/andygrunwald/go-jira/issues/52
@@ -161,8 +185,3 @@ func initHttpClient() (*http.Client, error) {
 	//}
 	//return &http.Client{Transport: tr}, nil
 }
-
-func isoTime2String(time *gitlab.ISOTime) (string, error) {
-	bytes, err := time.MarshalJSON()
-	return string(bytes), err
-}
@@ -0,0 +1,185 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xanzy/go-gitlab"
+)
+
+// see https://docs.gitlab.com/ce/api/tags.html
+var tagsCmd = &golabCommand{
+	Parent: RootCmd,
+	Cmd: &cobra.Command{
+		Use:         "tags",
+		Aliases:     []string{"tag"},
+		Short:       "Tags and releases",
+		Long:        `Manage repository tags and their release notes`,
+		Annotations: map[string]string{idResourceAnnotation: "project"},
+	},
+	Run: func(cmd golabCommand) error {
+		return errors.New("cannot use this command without further sub-commands")
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/tags.html#list-project-repository-tags
+type tagsListFlags struct {
+	Id *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+}
+
+var tagsListCmd = &golabCommand{
+	Parent: tagsCmd.Cmd,
+	Flags:  &tagsListFlags{},
+	Cmd: &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List project repository tags",
+		Long:    `Get a list of repository tags from a project, sorted by name in reverse alphabetical order.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*tagsListFlags)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Tags.ListTags(parsePid(*flags.Id), page)
+		})
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/tags.html#get-a-single-repository-tag
+type tagsGetFlags struct {
+	Id      *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	TagName *string `flag_name:"tag_name" short:"t" type:"string" required:"yes" description:"The name of the tag"`
+}
+
+var tagsGetCmd = &golabCommand{
+	Parent: tagsCmd.Cmd,
+	Flags:  &tagsGetFlags{},
+	Cmd: &cobra.Command{
+		Use:   "get",
+		Short: "Get a single repository tag",
+		Long:  `Get a specific repository tag determined by its name, including its release notes.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*tagsGetFlags)
+		tag, _, err := gitlabClient.Tags.GetTag(parsePid(*flags.Id), *flags.TagName)
+		if err != nil {
+			return err
+		}
+		return Output(tag)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/tags.html#create-a-new-tag
+type tagsCreateFlags struct {
+	Id                 *string   `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	TagName            *string   `flag_name:"tag_name" short:"t" type:"string" required:"yes" description:"The name of the tag"`
+	Ref                *string   `flag_name:"ref" short:"r" type:"string" required:"yes" description:"Create tag using commit SHA, another tag name, or branch name"`
+	Message            *string   `flag_name:"message" short:"m" type:"string" file:"raw" required:"no" description:"Creates an annotated tag with this message"`
+	ReleaseDescription *string   `flag_name:"release_description" short:"d" type:"string" file:"raw" required:"no" description:"Add release notes to the git tag and store it in the GitLab database"`
+	Attach             *[]string `flag_name:"attach" short:"a" type:"array" required:"no" description:"Upload this file to the project and link it in the release notes, can be given multiple times"`
+}
+
+var tagsCreateCmd = &golabCommand{
+	Parent: tagsCmd.Cmd,
+	Flags:  &tagsCreateFlags{},
+	Opts:   &gitlab.CreateTagOptions{},
+	Cmd: &cobra.Command{
+		Use:   "create",
+		Short: "Create a new tag",
+		Long: `Creates a new tag in the repository that points to the supplied ref, optionally with release notes.
+
+Files given with --attach are uploaded to the project first and linked at the end of the release notes:
+
+    golab tags create -t v1.2.0 -r master -d @CHANGELOG.md -a dist/app.tar.gz -a dist/app.zip`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*tagsCreateFlags)
+		opts := cmd.Opts.(*gitlab.CreateTagOptions)
+		if flags.Attach != nil {
+			notes, err := attachUploads(*flags.Id, opts.ReleaseDescription, *flags.Attach)
+			if err != nil {
+				return err
+			}
+			opts.ReleaseDescription = &notes
+		}
+		tag, _, err := gitlabClient.Tags.CreateTag(parsePid(*flags.Id), opts)
+		if err != nil {
+			return err
+		}
+		return Output(tag)
+	},
+}
+
+// attachUploads uploads the files to the project and appends their markdown links to the release notes,
+// one link per line
+func attachUploads(pid string, notes *string, files []string) (string, error) {
+	var links []string
+	for _, file := range files {
+		if file == "-" {
+			return "", errors.New("attachments cannot be read from stdin, use a file instead")
+		}
+		name, content, err := readUpload(file, "")
+		if err != nil {
+			return "", err
+		}
+		projectFile, err := uploadProjectFile(pid, name, content)
+		if err != nil {
+			return "", err
+		}
+		links = append(links, projectFile.Markdown)
+	}
+	if notes == nil || *notes == "" {
+		return strings.Join(links, "\n"), nil
+	}
+	return strings.TrimRight(*notes, "\n") + "\n\n" + strings.Join(links, "\n"), nil
+}
+
+// see https://docs.gitlab.com/ce/api/tags.html#delete-a-tag
+type tagsDeleteFlags struct {
+	Id      *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	TagName *string `flag_name:"tag_name" short:"t" type:"string" required:"yes" description:"The name of the tag"`
+}
+
+var tagsDeleteCmd = &golabCommand{
+	Parent: tagsCmd.Cmd,
+	Flags:  &tagsDeleteFlags{},
+	Cmd: &cobra.Command{
+		Use:   "delete",
+		Short: "Delete a tag",
+		Long:  `Deletes a tag of the repository with the given name.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*tagsDeleteFlags)
+		_, err := gitlabClient.Tags.DeleteTag(parsePid(*flags.Id), *flags.TagName)
+		return err
+	},
+}
+
+func init() {
+	tagsCmd.Init()
+	tagsListCmd.Init()
+	initPaginationFlags(tagsListCmd.Cmd)
+	tagsGetCmd.Init()
+	tagsCreateCmd.Init()
+	tagsDeleteCmd.Init()
+}
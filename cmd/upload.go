@@ -0,0 +1,111 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/michaellihs/golab/cmd/mapper"
+	"github.com/xanzy/go-gitlab"
+)
+
+// readUpload returns the name and the unchanged content of a file given as `path`, `@path` or `-` for stdin,
+// name is used for stdin as there is no file name
+func readUpload(value string, name string) (string, []byte, error) {
+	if value == "-" && name == "" {
+		return "", nil, errors.New("required parameter `--name` not given for upload from stdin - exiting")
+	}
+	content, err := mapper.ReadValue(value)
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" {
+		name = filepath.Base(strings.TrimPrefix(value, "@"))
+	}
+	return name, content, nil
+}
+
+// uploadFile sends the content as multipart form field to the Gitlab API, since go-gitlab only uploads files from disk
+func uploadFile(method string, path string, field string, name string, content []byte, v interface{}) (*gitlab.Response, error) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	fw, err := w.CreateFormFile(field, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = fw.Write(content); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	req, err := gitlabClient.NewRequest("", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Method = method
+	req.Body = ioutil.NopCloser(body)
+	req.ContentLength = int64(body.Len())
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return gitlabClient.Do(req, v)
+}
+
+// uploadProjectFile uploads a file to a project to be linked in descriptions or comments, see
+// https://docs.gitlab.com/ce/api/projects.html#upload-a-file
+func uploadProjectFile(pid string, name string, content []byte) (*gitlab.ProjectFile, error) {
+	projectFile := &gitlab.ProjectFile{}
+	if _, err := uploadFile("POST", "projects/"+url.QueryEscape(pid)+"/uploads", "file", name, content, projectFile); err != nil {
+		return nil, err
+	}
+	return projectFile, nil
+}
+
+// uploadAvatar sets the avatar of the project to the image given as `path`, `@path` or `-` for stdin
+func uploadAvatar(pid string, value string) (*gitlab.Project, error) {
+	content, err := mapper.ReadValue(value)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(strings.TrimPrefix(value, "@"))
+	if value == "-" {
+		// Gitlab checks the extension of the file name, so we derive it from the content
+		name = "avatar" + imageExtensions[http.DetectContentType(content)]
+	}
+	project := &gitlab.Project{}
+	if _, err = uploadFile("PUT", "projects/"+url.QueryEscape(pid), "avatar", name, content, project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/bmp":  ".bmp",
+}
@@ -67,7 +67,7 @@ var userGetCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(user)
+		return Output(user)
 	},
 }
 
@@ -109,18 +109,16 @@ var userLsCmd = &golabCommand{
 	},
 	Run: func(cmd golabCommand) error {
 		opts := cmd.Opts.(*gitlab.ListUsersOptions)
-		users, _, err := gitlabClient.Users.ListUsers(opts)
-		if err != nil {
-			return err
-		}
-		return OutputJson(users)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Users.ListUsers(opts, page)
+		})
 	},
 }
 
 // see https://docs.gitlab.com/ce/api/users.html#user-creation
 type userCreateFlags struct {
 	Email            *string `flag_name:"email" short:"e" type:"string" required:"yes" description:"Email"`
-	Password         *string `flag_name:"password" short:"p" type:"string" required:"no" description:"Password"`
+	Password         *string `flag_name:"password" short:"p" type:"string" file:"yes" required:"no" description:"Password"`
 	ResetPassword    *bool   `flag_name:"reset_password" type:"bool" required:"no" description:"Send user password reset link - true or false(default)"`
 	Username         *string `flag_name:"username" short:"u" type:"string" required:"yes" description:"Username"`
 	Name             *string `flag_name:"name" short:"n" type:"string" required:"yes" description:"Name"`
@@ -153,12 +151,12 @@ var userCreateCmd = &golabCommand{
 	},
 	Run: func(cmd golabCommand) error {
 		opts := cmd.Opts.(*gitlab.CreateUserOptions)
-		OutputJson(opts)
+		Output(opts)
 		user, _, err := gitlabClient.Users.CreateUser(opts)
 		if err != nil {
 			return err
 		}
-		return OutputJson(user)
+		return Output(user)
 	},
 }
 
@@ -203,7 +201,7 @@ func userIdFromFlag(intStrId string) (int, error) {
 type userModifyFlags struct {
 	Id               *string `flag_name:"id" short:"i" type:"string" required:"yes" description:"User ID or user name of user to be deleted"`
 	Email            *string `flag_name:"email" short:"e" type:"string" required:"no" description:"Email"`
-	Password         *string `flag_name:"password" short:"p" type:"string" required:"no" description:"Password"`
+	Password         *string `flag_name:"password" short:"p" type:"string" file:"yes" required:"no" description:"Password"`
 	Username         *string `flag_name:"username" short:"u" type:"string" required:"no" description:"Username"`
 	Name             *string `flag_name:"name" short:"n" type:"string" required:"no" description:"Name"`
 	Skype            *string `flag_name:"skype" type:"string" required:"no" description:"Skype ID"`
@@ -244,7 +242,7 @@ var userModifyCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(user)
+		return Output(user)
 	},
 }
 
@@ -293,7 +291,7 @@ var userSshKeysListCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(sshKeys)
+		return Output(sshKeys)
 	},
 }
 
@@ -317,7 +315,7 @@ var userSshKeysGetCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(sshKey)
+		return Output(sshKey)
 	},
 }
 
@@ -325,7 +323,7 @@ var userSshKeysGetCmd = &golabCommand{
 type userSshKeysAddFlags struct {
 	User  *string `flag_name:"user" short:"u" type:"string" required:"yes" description:"User ID or user name of user to delete SSH key from"`
 	Title *string `flag_name:"title" short:"t" type:"string" required:"yes" description:"New SSH Key's title"`
-	Key   *string `flag_name:"key" short:"k" type:"string" required:"yes" description:"Public SSH key"`
+	Key   *string `flag_name:"key" short:"k" type:"string" file:"yes" required:"yes" description:"Public SSH key"`
 }
 
 var userSshKeysAddCmd = &golabCommand{
@@ -354,7 +352,7 @@ var userSshKeysAddCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(key)
+		return Output(key)
 	},
 }
 
@@ -412,11 +410,9 @@ By default, it shows the activity for all users in the last 6 months, but this c
 	Run: func(cmd golabCommand) error {
 		// TODO From flag currently not supported by go-gitlab
 		// flags := cmd.Flags.(*userActivitiesFlags)
-		userActivities, _, err := gitlabClient.Users.GetUserActivities()
-		if err != nil {
-			return err
-		}
-		return OutputJson(userActivities)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Users.GetUserActivities(page)
+		})
 	},
 }
 
@@ -438,7 +434,7 @@ var userImpersonationTokenCmd = &golabCommand{
 // see https://docs.gitlab.com/ce/api/users.html#get-all-impersonation-tokens-of-a-user
 type userImpersonationTokenGetAllFlags struct {
 	UserId *string `flag_name:"user_id" short:"u" type:"string" required:"yes" description:"The ID of the user or the name of the user to get tokens for"`
-	State  *string `flag_name:"state" short:"s" type:"string" required:"no" description:"filter tokens based on state (all, active, inactive)"`
+	State  *string `flag_name:"state" short:"s" type:"string" choices:"all,active,inactive" required:"no" description:"filter tokens based on state (all, active, inactive)"`
 }
 
 var userImpersonationTokenGetAllCmd = &golabCommand{
@@ -457,11 +453,9 @@ var userImpersonationTokenGetAllCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		tokens, _, err := gitlabClient.Users.GetAllImpersonationTokens(userId, opts)
-		if err != nil {
-			return err
-		}
-		return OutputJson(tokens)
+		return OutputList(func(page gitlab.OptionFunc) (interface{}, *gitlab.Response, error) {
+			return gitlabClient.Users.GetAllImpersonationTokens(userId, opts, page)
+		})
 	},
 }
 
@@ -490,7 +484,7 @@ var userImpersonationTokenGetCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(token)
+		return Output(token)
 	},
 }
 
@@ -522,7 +516,7 @@ var userImpersonationTokenCreateCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(token)
+		return Output(token)
 	},
 }
 
@@ -595,7 +589,7 @@ If a user_id is given: Get a list of a specified user's emails. Available only f
 		if err != nil {
 			return err
 		}
-		return OutputJson(emails)
+		return Output(emails)
 	},
 }
 
@@ -618,7 +612,7 @@ var userEmailsGetCmd = &golabCommand{
 		if err != nil {
 			return err
 		}
-		return OutputJson(email)
+		return Output(email)
 	},
 }
 
@@ -657,7 +651,7 @@ Will return created email on success.`,
 		if err != nil {
 			return err
 		}
-		return OutputJson(email)
+		return Output(email)
 	},
 }
 
@@ -758,6 +752,7 @@ func getUserId(id int, username string) (int, error) {
 func init() {
 	userGetCmd.Init()
 	userLsCmd.Init()
+	initPaginationFlags(userLsCmd.Cmd)
 	userCreateCmd.Init()
 	userModifyCmd.Init()
 	userDeleteCmd.Init()
@@ -767,8 +762,10 @@ func init() {
 	userSshKeysAddCmd.Init()
 	userSshKeysDeleteCmd.Init()
 	userActivitiesCmd.Init()
+	initPaginationFlags(userActivitiesCmd.Cmd)
 	userImpersonationTokenCmd.Init()
 	userImpersonationTokenGetAllCmd.Init()
+	initPaginationFlags(userImpersonationTokenGetAllCmd.Cmd)
 	userImpersonationTokenGetCmd.Init()
 	userImpersonationTokenCreateCmd.Init()
 	userImpersonationTokenRevokeCmd.Init()
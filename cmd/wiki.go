@@ -0,0 +1,275 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/xanzy/go-gitlab"
+)
+
+// see https://docs.gitlab.com/ce/api/wikis.html
+var wikiCmd = &golabCommand{
+	Parent: RootCmd,
+	Cmd: &cobra.Command{
+		Use:         "wiki",
+		Aliases:     []string{"wikis"},
+		Short:       "Wiki pages",
+		Long:        `Manage the wiki pages of a project and keep them in a local directory`,
+		Annotations: map[string]string{idResourceAnnotation: "project"},
+	},
+	Run: func(cmd golabCommand) error {
+		return errors.New("cannot use this command without further sub-commands")
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/wikis.html#list-wiki-pages
+type wikiListFlags struct {
+	Id          *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	WithContent *bool   `flag_name:"with_content" short:"c" type:"boolean" required:"no" description:"Include pages' content"`
+}
+
+var wikiListCmd = &golabCommand{
+	Parent: wikiCmd.Cmd,
+	Flags:  &wikiListFlags{},
+	Opts:   &gitlab.ListWikisOptions{},
+	Cmd: &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List wiki pages",
+		Long:    `Get all wiki pages for a given project.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*wikiListFlags)
+		opts := cmd.Opts.(*gitlab.ListWikisOptions)
+		pages, _, err := gitlabClient.Wikis.ListWikis(parsePid(*flags.Id), opts)
+		if err != nil {
+			return err
+		}
+		return Output(pages)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/wikis.html#get-a-wiki-page
+type wikiGetFlags struct {
+	Id   *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Slug *string `flag_name:"slug" short:"s" type:"string" required:"yes" description:"The slug (a unique string) of the wiki page"`
+}
+
+var wikiGetCmd = &golabCommand{
+	Parent: wikiCmd.Cmd,
+	Flags:  &wikiGetFlags{},
+	Cmd: &cobra.Command{
+		Use:   "get",
+		Short: "Get a wiki page",
+		Long:  `Get a wiki page for a given project.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*wikiGetFlags)
+		page, _, err := gitlabClient.Wikis.GetWikiPage(parsePid(*flags.Id), *flags.Slug)
+		if err != nil {
+			return err
+		}
+		return Output(page)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/wikis.html#create-a-new-wiki-page
+type wikiCreateFlags struct {
+	Id      *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Title   *string `flag_name:"title" short:"t" type:"string" required:"yes" description:"The title of the wiki page"`
+	Content *string `flag_name:"content" short:"c" type:"string" file:"raw" required:"yes" description:"The content of the wiki page"`
+	Format  *string `flag_name:"format" short:"f" type:"string" choices:"markdown,rdoc,asciidoc" required:"no" description:"The format of the wiki page"`
+}
+
+var wikiCreateCmd = &golabCommand{
+	Parent: wikiCmd.Cmd,
+	Flags:  &wikiCreateFlags{},
+	Opts:   &gitlab.CreateWikiPageOptions{},
+	Cmd: &cobra.Command{
+		Use:   "create",
+		Short: "Create a new wiki page",
+		Long: `Creates a new wiki page for the given repository with the given title, slug, and content.
+
+    golab wiki create -t "Getting Started" -c @docs/getting-started.md`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*wikiCreateFlags)
+		opts := cmd.Opts.(*gitlab.CreateWikiPageOptions)
+		page, _, err := gitlabClient.Wikis.CreateWikiPage(parsePid(*flags.Id), opts)
+		if err != nil {
+			return err
+		}
+		return Output(page)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/wikis.html#edit-an-existing-wiki-page
+type wikiUpdateFlags struct {
+	Id      *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Slug    *string `flag_name:"slug" short:"s" type:"string" required:"yes" description:"The slug (a unique string) of the wiki page"`
+	Title   *string `flag_name:"title" short:"t" type:"string" required:"no" description:"The new title of the wiki page"`
+	Content *string `flag_name:"content" short:"c" type:"string" file:"raw" required:"no" description:"The new content of the wiki page"`
+	Format  *string `flag_name:"format" short:"f" type:"string" choices:"markdown,rdoc,asciidoc" required:"no" description:"The new format of the wiki page"`
+}
+
+var wikiUpdateCmd = &golabCommand{
+	Parent: wikiCmd.Cmd,
+	Flags:  &wikiUpdateFlags{},
+	Opts:   &gitlab.EditWikiPageOptions{},
+	Cmd: &cobra.Command{
+		Use:   "update",
+		Short: "Edit an existing wiki page",
+		Long:  `Updates an existing wiki page. At least one parameter is required to update the wiki page.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*wikiUpdateFlags)
+		opts := cmd.Opts.(*gitlab.EditWikiPageOptions)
+		if opts.Title == nil && opts.Content == nil && opts.Format == nil {
+			return errors.New("one of `--title`, `--content` or `--format` is required - exiting")
+		}
+		// go-gitlab always sends title and content, so missing values are taken from the current page
+		if opts.Title == nil || opts.Content == nil {
+			current, _, err := gitlabClient.Wikis.GetWikiPage(parsePid(*flags.Id), *flags.Slug)
+			if err != nil {
+				return err
+			}
+			if opts.Title == nil {
+				opts.Title = gitlab.String(wikiTitlePath(current.Slug, current.Title))
+			}
+			if opts.Content == nil {
+				opts.Content = &current.Content
+			}
+		}
+		page, _, err := gitlabClient.Wikis.EditWikiPage(parsePid(*flags.Id), *flags.Slug, opts)
+		if err != nil {
+			return err
+		}
+		return Output(page)
+	},
+}
+
+// see https://docs.gitlab.com/ce/api/wikis.html#delete-a-wiki-page
+type wikiDeleteFlags struct {
+	Id   *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Slug *string `flag_name:"slug" short:"s" type:"string" required:"yes" description:"The slug (a unique string) of the wiki page"`
+}
+
+var wikiDeleteCmd = &golabCommand{
+	Parent: wikiCmd.Cmd,
+	Flags:  &wikiDeleteFlags{},
+	Cmd: &cobra.Command{
+		Use:   "delete",
+		Short: "Delete a wiki page",
+		Long:  `Deletes a wiki page with a given slug.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*wikiDeleteFlags)
+		_, err := gitlabClient.Wikis.DeleteWikiPage(parsePid(*flags.Id), *flags.Slug)
+		return err
+	},
+}
+
+type wikiExportFlags struct {
+	Id  *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Dir *string `flag_name:"dir" short:"d" type:"string" required:"yes" description:"The directory to write the wiki pages to, it is created if it does not exist"`
+}
+
+var wikiExportCmd = &golabCommand{
+	Parent: wikiCmd.Cmd,
+	Flags:  &wikiExportFlags{},
+	Cmd: &cobra.Command{
+		Use:   "export",
+		Short: "Write all wiki pages to a local directory",
+		Long: `Writes every wiki page to a file named after its slug with the extension of its format (.md, .rdoc or .adoc).
+Pages in wiki directories are written to sub-directories. Each file starts with a front matter that holds the title
+and format of the page:
+
+    ---
+    title: Getting Started
+    format: markdown
+    ---
+    # Getting Started
+
+Existing files are overwritten, files of pages that no longer exist are kept.`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*wikiExportFlags)
+		pages, _, err := gitlabClient.Wikis.ListWikis(parsePid(*flags.Id), &gitlab.ListWikisOptions{WithContent: gitlab.Bool(true)})
+		if err != nil {
+			return err
+		}
+		for _, page := range pages {
+			if err := writeWikiPage(*flags.Dir, page); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("** %d wiki pages written to %s\n", len(pages), *flags.Dir)
+		return nil
+	},
+}
+
+type wikiImportFlags struct {
+	Id    *string `flag_name:"id" short:"i" type:"integer/string" env:"GOLAB_PROJECT" required:"yes" description:"The ID or URL-encoded path of the project owned by the authenticated user"`
+	Dir   *string `flag_name:"dir" short:"d" type:"string" required:"yes" description:"The directory to read the wiki pages from"`
+	Prune *bool   `flag_name:"prune" type:"boolean" required:"no" description:"Delete wiki pages that do not exist in the directory"`
+}
+
+var wikiImportCmd = &golabCommand{
+	Parent: wikiCmd.Cmd,
+	Flags:  &wikiImportFlags{},
+	Cmd: &cobra.Command{
+		Use:   "import",
+		Short: "Create or update wiki pages from a local directory",
+		Long: `Reads the .md, .rdoc and .adoc files of a directory - as written by 'golab wiki export' - and creates the pages
+that do not exist and updates the pages whose title, format or content differ. A page is identified by its directory
+and title, the title is taken from the front matter or, without front matter, from the file name ("getting-started.md"
+becomes "getting started"). The format defaults to the one of the file extension. Pages that only exist in the wiki
+are deleted with --prune. The changes are printed before they are made, use --dry-run to only print them:
+
+    golab wiki export -d wiki && vi wiki/home.md && golab wiki import -d wiki --prune --dry-run`,
+	},
+	Run: func(cmd golabCommand) error {
+		flags := cmd.Flags.(*wikiImportFlags)
+		local, err := readWikiDir(*flags.Dir)
+		if err != nil {
+			return err
+		}
+		remote, _, err := gitlabClient.Wikis.ListWikis(parsePid(*flags.Id), &gitlab.ListWikisOptions{WithContent: gitlab.Bool(true)})
+		if err != nil {
+			return err
+		}
+		return applyChanges(planWiki(*flags.Id, local, remote, flags.Prune != nil && *flags.Prune))
+	},
+}
+
+func init() {
+	wikiCmd.Init()
+	wikiListCmd.Init()
+	wikiGetCmd.Init()
+	wikiCreateCmd.Init()
+	wikiUpdateCmd.Init()
+	wikiDeleteCmd.Init()
+	wikiExportCmd.Init()
+	wikiImportCmd.Init()
+}
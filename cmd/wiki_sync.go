@@ -0,0 +1,232 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+	"gopkg.in/yaml.v2"
+)
+
+// wikiExtensions are the file extensions of the wiki page formats
+var wikiExtensions = map[string]string{
+	"markdown": ".md",
+	"rdoc":     ".rdoc",
+	"asciidoc": ".adoc",
+}
+
+// wikiPage is a wiki page read from a local directory
+type wikiPage struct {
+	slug    string
+	title   string
+	format  string
+	content string
+}
+
+// wikiFrontMatter is the YAML block at the beginning of exported wiki pages
+type wikiFrontMatter struct {
+	Title  string `yaml:"title"`
+	Format string `yaml:"format"`
+}
+
+const frontMatterDelimiter = "---\n"
+
+func wikiFormat(page *gitlab.Wiki) string {
+	if page.Format == "" {
+		return "markdown"
+	}
+	return string(page.Format)
+}
+
+// renderWikiPage returns the content of the page with a front matter that holds its title and format
+func renderWikiPage(page *gitlab.Wiki) ([]byte, error) {
+	frontMatter, err := yaml.Marshal(&wikiFrontMatter{Title: page.Title, Format: wikiFormat(page)})
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	b.WriteString(frontMatterDelimiter)
+	b.Write(frontMatter)
+	b.WriteString(frontMatterDelimiter)
+	b.WriteString(page.Content)
+	return b.Bytes(), nil
+}
+
+func writeWikiPage(dir string, page *gitlab.Wiki) error {
+	ext, ok := wikiExtensions[wikiFormat(page)]
+	if !ok {
+		return fmt.Errorf("wiki page %s has the unknown format %s", page.Slug, page.Format)
+	}
+	content, err := renderWikiPage(page)
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(dir, filepath.FromSlash(page.Slug)+ext)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, content, 0644)
+}
+
+// parseWikiPage reads a wiki page from a file with the path rel relative to the wiki directory, the front matter is
+// optional, ok is false for files without the extension of a wiki format
+func parseWikiPage(rel string, content []byte) (page *wikiPage, ok bool, err error) {
+	ext := path.Ext(rel)
+	page = &wikiPage{content: string(content)}
+	for format, formatExt := range wikiExtensions {
+		if ext == formatExt {
+			page.format = format
+		}
+	}
+	if page.format == "" {
+		return nil, false, nil
+	}
+	page.title = strings.Replace(path.Base(strings.TrimSuffix(rel, ext)), "-", " ", -1)
+	if strings.HasPrefix(page.content, frontMatterDelimiter) {
+		end := strings.Index(page.content[len(frontMatterDelimiter):], "\n"+frontMatterDelimiter)
+		if end < 0 {
+			return nil, false, fmt.Errorf("front matter of %s is not closed with ---", rel)
+		}
+		end += len(frontMatterDelimiter)
+		frontMatter := &wikiFrontMatter{}
+		if err := yaml.Unmarshal([]byte(page.content[len(frontMatterDelimiter):end]), frontMatter); err != nil {
+			return nil, false, fmt.Errorf("could not parse front matter of %s: %s", rel, err)
+		}
+		if frontMatter.Title != "" {
+			page.title = frontMatter.Title
+		}
+		if frontMatter.Format != "" {
+			if _, known := wikiExtensions[frontMatter.Format]; !known {
+				return nil, false, fmt.Errorf("unknown format %s in %s", frontMatter.Format, rel)
+			}
+			page.format = frontMatter.Format
+		}
+		page.content = page.content[end+1+len(frontMatterDelimiter):]
+	}
+	page.slug = wikiSlug(path.Dir(rel), page.title)
+	return page, true, nil
+}
+
+// wikiSlug returns the slug Gitlab derives from the title of a page in the wiki directory dir
+func wikiSlug(dir string, title string) string {
+	return path.Join(dir, strings.Replace(title, " ", "-", -1))
+}
+
+// wikiTitlePath returns the title to send for a page, pages in wiki directories are created and kept in their
+// directory by prefixing the title with it
+func wikiTitlePath(slug string, title string) string {
+	if dir := path.Dir(slug); dir != "." {
+		return dir + "/" + title
+	}
+	return title
+}
+
+// readWikiDir reads the wiki pages of a directory ordered by slug
+func readWikiDir(dir string) ([]*wikiPage, error) {
+	files, err := localFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var pages []*wikiPage
+	sources := map[string]string{}
+	for rel, content := range files {
+		page, ok, err := parseWikiPage(rel, content)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if other, exists := sources[page.slug]; exists {
+			return nil, fmt.Errorf("%s and %s are both the wiki page %s", other, rel, page.slug)
+		}
+		sources[page.slug] = rel
+		pages = append(pages, page)
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].slug < pages[j].slug
+	})
+	return pages, nil
+}
+
+// planWiki compares the local with the remote wiki pages by their slug
+func planWiki(pid string, local []*wikiPage, remote []*gitlab.Wiki, remove bool) []*change {
+	var changes []*change
+	remoteBySlug := map[string]*gitlab.Wiki{}
+	for _, page := range remote {
+		remoteBySlug[page.Slug] = page
+	}
+	localSlugs := map[string]bool{}
+	for _, page := range local {
+		page := page
+		localSlugs[page.slug] = true
+		resource := fmt.Sprintf("wiki page %s of project %s", page.slug, pid)
+		current, exists := remoteBySlug[page.slug]
+		if !exists {
+			changes = append(changes, &change{action: "create", resource: resource, details: []string{"title: " + page.title, "format: " + page.format}, apply: func() error {
+				_, _, err := gitlabClient.Wikis.CreateWikiPage(pid, &gitlab.CreateWikiPageOptions{Title: gitlab.String(wikiTitlePath(page.slug, page.title)), Content: &page.content, Format: &page.format})
+				return err
+			}})
+			continue
+		}
+		var details []string
+		if current.Title != page.title {
+			details = append(details, fmt.Sprintf("title: %s => %s", current.Title, page.title))
+		}
+		if wikiFormat(current) != page.format {
+			details = append(details, fmt.Sprintf("format: %s => %s", wikiFormat(current), page.format))
+		}
+		if current.Content != page.content {
+			details = append(details, "content changed")
+		}
+		if len(details) > 0 {
+			changes = append(changes, &change{action: "update", resource: resource, details: details, apply: func() error {
+				_, _, err := gitlabClient.Wikis.EditWikiPage(pid, page.slug, &gitlab.EditWikiPageOptions{Title: gitlab.String(wikiTitlePath(page.slug, page.title)), Content: &page.content, Format: &page.format})
+				return err
+			}})
+		}
+	}
+	if remove {
+		var slugs []string
+		for _, page := range remote {
+			if !localSlugs[page.Slug] {
+				slugs = append(slugs, page.Slug)
+			}
+		}
+		sort.Strings(slugs)
+		for _, slug := range slugs {
+			slug := slug
+			changes = append(changes, &change{action: "delete", resource: fmt.Sprintf("wiki page %s of project %s", slug, pid), apply: func() error {
+				_, err := gitlabClient.Wikis.DeleteWikiPage(pid, slug)
+				return err
+			}})
+		}
+	}
+	return changes
+}
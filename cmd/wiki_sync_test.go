@@ -0,0 +1,99 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("wiki sync", func() {
+
+	It("renders pages with a front matter and parses them back", func() {
+		content, err := renderWikiPage(&gitlab.Wiki{Slug: "guides/Getting-Started", Title: "Getting Started", Content: "# Start\n---\n"})
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(Equal("---\ntitle: Getting Started\nformat: markdown\n---\n# Start\n---\n"))
+
+		page, ok, err := parseWikiPage("guides/Getting-Started.md", content)
+		Expect(err).To(BeNil())
+		Expect(ok).To(BeTrue())
+		Expect(page).To(Equal(&wikiPage{slug: "guides/Getting-Started", title: "Getting Started", format: "markdown", content: "# Start\n---\n"}))
+	})
+
+	It("takes title and format from the file name without front matter and skips other files", func() {
+		page, ok, err := parseWikiPage("release-process.adoc", []byte("= Releases\n"))
+		Expect(err).To(BeNil())
+		Expect(ok).To(BeTrue())
+		Expect(page).To(Equal(&wikiPage{slug: "release-process", title: "release process", format: "asciidoc", content: "= Releases\n"}))
+
+		_, ok, err = parseWikiPage("images/logo.png", []byte{0x89})
+		Expect(err).To(BeNil())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("rejects unclosed front matter and unknown formats", func() {
+		_, _, err := parseWikiPage("home.md", []byte("---\ntitle: Home\n"))
+		Expect(err).To(MatchError("front matter of home.md is not closed with ---"))
+
+		_, _, err = parseWikiPage("home.md", []byte("---\nformat: textile\n---\n"))
+		Expect(err).To(MatchError("unknown format textile in home.md"))
+	})
+
+	It("keeps pages of wiki directories in their directory", func() {
+		Expect(wikiTitlePath("guides/Getting-Started", "Getting Started")).To(Equal("guides/Getting Started"))
+		Expect(wikiTitlePath("home", "home")).To(Equal("home"))
+	})
+
+	Describe("planWiki", func() {
+
+		local := []*wikiPage{
+			{slug: "guides/Setup", title: "Setup", format: "markdown", content: "new"},
+			{slug: "home", title: "home", format: "markdown", content: "same"},
+			{slug: "new-page", title: "new page", format: "rdoc", content: "= New"},
+		}
+		remote := []*gitlab.Wiki{
+			{Slug: "home", Title: "home", Format: "markdown", Content: "same"},
+			{Slug: "guides/Setup", Title: "Setup", Format: "asciidoc", Content: "old"},
+			{Slug: "old", Title: "old", Content: "gone"},
+		}
+
+		summary := func(changes []*change) [][]string {
+			var result [][]string
+			for _, c := range changes {
+				result = append(result, append([]string{c.action, c.resource}, c.details...))
+			}
+			return result
+		}
+
+		It("creates missing and updates changed pages and deletes remote pages with remove", func() {
+			Expect(summary(planWiki("group/project", local, remote, true))).To(Equal([][]string{
+				{"update", "wiki page guides/Setup of project group/project", "format: asciidoc => markdown", "content changed"},
+				{"create", "wiki page new-page of project group/project", "title: new page", "format: rdoc"},
+				{"delete", "wiki page old of project group/project"},
+			}))
+		})
+
+		It("returns no changes if the pages are in sync", func() {
+			Expect(planWiki("group/project", local[1:2], remote, false)).To(BeEmpty())
+		})
+	})
+})
@@ -21,8 +21,17 @@
 package cmd
 
 import (
-	"github.com/spf13/cobra"
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/michaellihs/golab/cmd/mapper"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var zshCompletionPath string
@@ -30,13 +39,19 @@ var zshCompletionPath string
 var zshCompletionCmd = &cobra.Command{
 	Use: "zsh-completion",
 	Short: "Generate ZSH completion file",
-	Long: `Generate ZSH completion file`,
+	Long: `Generate ZSH completion file
+
+The completion offers sub-commands, flags and the accepted values of flags with a fixed set of choices.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if zshCompletionPath == "" {
 			return errors.New("required parameter `-p` or `--path` not given - exiting")
 		}
-		err := RootCmd.GenZshCompletionFile(zshCompletionPath)
-		return err
+		file, err := os.Create(zshCompletionPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return genZshCompletion(file, RootCmd)
 	},
 }
 
@@ -47,4 +62,115 @@ func init() {
 func initZshCompletionCmd() {
 	zshCompletionCmd.PersistentFlags().StringVarP(&zshCompletionPath, "path", "p", "", "(required) Path into which to render ZSH completion")
 	RootCmd.AddCommand(zshCompletionCmd)
-}
\ No newline at end of file
+}
+
+// genZshCompletion writes a completion function for every command, see
+// http://zsh.sourceforge.net/Doc/Release/Completion-System.html#Completion-Functions for _arguments
+func genZshCompletion(w io.Writer, root *cobra.Command) error {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "#compdef %s\n", root.Name())
+	writeZshFunction(buf, root)
+	fmt.Fprintf(buf, "\n%s \"$@\"\n", zshFunctionName(root))
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func writeZshFunction(w io.Writer, cmd *cobra.Command) {
+	var subCommands []*cobra.Command
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() || sub.Name() == "help" {
+			subCommands = append(subCommands, sub)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%s() {\n", zshFunctionName(cmd))
+	fmt.Fprintln(w, "  local context state state_descr line")
+	fmt.Fprintln(w, "  typeset -A opt_args")
+	fmt.Fprintln(w, "  _arguments -C \\")
+	for _, flag := range zshFlags(cmd) {
+		fmt.Fprintf(w, "    %s \\\n", zshFlagSpec(flag))
+	}
+	if len(subCommands) == 0 {
+		fmt.Fprintln(w, "    '*: :_files'")
+		fmt.Fprintln(w, "}")
+		return
+	}
+
+	var names []string
+	for _, sub := range subCommands {
+		names = append(names, sub.Name())
+	}
+	fmt.Fprintf(w, "    '1: :(%s)' \\\n", strings.Join(names, " "))
+	fmt.Fprintln(w, "    '*:: :->args'")
+	fmt.Fprintln(w, "  case $state in")
+	fmt.Fprintln(w, "    args)")
+	fmt.Fprintln(w, "      case $words[1] in")
+	for _, sub := range subCommands {
+		fmt.Fprintf(w, "        %s) %s ;;\n", sub.Name(), zshFunctionName(sub))
+	}
+	fmt.Fprintln(w, "      esac")
+	fmt.Fprintln(w, "    ;;")
+	fmt.Fprintln(w, "  esac")
+	fmt.Fprintln(w, "}")
+
+	for _, sub := range subCommands {
+		writeZshFunction(w, sub)
+	}
+}
+
+func zshFunctionName(cmd *cobra.Command) string {
+	return "_" + strings.Replace(strings.Replace(cmd.CommandPath(), " ", "_", -1), "-", "_", -1)
+}
+
+// zshFlags returns the local and inherited flags of the command ordered by name
+func zshFlags(cmd *cobra.Command) []*pflag.Flag {
+	byName := map[string]*pflag.Flag{}
+	collect := func(flag *pflag.Flag) {
+		if !flag.Hidden {
+			byName[flag.Name] = flag
+		}
+	}
+	cmd.NonInheritedFlags().VisitAll(collect)
+	cmd.InheritedFlags().VisitAll(collect)
+	var names []string
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var flags []*pflag.Flag
+	for _, name := range names {
+		flags = append(flags, byName[name])
+	}
+	return flags
+}
+
+// zshFlagSpec returns the _arguments spec of the flag, e.g. '--sort[(optional) ...]:sort:(asc desc)'
+func zshFlagSpec(flag *pflag.Flag) string {
+	description := zshQuote(strings.NewReplacer("[", "\\[", "]", "\\]", "\n", " ").Replace(flag.Usage))
+	repeatable := strings.HasSuffix(flag.Value.Type(), "Array") || strings.HasSuffix(flag.Value.Type(), "Slice")
+	var spec string
+	switch {
+	case repeatable && flag.Shorthand != "":
+		// a repeatable flag must not exclude itself
+		spec = fmt.Sprintf("'*'{-%s,--%s}'[%s]", flag.Shorthand, flag.Name, description)
+	case repeatable:
+		spec = fmt.Sprintf("'*--%s[%s]", flag.Name, description)
+	case flag.Shorthand != "":
+		spec = fmt.Sprintf("'(-%s --%s)'{-%s,--%s}'[%s]", flag.Shorthand, flag.Name, flag.Shorthand, flag.Name, description)
+	default:
+		spec = fmt.Sprintf("'--%s[%s]", flag.Name, description)
+	}
+	if flag.Value.Type() != "bool" {
+		action := " "
+		if choices := flag.Annotations[mapper.ChoicesAnnotation]; len(choices) > 0 {
+			action = "(" + zshQuote(strings.Join(choices, " ")) + ")"
+		}
+		spec += ":" + flag.Name + ":" + action
+	}
+	return spec + "'"
+}
+
+// zshQuote escapes single quotes for a single quoted zsh string
+func zshQuote(s string) string {
+	return strings.Replace(s, "'", "'\\''", -1)
+}
@@ -0,0 +1,74 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+
+	"github.com/michaellihs/golab/cmd/mapper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+)
+
+var _ = Describe("zsh completion", func() {
+
+	It("completes sub-commands, flags and choices", func() {
+		type listFlags struct {
+			Sort *string `flag_name:"sort" short:"s" type:"string" choices:"asc,desc" required:"no" description:"sort [default: desc]"`
+		}
+		root := &cobra.Command{Use: "golab"}
+		root.PersistentFlags().Bool("debug", false, "(optional) log requests")
+		parent := &cobra.Command{Use: "merge-requests", Run: func(cmd *cobra.Command, args []string) {}}
+		list := &cobra.Command{Use: "ls", Run: func(cmd *cobra.Command, args []string) {}}
+		mapper.InitializedMapper(list, &listFlags{}, nil)
+		root.AddCommand(parent)
+		parent.AddCommand(list)
+
+		out := &bytes.Buffer{}
+		Expect(genZshCompletion(out, root)).To(Succeed())
+
+		Expect(out.String()).To(HavePrefix("#compdef golab\n"))
+		Expect(out.String()).To(ContainSubstring("    '1: :(merge-requests)' \\\n"))
+		Expect(out.String()).To(ContainSubstring("        merge-requests) _golab_merge_requests ;;\n"))
+		Expect(out.String()).To(ContainSubstring("_golab_merge_requests_ls() {"))
+		Expect(out.String()).To(ContainSubstring(`    '--debug[(optional) log requests]' \`))
+		Expect(out.String()).To(ContainSubstring(`    '(-s --sort)'{-s,--sort}'[(optional) sort \[default: desc\] (one of: asc, desc)]:sort:(asc desc)' \`))
+		Expect(out.String()).To(HaveSuffix("\n_golab \"$@\"\n"))
+	})
+
+	It("completes repeatable flags without excluding them", func() {
+		type createFlags struct {
+			Attach *[]string `flag_name:"attach" short:"a" type:"array" required:"no" description:"attach a file"`
+			Scopes *[]string `flag_name:"scopes" type:"array" required:"no" description:"scopes of the token"`
+		}
+		root := &cobra.Command{Use: "golab"}
+		create := &cobra.Command{Use: "create", Run: func(cmd *cobra.Command, args []string) {}}
+		mapper.InitializedMapper(create, &createFlags{}, nil)
+		root.AddCommand(create)
+
+		out := &bytes.Buffer{}
+		Expect(genZshCompletion(out, root)).To(Succeed())
+
+		Expect(out.String()).To(ContainSubstring(`    '*'{-a,--attach}'[(optional) attach a file]:attach: ' \`))
+		Expect(out.String()).To(ContainSubstring(`    '*--scopes[(optional) scopes of the token]:scopes: ' \`))
+	})
+})
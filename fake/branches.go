@@ -0,0 +1,169 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func (s *Server) registerBranchRoutes() {
+	s.handle("GET", "/projects/:project/repository/branches", s.withProject(s.listBranches))
+	s.handle("POST", "/projects/:project/repository/branches", s.withProject(s.createBranch))
+	s.handle("GET", "/projects/:project/repository/branches/:branch", s.withProject(s.getBranch))
+	s.handle("DELETE", "/projects/:project/repository/branches/:branch", s.withProject(s.deleteBranch))
+	s.handle("PUT", "/projects/:project/repository/branches/:branch/protect", s.withProject(s.protectBranch(true)))
+	s.handle("PUT", "/projects/:project/repository/branches/:branch/unprotect", s.withProject(s.protectBranch(false)))
+	s.handle("DELETE", "/projects/:project/repository/merged_branches", s.withProject(s.deleteMergedBranches))
+}
+
+// newBranch creates a branch pointing to a new commit with the given message
+func (s *Server) newBranch(project *gitlab.Project, name string, message string) *gitlab.Branch {
+	sha := fmt.Sprintf("%x", sha1.Sum([]byte(fmt.Sprintf("%d/%s/%d", project.ID, name, s.nextId()))))
+	owner := project.Owner
+	branch := &gitlab.Branch{
+		Name: name,
+		Commit: &gitlab.Commit{
+			ID:             sha,
+			ShortID:        sha[:8],
+			Title:          message,
+			Message:        message,
+			AuthorName:     owner.Name,
+			AuthorEmail:    owner.Email,
+			AuthoredDate:   now(),
+			CommitterName:  owner.Name,
+			CommitterEmail: owner.Email,
+			CommittedDate:  now(),
+			CreatedAt:      now(),
+			ParentIDs:      []string{},
+		},
+	}
+	s.commits[project.ID] = append(s.commits[project.ID], branch.Commit)
+	return branch
+}
+
+func findBranch(branches []*gitlab.Branch, name string) *gitlab.Branch {
+	for _, branch := range branches {
+		if branch.Name == name {
+			return branch
+		}
+	}
+	return nil
+}
+
+func removeBranch(branches []*gitlab.Branch, name string) []*gitlab.Branch {
+	var result []*gitlab.Branch
+	for _, branch := range branches {
+		if branch.Name != name {
+			result = append(result, branch)
+		}
+	}
+	return result
+}
+
+func (s *Server) listBranches(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	search := r.URL.Query().Get("search")
+	branches := []*gitlab.Branch{}
+	for _, branch := range s.branches[project.ID] {
+		if search == "" || strings.Contains(branch.Name, search) {
+			branches = append(branches, branch)
+		}
+	}
+	writePage(w, r, branches)
+}
+
+func (s *Server) getBranch(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	branch := findBranch(s.branches[project.ID], params["branch"])
+	if branch == nil {
+		writeError(w, http.StatusNotFound, "404 Branch Not Found")
+		return
+	}
+	writeJson(w, http.StatusOK, branch)
+}
+
+func (s *Server) createBranch(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	body := readBody(r)
+	if missing(w, body, "branch", "ref") {
+		return
+	}
+	name, _ := stringValue(body, "branch")
+	ref, _ := stringValue(body, "ref")
+	if findBranch(s.branches[project.ID], name) != nil {
+		writeError(w, http.StatusBadRequest, "Branch already exists")
+		return
+	}
+	var commit *gitlab.Commit
+	for _, branch := range s.branches[project.ID] {
+		if branch.Name == ref || branch.Commit.ID == ref || branch.Commit.ShortID == ref {
+			commit = branch.Commit
+		}
+	}
+	if commit == nil {
+		writeError(w, http.StatusBadRequest, "Invalid reference name")
+		return
+	}
+	branch := &gitlab.Branch{Name: name, Commit: commit}
+	s.branches[project.ID] = append(s.branches[project.ID], branch)
+	writeJson(w, http.StatusCreated, branch)
+}
+
+func (s *Server) deleteBranch(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	branch := findBranch(s.branches[project.ID], params["branch"])
+	if branch == nil {
+		writeError(w, http.StatusNotFound, "404 Branch Not Found")
+		return
+	}
+	if branch.Protected {
+		writeError(w, http.StatusMethodNotAllowed, "Protected branch cant be removed")
+		return
+	}
+	s.branches[project.ID] = removeBranch(s.branches[project.ID], branch.Name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) protectBranch(protected bool) projectHandler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+		branch := findBranch(s.branches[project.ID], params["branch"])
+		if branch == nil {
+			writeError(w, http.StatusNotFound, "404 Branch Not Found")
+			return
+		}
+		body := readBody(r)
+		branch.Protected = protected
+		branch.DevelopersCanPush, _ = boolValue(body, "developers_can_push")
+		branch.DevelopersCanMerge, _ = boolValue(body, "developers_can_merge")
+		writeJson(w, http.StatusOK, branch)
+	}
+}
+
+// deleteMergedBranches removes all merged branches that are neither protected nor the default branch
+func (s *Server) deleteMergedBranches(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	for _, branch := range s.branches[project.ID] {
+		if branch.Merged && !branch.Protected && branch.Name != project.DefaultBranch {
+			s.branches[project.ID] = removeBranch(s.branches[project.ID], branch.Name)
+		}
+	}
+	writeJson(w, http.StatusAccepted, map[string]string{"message": "202 Accepted"})
+}
@@ -0,0 +1,306 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func (s *Server) registerCommitRoutes() {
+	s.handle("GET", "/projects/:project/repository/commits", s.withProject(s.listCommits))
+	s.handle("GET", "/projects/:project/repository/commits/:sha", s.withCommit(s.getCommit))
+	s.handle("GET", "/projects/:project/repository/commits/:sha/diff", s.withCommit(s.getCommitDiff))
+	s.handle("GET", "/projects/:project/repository/commits/:sha/comments", s.withCommit(s.listCommitComments))
+	s.handle("POST", "/projects/:project/repository/commits/:sha/comments", s.withCommit(s.addCommitComment))
+	s.handle("GET", "/projects/:project/repository/commits/:sha/statuses", s.withCommit(s.listCommitStatuses))
+	s.handle("POST", "/projects/:project/repository/commits/:sha/cherry_pick", s.withCommit(s.cherryPickCommit))
+}
+
+// AddCommitStatus adds a status, e.g. of a CI job, to the commit with the given ID
+func (s *Server) AddCommitStatus(sha string, ref string, name string, status string) *gitlab.CommitStatus {
+	commitStatus := &gitlab.CommitStatus{ID: s.nextId(), SHA: sha, Ref: ref, Name: name, Status: status, CreatedAt: now()}
+	s.commitStatuses[sha] = append(s.commitStatuses[sha], commitStatus)
+	return commitStatus
+}
+
+// findCommit returns the commit of a branch, a tag or the commit with the given (short) ID,
+// ref defaults to the default branch
+func (s *Server) findCommit(project *gitlab.Project, ref string) *gitlab.Commit {
+	if ref == "" {
+		ref = project.DefaultBranch
+	}
+	if branch := findBranch(s.branches[project.ID], ref); branch != nil {
+		return branch.Commit
+	}
+	if tag := findTag(s.tags[project.ID], ref); tag != nil {
+		return tag.Commit
+	}
+	for _, commit := range s.commits[project.ID] {
+		if commit.ID == ref || commit.ShortID == ref {
+			return commit
+		}
+	}
+	return nil
+}
+
+type commitHandler func(w http.ResponseWriter, r *http.Request, project *gitlab.Project, commit *gitlab.Commit)
+
+func (s *Server) withCommit(h commitHandler) handler {
+	return s.withProject(func(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+		commit := s.findCommit(project, params["sha"])
+		if commit == nil {
+			writeError(w, http.StatusNotFound, "404 Commit Not Found")
+			return
+		}
+		h(w, r, project, commit)
+	})
+}
+
+// parentFiles returns the files of the first parent of a commit, which are empty for the initial commit
+func (s *Server) parentFiles(project *gitlab.Project, commit *gitlab.Commit) map[string][]byte {
+	if len(commit.ParentIDs) == 0 {
+		return map[string][]byte{}
+	}
+	return s.files[s.findCommit(project, commit.ParentIDs[0]).ID]
+}
+
+// changedPaths returns the sorted paths of all files that differ between two sets of files
+func changedPaths(before map[string][]byte, after map[string][]byte) []string {
+	var paths []string
+	for p, content := range after {
+		if old, ok := before[p]; !ok || string(old) != string(content) {
+			paths = append(paths, p)
+		}
+	}
+	for p := range before {
+		if _, ok := after[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// parseTime accepts the ISO 8601 dates and times Gitlab accepts for since and until
+func parseTime(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// listCommits lists the commits of a branch, latest first, optionally only those that changed a path or
+// were committed in a time range
+func (s *Server) listCommits(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	query := r.URL.Query()
+	commit := s.findCommit(project, query.Get("ref_name"))
+	if commit == nil {
+		writeError(w, http.StatusNotFound, "404 Reference Not Found")
+		return
+	}
+	since, hasSince := parseTime(query.Get("since"))
+	until, hasUntil := parseTime(query.Get("until"))
+	filePath := strings.Trim(query.Get("path"), "/")
+	commits := []*gitlab.Commit{}
+	for ; commit != nil; commit = s.parentCommit(project, commit) {
+		if hasSince && commit.CommittedDate.Before(since) || hasUntil && commit.CommittedDate.After(until) {
+			continue
+		}
+		if filePath != "" && !touches(changedPaths(s.parentFiles(project, commit), s.files[commit.ID]), filePath) {
+			continue
+		}
+		commits = append(commits, commit)
+	}
+	writePage(w, r, commits)
+}
+
+func (s *Server) parentCommit(project *gitlab.Project, commit *gitlab.Commit) *gitlab.Commit {
+	if len(commit.ParentIDs) == 0 {
+		return nil
+	}
+	return s.findCommit(project, commit.ParentIDs[0])
+}
+
+// touches tells whether one of the paths is the file or below the directory filePath
+func touches(paths []string, filePath string) bool {
+	for _, p := range paths {
+		if p == filePath || strings.HasPrefix(p, filePath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// commitDiffs returns the diffs of a commit, each changed file is reported as a single hunk replacing all its lines
+func (s *Server) commitDiffs(project *gitlab.Project, commit *gitlab.Commit) ([]*gitlab.Diff, *gitlab.CommitStats) {
+	before, after := s.parentFiles(project, commit), s.files[commit.ID]
+	diffs := []*gitlab.Diff{}
+	stats := &gitlab.CommitStats{}
+	for _, p := range changedPaths(before, after) {
+		old, existed := before[p]
+		content, exists := after[p]
+		diff, additions, deletions := fileDiff(old, content)
+		d := &gitlab.Diff{Diff: diff, OldPath: p, NewPath: p, AMode: "100644", BMode: "100644", NewFile: !existed, DeletedFile: !exists}
+		if !existed {
+			d.AMode = "0"
+		}
+		if !exists {
+			d.BMode = "0"
+		}
+		diffs = append(diffs, d)
+		stats.Additions += additions
+		stats.Deletions += deletions
+	}
+	stats.Total = stats.Additions + stats.Deletions
+	return diffs, stats
+}
+
+// fileDiff returns a unified diff hunk that replaces all lines of old with all lines of new
+func fileDiff(old []byte, new []byte) (string, int, int) {
+	removed, deletions := diffLines("-", old)
+	added, additions := diffLines("+", new)
+	return fmt.Sprintf("@@ -%s +%s @@\n%s%s", hunkRange(deletions), hunkRange(additions), removed, added), additions, deletions
+}
+
+func hunkRange(lines int) string {
+	if lines == 0 {
+		return "0,0"
+	}
+	return fmt.Sprintf("1,%d", lines)
+}
+
+// diffLines prefixes all lines of content, a missing newline at the end is marked as git does
+func diffLines(prefix string, content []byte) (string, int) {
+	if len(content) == 0 {
+		return "", 0
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	result := ""
+	for _, line := range lines {
+		result += prefix + line
+		if !strings.HasSuffix(line, "\n") {
+			result += "\n\\ No newline at end of file\n"
+		}
+	}
+	return result, len(lines)
+}
+
+func (s *Server) getCommit(w http.ResponseWriter, r *http.Request, project *gitlab.Project, commit *gitlab.Commit) {
+	withStats := *commit
+	_, withStats.Stats = s.commitDiffs(project, commit)
+	writeJson(w, http.StatusOK, &withStats)
+}
+
+func (s *Server) getCommitDiff(w http.ResponseWriter, r *http.Request, project *gitlab.Project, commit *gitlab.Commit) {
+	diffs, _ := s.commitDiffs(project, commit)
+	writePage(w, r, diffs)
+}
+
+func (s *Server) listCommitComments(w http.ResponseWriter, r *http.Request, project *gitlab.Project, commit *gitlab.Commit) {
+	comments := s.commitComments[commit.ID]
+	if comments == nil {
+		comments = []*gitlab.CommitComment{}
+	}
+	writePage(w, r, comments)
+}
+
+// addCommitComment adds a comment to the commit, path, line and line_type anchor it to a line of the diff
+func (s *Server) addCommitComment(w http.ResponseWriter, r *http.Request, project *gitlab.Project, commit *gitlab.Commit) {
+	body := readBody(r)
+	if missing(w, body, "note") {
+		return
+	}
+	user := s.currentUser(r)
+	comment := &gitlab.CommitComment{Author: gitlab.Author{ID: user.ID, Username: user.Username, Email: user.Email, Name: user.Name, State: user.State}}
+	comment.Note, _ = stringValue(body, "note")
+	path, hasPath := stringValue(body, "path")
+	line, hasLine := intValue(body, "line")
+	lineType, hasLineType := stringValue(body, "line_type")
+	if hasPath || hasLine || hasLineType {
+		if !(hasPath && hasLine && hasLineType) {
+			writeError(w, http.StatusBadRequest, "path, line, line_type are missing, at least one parameter must be provided")
+			return
+		}
+		if lineType != "new" && lineType != "old" {
+			writeError(w, http.StatusBadRequest, "line_type does not have a valid value")
+			return
+		}
+		comment.Path, comment.Line, comment.LineType = path, line, lineType
+	}
+	s.commitComments[commit.ID] = append(s.commitComments[commit.ID], comment)
+	writeJson(w, http.StatusCreated, comment)
+}
+
+func (s *Server) listCommitStatuses(w http.ResponseWriter, r *http.Request, project *gitlab.Project, commit *gitlab.Commit) {
+	query := r.URL.Query()
+	statuses := []*gitlab.CommitStatus{}
+	for _, status := range s.commitStatuses[commit.ID] {
+		if (query.Get("ref") == "" || status.Ref == query.Get("ref")) && (query.Get("name") == "" || status.Name == query.Get("name")) {
+			statuses = append(statuses, status)
+		}
+	}
+	writePage(w, r, statuses)
+}
+
+// cherryPickCommit applies the changes of a commit to a branch, it fails if a changed file was also changed on the branch
+func (s *Server) cherryPickCommit(w http.ResponseWriter, r *http.Request, project *gitlab.Project, commit *gitlab.Commit) {
+	body := readBody(r)
+	if missing(w, body, "branch") {
+		return
+	}
+	name, _ := stringValue(body, "branch")
+	branch := findBranch(s.branches[project.ID], name)
+	if branch == nil {
+		writeError(w, http.StatusNotFound, "404 Branch Not Found")
+		return
+	}
+	before, after := s.parentFiles(project, commit), s.files[commit.ID]
+	author := &gitlab.User{Name: commit.AuthorName, Email: commit.AuthorEmail}
+	picked, msg := s.commit(project, branch, commit.Message, author, func(files map[string][]byte) string {
+		for _, p := range changedPaths(before, after) {
+			if string(files[p]) != string(before[p]) {
+				return "Sorry, we cannot cherry-pick this commit automatically. This commit may already have been cherry-picked, or a more recent commit may have updated some of its content."
+			}
+			if content, ok := after[p]; ok {
+				files[p] = content
+			} else {
+				delete(files, p)
+			}
+		}
+		return ""
+	})
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	writeJson(w, http.StatusCreated, picked)
+}
@@ -0,0 +1,726 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+// these specs run the golab binary against the fake server, see http://lucapette.me/writing-integration-tests-for-a-go-cli-application
+var _ = Describe("golab against the fake server", func() {
+
+	var (
+		server  *Server
+		tempDir string
+		config  string
+	)
+
+	golab := func(args ...string) (string, error) {
+		cmd := exec.Command(golabBinary, append([]string{"--config", config}, args...)...)
+		cmd.Dir = tempDir
+		out, err := cmd.Output()
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return string(out), fmt.Errorf("%s: %s", err, exitErr.Stderr)
+		}
+		return string(out), err
+	}
+
+	BeforeEach(func() {
+		server = NewServer()
+		var err error
+		tempDir, err = ioutil.TempDir("", "golab-fake")
+		Expect(err).To(BeNil())
+		config = filepath.Join(tempDir, "golab.yml")
+		Expect(ioutil.WriteFile(config, []byte(fmt.Sprintf("---\nurl: \"%s\"\ntoken: \"%s\"\nmax_retry_wait: \"10ms\"\n", server.URL, Token)), 0600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(tempDir)
+	})
+
+	// golabWithoutConfig runs golab with the user config dir in tempDir instead of an explicit --config
+	golabWithoutConfig := func(env []string, args ...string) (string, error) {
+		cmd := exec.Command(golabBinary, args...)
+		cmd.Dir = tempDir
+		cmd.Env = append(os.Environ(), append([]string{"HOME=" + tempDir, "XDG_CONFIG_HOME=" + tempDir}, env...)...)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	// golabWithStdin runs golab with the given input on stdin, stdout and stderr are combined
+	golabWithStdin := func(stdin []byte, args ...string) (string, error) {
+		cmd := exec.Command(golabBinary, append([]string{"--config", config}, args...)...)
+		cmd.Dir = tempDir
+		cmd.Stdin = bytes.NewReader(stdin)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	It("logs in and writes the token to the user config dir", func() {
+		out, err := golabWithoutConfig(nil, "login", "--host", server.URL, "--user", "root", "--password", RootPassword)
+		Expect(err).To(BeNil(), out)
+
+		written, err := ioutil.ReadFile(filepath.Join(tempDir, "golab", "config.yml"))
+		Expect(err).To(BeNil())
+		Expect(string(written)).To(ContainSubstring(server.URL))
+		Expect(string(written)).To(ContainSubstring("token-"))
+		_, err = os.Stat(filepath.Join(tempDir, ".golab.yml"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+
+		out, err = golabWithoutConfig(nil, "user", "get", "-u", "root")
+		Expect(err).To(BeNil(), out)
+
+		out, err = golabWithoutConfig(nil, "logout")
+		Expect(err).To(BeNil(), out)
+		written, _ = ioutil.ReadFile(filepath.Join(tempDir, "golab", "config.yml"))
+		Expect(string(written)).NotTo(ContainSubstring("token"))
+	})
+
+	It("logs in with an encrypted token file and logs out", func() {
+		passphrase := []string{"GOLAB_TOKEN_PASSPHRASE=secret"}
+		out, err := golabWithoutConfig(passphrase, "login", "--encrypt", "--host", server.URL, "--user", "root", "--password", RootPassword)
+		Expect(err).To(BeNil(), out)
+
+		tokenFile := filepath.Join(tempDir, "golab", "token.asc")
+		encrypted, err := ioutil.ReadFile(tokenFile)
+		Expect(err).To(BeNil())
+		Expect(string(encrypted)).To(HavePrefix("-----BEGIN PGP MESSAGE-----"))
+		Expect(string(encrypted)).NotTo(ContainSubstring("token-"))
+
+		out, err = golabWithoutConfig(passphrase, "user", "get", "-u", "root")
+		Expect(err).To(BeNil(), out)
+		out, err = golabWithoutConfig([]string{"GOLAB_TOKEN_PASSPHRASE=wrong"}, "user", "get", "-u", "root")
+		Expect(err).NotTo(BeNil())
+		Expect(out).To(ContainSubstring("wrong passphrase"))
+
+		out, err = golabWithoutConfig(nil, "logout")
+		Expect(err).To(BeNil(), out)
+		_, err = os.Stat(tokenFile)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("gets the token from a token_command", func() {
+		Expect(ioutil.WriteFile(config, []byte(fmt.Sprintf("---\nurl: \"%s\"\ntoken_command: \"echo %s\"\n", server.URL, Token)), 0600)).To(Succeed())
+
+		out, err := golab("user", "get", "-u", "root")
+		Expect(err).To(BeNil())
+		Expect(out).To(ContainSubstring("admin@example.com"))
+	})
+
+	It("logs in with a personal access token", func() {
+		out, err := golabWithoutConfig(nil, "login", "--host", server.URL, "--token", Token)
+		Expect(err).To(BeNil(), out)
+		Expect(out).To(ContainSubstring("logged in to " + server.URL + " as root (Administrator), Gitlab version " + Version))
+
+		written, err := ioutil.ReadFile(filepath.Join(tempDir, "golab", "config.yml"))
+		Expect(err).To(BeNil())
+		Expect(string(written)).To(ContainSubstring(Token))
+
+		out, err = golabWithoutConfig(nil, "login", "--host", server.URL, "--token", "invalid")
+		Expect(err).NotTo(BeNil())
+		Expect(out).To(ContainSubstring("could not get the user of the token"))
+	})
+
+	It("logs in with OAuth2 and refreshes expired tokens", func() {
+		out, err := golabWithoutConfig(nil, "login", "--oauth", "--host", server.URL, "--user", "root", "--password", RootPassword)
+		Expect(err).To(BeNil(), out)
+		Expect(out).To(ContainSubstring("as root (Administrator)"))
+
+		configFile := filepath.Join(tempDir, "golab", "config.yml")
+		written, err := ioutil.ReadFile(configFile)
+		Expect(err).To(BeNil())
+		Expect(string(written)).To(ContainSubstring("access_token: oauth-"))
+
+		expired := regexp.MustCompile(`expires_at: "?[^\n"]*"?`).ReplaceAllString(string(written), `expires_at: "2017-01-01T00:00:00Z"`)
+		Expect(ioutil.WriteFile(configFile, []byte(expired), 0600)).To(Succeed())
+		out, err = golabWithoutConfig(nil, "user", "get", "-u", "root")
+		Expect(err).To(BeNil(), out)
+
+		refreshed, err := ioutil.ReadFile(configFile)
+		Expect(err).To(BeNil())
+		Expect(string(refreshed)).NotTo(ContainSubstring("2017-01-01"))
+		Expect(string(refreshed)).NotTo(Equal(string(written)))
+
+		out, err = golabWithoutConfig(nil, "login", "--oauth", "--host", server.URL, "--user", "root", "--password", "wrong")
+		Expect(err).NotTo(BeNil())
+		Expect(out).To(ContainSubstring("The provided authorization grant is invalid"))
+	})
+
+	It("reports invalid flag values without a stack trace", func() {
+		out, err := golab("group", "create", "-n", "Platform", "-p", "platform", "--visibility", "confidential")
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("invalid value 'confidential' for --visibility: expected one of private, internal, public"))
+		Expect(err.Error()).NotTo(ContainSubstring("panic"))
+		Expect(out).To(BeEmpty())
+		Expect(server.Groups()).To(BeEmpty())
+	})
+
+	It("creates, gets and deletes a user", func() {
+		_, err := golab("user", "create", "-u", "jdoe", "-e", "jdoe@example.com", "-n", "John Doe", "-p", "12341234")
+		Expect(err).To(BeNil())
+
+		out, err := golab("user", "get", "-u", "jdoe")
+		Expect(err).To(BeNil())
+		user := &gitlab.User{}
+		Expect(json.Unmarshal([]byte(out), user)).To(Succeed())
+		Expect(user.Email).To(Equal("jdoe@example.com"))
+
+		_, err = golab("user", "delete", "-i", strconv.Itoa(user.ID))
+		Expect(err).To(BeNil())
+		Expect(server.Users()).To(HaveLen(1))
+	})
+
+	It("pages through lists", func() {
+		for i := 1; i <= 5; i++ {
+			server.AddUser(fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@example.com", i), "User", "")
+		}
+
+		out, err := golab("user", "ls", "--per-page", "2", "--page", "2", "-o", "table=username")
+		Expect(err).To(BeNil())
+		Expect(strings.Fields(out)).To(Equal([]string{"USERNAME", "user2", "user3"}))
+
+		out, err = golab("user", "ls", "--all", "--per-page", "2", "-o", "ids")
+		Expect(err).To(BeNil())
+		Expect(strings.Fields(out)).To(HaveLen(6))
+	})
+
+	It("manages groups and group members", func() {
+		out, err := golab("group", "create", "-n", "My Group", "-p", "my-group")
+		Expect(err).To(BeNil())
+		group := &gitlab.Group{}
+		Expect(json.Unmarshal([]byte(out), group)).To(Succeed())
+		user := server.AddUser("jdoe", "jdoe@example.com", "John Doe", "")
+
+		_, err = golab("group-members", "add", "-i", strconv.Itoa(group.ID), "-u", strconv.Itoa(user.ID), "-a", "30")
+		Expect(err).To(BeNil())
+
+		out, err = golab("group-members", "ls", "-i", strconv.Itoa(group.ID), "-o", "table=username,access_level")
+		Expect(err).To(BeNil())
+		Expect(out).To(MatchRegexp(`jdoe\s+30`))
+	})
+
+	It("manages project members and syncs them from groups and projects", func() {
+		root := server.Users()[0]
+		group := server.AddGroup("Group", "group", nil)
+		source := server.AddProject("source", "source", group, root)
+		target := server.AddProject("target", "target", group, root)
+		alice := server.AddUser("alice", "alice@example.com", "Alice", "")
+		bob := server.AddUser("bob", "bob@example.com", "Bob", "")
+		carol := server.AddUser("carol", "carol@example.com", "Carol", "")
+
+		_, err := golab("group-members", "add", "-i", strconv.Itoa(group.ID), "-u", strconv.Itoa(alice.ID), "-a", "50", "-e", "2030-12-31")
+		Expect(err).To(BeNil())
+		_, err = golab("project-members", "add", "-i", "group/source", "-u", strconv.Itoa(bob.ID), "-a", "20")
+		Expect(err).To(BeNil())
+		_, err = golab("project-members", "add", "-i", "group/target", "-u", strconv.Itoa(carol.ID), "-a", "30")
+		Expect(err).To(BeNil())
+
+		out, err := golab("project-members", "sync", "--source_group", "group", "--target", "group/target", "-o", "table=action,username,access_level,expires_at")
+		Expect(err).To(BeNil())
+		Expect(strings.Split(strings.TrimSpace(out), "\n")).To(HaveLen(2))
+		Expect(out).To(MatchRegexp(`add\s+alice\s+40\s+2030-12-31`))
+
+		out, err = golab("project-members", "sync", "--source", strconv.Itoa(source.ID), "--target", strconv.Itoa(target.ID), "--remove", "-o", "table=action,username")
+		Expect(err).To(BeNil())
+		Expect(out).To(MatchRegexp(`add\s+bob`))
+		Expect(out).To(MatchRegexp(`remove\s+alice`))
+		Expect(out).To(MatchRegexp(`remove\s+carol`))
+		out, err = golab("project-members", "ls", "-i", "group/target", "-o", "ids")
+		Expect(err).To(BeNil())
+		Expect(strings.Fields(out)).To(Equal([]string{strconv.Itoa(root.ID), strconv.Itoa(bob.ID)}))
+
+		_, err = golab("project-members", "edit", "-i", "group/target", "-u", strconv.Itoa(bob.ID), "-a", "30", "-e", "2031-01-31")
+		Expect(err).To(BeNil())
+		out, err = golab("project-members", "get", "-i", "group/target", "-u", strconv.Itoa(bob.ID), "-o", "table=access_level,expires_at")
+		Expect(err).To(BeNil())
+		Expect(out).To(MatchRegexp(`30\s+2031-01-31`))
+
+		_, err = golab("project-members", "delete", "-i", "group/target", "-u", strconv.Itoa(bob.ID))
+		Expect(err).To(BeNil())
+		_, err = golab("project-members", "sync", "--target", "group/target")
+		Expect(err).To(MatchError(ContainSubstring("either `--source` or `--source_group` has to be given")))
+	})
+
+	It("syncs group members by path and reports the changes", func() {
+		source := server.AddGroup("Source", "source", nil)
+		target := server.AddGroup("Target", "target", nil)
+		for i, username := range []string{"alice", "bob", "carol"} {
+			user := server.AddUser(username, username+"@example.com", username, "")
+			group := source
+			if i == 2 {
+				group = target
+			}
+			_, err := golab("group-members", "add", "-i", strconv.Itoa(group.ID), "-u", strconv.Itoa(user.ID), "-a", "30")
+			Expect(err).To(BeNil())
+		}
+		bob := server.Users()[2]
+		_, err := golab("group-members", "add", "-i", strconv.Itoa(target.ID), "-u", strconv.Itoa(bob.ID), "-a", "20", "-e", "2030-12-31")
+		Expect(err).To(BeNil())
+		_, err = golab("group-members", "edit", "-i", strconv.Itoa(source.ID), "-u", strconv.Itoa(bob.ID), "-a", "40")
+		Expect(err).To(BeNil())
+
+		out, err := golab("--dry-run", "group-members", "sync", "-s", "source", "-t", "target", "--update-access-levels", "--remove", "-o", "table=action,username,access_level,previous_access_level,previous_expires_at")
+		Expect(err).To(BeNil())
+		Expect(out).To(MatchRegexp(`add\s+alice\s+30`))
+		Expect(out).To(MatchRegexp(`update\s+bob\s+40\s+20\s+2030-12-31`))
+		Expect(out).To(MatchRegexp(`remove\s+carol\s+30`))
+		listTarget := func() string {
+			out, err := golab("group-members", "ls", "-i", strconv.Itoa(target.ID), "-o", "table=username,access_level")
+			Expect(err).To(BeNil())
+			return out
+		}
+		Expect(listTarget()).NotTo(ContainSubstring("alice"))
+
+		// a failed change is reported together with the changes made before and the skipped ones
+		server.Fail("PUT", fmt.Sprintf("/groups/target/members/%d", bob.ID), http.StatusForbidden, 1)
+		out, err = golab("group-members", "sync", "-s", "source", "-t", "target", "--update-access-levels", "--remove", "-o", "table=action,username,status")
+		Expect(err).To(MatchError(ContainSubstring("could not update member bob of group target")))
+		Expect(out).To(MatchRegexp(`add\s+alice\s+applied`))
+		Expect(out).To(MatchRegexp(`update\s+bob\s+failed`))
+		Expect(out).To(MatchRegexp(`remove\s+carol\s+skipped`))
+
+		_, err = golab("group-members", "sync", "-s", "source", "-t", "target", "--update-access-levels", "--remove")
+		Expect(err).To(BeNil())
+		members := listTarget()
+		Expect(members).To(MatchRegexp(`alice\s+30`))
+		Expect(members).To(MatchRegexp(`bob\s+40`))
+		Expect(members).NotTo(ContainSubstring("carol"))
+
+		out, err = golab("group-members", "sync", "-s", "source", "-t", "target", "--update-access-levels", "--remove")
+		Expect(err).To(BeNil())
+		Expect(strings.TrimSpace(out)).To(Equal("[]"))
+	})
+
+	It("creates projects and branches", func() {
+		group := server.AddGroup("Group", "group", nil)
+		_, err := golab("project", "create", "-n", "project", "--namespace_id", strconv.Itoa(group.ID))
+		Expect(err).To(BeNil())
+		_, err = golab("files", "create", "-i", "group/project", "-f", "README.md", "-b", "master", "-c", "# Project", "-m", "Initial commit")
+		Expect(err).To(BeNil())
+
+		_, err = golab("branches", "create", "-i", "group/project", "-b", "feature", "-r", "master")
+		Expect(err).To(BeNil())
+
+		out, err := golab("branches", "list", "-i", "group/project", "-o", "ids")
+		Expect(err).To(BeNil())
+		Expect(strings.Fields(out)).To(Equal([]string{"master", "feature"}))
+	})
+
+	It("takes flag values from the environment and the defaults of the config file", func() {
+		group := server.AddGroup("Group", "group", nil)
+		_, err := golab("project", "create", "-n", "project", "--namespace_id", strconv.Itoa(group.ID))
+		Expect(err).To(BeNil())
+		_, err = golab("files", "create", "-i", "group/project", "-f", "README.md", "-b", "master", "-c", "# Project", "-m", "Initial commit")
+		Expect(err).To(BeNil())
+		_, err = golab("branches", "create", "-i", "group/project", "-b", "feature", "-r", "master")
+		Expect(err).To(BeNil())
+		f, err := os.OpenFile(config, os.O_APPEND|os.O_WRONLY, 0600)
+		Expect(err).To(BeNil())
+		_, err = f.WriteString("defaults:\n  merge-requests:\n    create:\n      target_branch: master\n      title: Default title\n")
+		Expect(err).To(BeNil())
+		f.Close()
+		os.Setenv("GOLAB_PROJECT", "group/project")
+		defer os.Unsetenv("GOLAB_PROJECT")
+
+		out, err := golab("merge-requests", "create", "-s", "feature")
+		Expect(err).To(BeNil())
+		mergeRequest := &gitlab.MergeRequest{}
+		Expect(json.Unmarshal([]byte(out), mergeRequest)).To(Succeed())
+		Expect(mergeRequest.TargetBranch).To(Equal("master"))
+		Expect(mergeRequest.Title).To(Equal("Default title"))
+
+		out, err = golab("merge-requests", "create", "-s", "master", "-t", "feature", "-n", "Explicit title")
+		Expect(err).To(BeNil())
+		Expect(json.Unmarshal([]byte(out), mergeRequest)).To(Succeed())
+		Expect(mergeRequest.TargetBranch).To(Equal("feature"))
+		Expect(mergeRequest.Title).To(Equal("Explicit title"))
+
+		_, err = golab("project", "hooks", "add", "--url", "https://ci.example.com/hook")
+		Expect(err).To(BeNil())
+		out, err = golab("project", "hooks", "ls", "-o", "ids")
+		Expect(err).To(BeNil())
+		Expect(out).To(MatchRegexp(`^\d+\n$`))
+	})
+
+	It("reads flag values and uploads from files and stdin", func() {
+		group := server.AddGroup("Group", "group", nil)
+		project := server.AddProject("project", "project", group, server.Users()[0])
+		Expect(ioutil.WriteFile(filepath.Join(tempDir, "description.md"), []byte("# Project\n\nwith `quotes`\n"), 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(tempDir, "hook.txt"), []byte("https://ci.example.com/hook?secret=abc\n"), 0600)).To(Succeed())
+
+		_, err := golab("project", "edit", "-i", "group/project", "-n", "project", "--description", "@description.md")
+		Expect(err).To(BeNil())
+		Expect(project.Description).To(Equal("# Project\n\nwith `quotes`\n"))
+
+		out, err := golab("project", "hooks", "add", "-i", "group/project", "--url", "@hook.txt")
+		Expect(err).To(BeNil())
+		hook := &gitlab.ProjectHook{}
+		Expect(json.Unmarshal([]byte(out), hook)).To(Succeed())
+		Expect(hook.URL).To(Equal("https://ci.example.com/hook?secret=abc"))
+
+		binary := []byte{0x00, 0xff, 0x0d, 0x0a, 0x1b, 0x0a}
+		out, err = golabWithStdin(binary, "project", "upload-file", "-i", "group/project", "-f", "-", "--name", "data.bin")
+		Expect(err).To(BeNil(), out)
+		projectFile := &gitlab.ProjectFile{}
+		Expect(json.Unmarshal([]byte(out), projectFile)).To(Succeed())
+		Expect(projectFile.Alt).To(Equal("data.bin"))
+		Expect(server.Upload(project, projectFile.URL)).To(Equal(binary))
+
+		png := append([]byte("\x89PNG\r\n\x1a\n"), binary...)
+		out, err = golabWithStdin(png, "project", "edit", "-i", "group/project", "-n", "project", "--avatar", "-")
+		Expect(err).To(BeNil(), out)
+		Expect(project.AvatarURL).To(HaveSuffix("/avatar.png"))
+		Expect(server.Upload(project, project.AvatarURL)).To(Equal(png))
+	})
+
+	It("manages repository files and pushes a local directory in one commit", func() {
+		group := server.AddGroup("Group", "group", nil)
+		project := server.AddProject("project", "project", group, server.Users()[0])
+		server.AddFile(project, "master", "templates/keep.txt", []byte("unchanged\n"))
+		server.AddFile(project, "master", "templates/old.txt", []byte("old\n"))
+		server.AddFile(project, "master", "README.md", []byte("# Project\n"))
+
+		_, err := golab("files", "create", "-i", "group/project", "-f", "docs/a b.md", "-b", "master", "-c", "first", "-m", "Add docs")
+		Expect(err).To(BeNil())
+		_, err = golab("files", "update", "-i", "group/project", "-f", "docs/a b.md", "-b", "master", "-c", "second", "-m", "Update docs")
+		Expect(err).To(BeNil())
+		out, err := golab("files", "raw", "-i", "group/project", "-f", "docs/a b.md", "-r", "master")
+		Expect(err).To(BeNil())
+		Expect(out).To(Equal("second"))
+		_, err = golab("files", "delete", "-i", "group/project", "-f", "docs/a b.md", "-b", "master", "-m", "Remove docs")
+		Expect(err).To(BeNil())
+		_, err = golab("files", "get", "-i", "group/project", "-f", "docs/a b.md", "-r", "master")
+		Expect(err).To(MatchError(ContainSubstring("404")))
+
+		dir := filepath.Join(tempDir, "templates")
+		Expect(os.MkdirAll(filepath.Join(dir, "sub"), 0700)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(dir, ".git"), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "keep.txt"), []byte("unchanged\n"), 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "sub", "new.bin"), []byte{0x00, 0xff}, 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref\n"), 0600)).To(Succeed())
+		head := server.branches[project.ID][0].Commit.ID
+
+		out, err = golab("files", "push", "-i", "group/project", "--dir", dir, "--path", "templates", "--branch", "master", "--message", "Push templates", "--delete")
+		Expect(err).To(BeNil(), out)
+		commit := &gitlab.Commit{}
+		Expect(json.Unmarshal([]byte(out), commit)).To(Succeed())
+		Expect(commit.Title).To(Equal("Push templates"))
+		Expect(commit.ParentIDs).To(Equal([]string{head}))
+		Expect(server.Files(project, "master")).To(Equal([]string{"README.md", "templates/keep.txt", "templates/sub/new.bin"}))
+		content, _ := server.File(project, "master", "templates/sub/new.bin")
+		Expect(content).To(Equal([]byte{0x00, 0xff}))
+
+		out, err = golab("files", "push", "-i", "group/project", "--dir", dir, "--path", "templates", "--branch", "master", "--message", "Push templates")
+		Expect(err).To(BeNil())
+		Expect(out).To(BeEmpty())
+		Expect(server.branches[project.ID][0].Commit.ID).To(Equal(commit.ID))
+	})
+
+	It("lists and shows commits, renders their diff as patch, comments on and cherry-picks them", func() {
+		group := server.AddGroup("Group", "group", nil)
+		project := server.AddProject("project", "project", group, server.Users()[0])
+		server.AddFile(project, "master", "docs/readme.md", []byte("line 1\nline 2\n"))
+		server.AddFile(project, "master", "main.go", []byte("package main"))
+		_, err := golab("branches", "create", "-i", "group/project", "-b", "stable", "-r", "master")
+		Expect(err).To(BeNil())
+		_, err = golab("files", "update", "-i", "group/project", "-f", "docs/readme.md", "-b", "master", "-c", "line 1\nline 2 changed\n", "-m", "Change docs")
+		Expect(err).To(BeNil())
+
+		out, err := golab("commits", "ls", "-i", "group/project", "--path", "docs", "-o", "go-template={{.title}}")
+		Expect(err).To(BeNil())
+		Expect(strings.Split(strings.TrimSpace(out), "\n")).To(Equal([]string{"Change docs", "Add docs/readme.md"}))
+
+		out, err = golab("commits", "get", "-i", "group/project", "-s", "master")
+		Expect(err).To(BeNil())
+		commit := &gitlab.Commit{}
+		Expect(json.Unmarshal([]byte(out), commit)).To(Succeed())
+		Expect(commit.Stats).To(Equal(&gitlab.CommitStats{Additions: 2, Deletions: 2, Total: 4}))
+
+		patch, err := golab("commits", "diff", "-i", "group/project", "-s", commit.ID)
+		Expect(err).To(BeNil())
+		Expect(patch).To(HavePrefix("diff --git a/docs/readme.md b/docs/readme.md\n--- a/docs/readme.md\n"))
+		Expect(os.MkdirAll(filepath.Join(tempDir, "docs"), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(tempDir, "docs", "readme.md"), []byte("line 1\nline 2\n"), 0600)).To(Succeed())
+		apply := exec.Command("git", "apply")
+		apply.Dir = tempDir
+		apply.Stdin = strings.NewReader(patch)
+		applied, err := apply.CombinedOutput()
+		Expect(err).To(BeNil(), string(applied))
+		Expect(ioutil.ReadFile(filepath.Join(tempDir, "docs", "readme.md"))).To(Equal([]byte("line 1\nline 2 changed\n")))
+
+		_, err = golab("commits", "comments", "add", "-i", "group/project", "-s", commit.ID, "-n", "Typo?", "-p", "docs/readme.md", "-l", "2")
+		Expect(err).To(BeNil())
+		out, err = golab("commits", "comments", "ls", "-i", "group/project", "-s", commit.ID)
+		Expect(err).To(BeNil())
+		var comments []*gitlab.CommitComment
+		Expect(json.Unmarshal([]byte(out), &comments)).To(Succeed())
+		Expect(comments).To(HaveLen(1))
+		Expect(comments[0].Path).To(Equal("docs/readme.md"))
+		Expect(comments[0].Line).To(Equal(2))
+		Expect(comments[0].LineType).To(Equal("new"))
+		_, err = golab("commits", "comments", "add", "-i", "group/project", "-s", commit.ID, "-n", "Typo?", "-l", "2")
+		Expect(err).To(MatchError(ContainSubstring("`--path` and `--line` are required")))
+
+		server.AddCommitStatus(commit.ID, "master", "test", "success")
+		out, err = golab("commits", "statuses", "-i", "group/project", "-s", commit.ID, "-o", "ids")
+		Expect(err).To(BeNil())
+		Expect(strings.Fields(out)).To(HaveLen(1))
+
+		out, err = golab("commits", "cherry-pick", "-i", "group/project", "-s", commit.ID, "-b", "stable")
+		Expect(err).To(BeNil())
+		picked := &gitlab.Commit{}
+		Expect(json.Unmarshal([]byte(out), picked)).To(Succeed())
+		Expect(picked.Title).To(Equal("Change docs"))
+		content, _ := server.File(project, "stable", "docs/readme.md")
+		Expect(string(content)).To(Equal("line 1\nline 2 changed\n"))
+		_, err = golab("commits", "cherry-pick", "-i", "group/project", "-s", commit.ID, "-b", "stable")
+		Expect(err).To(MatchError(ContainSubstring("cannot cherry-pick")))
+	})
+
+	It("creates tags with release notes from stdin and attached uploads", func() {
+		group := server.AddGroup("Group", "group", nil)
+		project := server.AddProject("project", "project", group, server.Users()[0])
+		Expect(ioutil.WriteFile(filepath.Join(tempDir, "app.tar.gz"), []byte{0x1f, 0x8b, 0x08}, 0600)).To(Succeed())
+
+		out, err := golabWithStdin([]byte("## Changes\n\n* fixed everything\n"), "tags", "create", "-i", "group/project", "-t", "v1.0.0", "-r", "master", "-m", "Release 1.0.0", "-d", "-", "-a", "app.tar.gz")
+		Expect(err).To(BeNil(), out)
+		tag := &gitlab.Tag{}
+		Expect(json.Unmarshal([]byte(out), tag)).To(Succeed())
+		Expect(tag.Message).To(Equal("Release 1.0.0"))
+		link := regexp.MustCompile(`\[app.tar.gz\]\((/uploads/\d+/app.tar.gz)\)$`).FindStringSubmatch(tag.Release.Description)
+		Expect(link).NotTo(BeNil(), tag.Release.Description)
+		Expect(tag.Release.Description).To(HavePrefix("## Changes\n\n* fixed everything\n\n["))
+		Expect(server.Upload(project, link[1])).To(Equal([]byte{0x1f, 0x8b, 0x08}))
+
+		_, err = golab("tags", "create", "-i", "group/project", "-t", "v0.9.0", "-r", "v1.0.0")
+		Expect(err).To(BeNil())
+		out, err = golab("tags", "ls", "-i", "group/project", "-o", "ids")
+		Expect(err).To(BeNil())
+		Expect(strings.Fields(out)).To(Equal([]string{"v1.0.0", "v0.9.0"}))
+
+		_, err = golab("tags", "delete", "-i", "group/project", "-t", "v0.9.0")
+		Expect(err).To(BeNil())
+		_, err = golab("tags", "get", "-i", "group/project", "-t", "v0.9.0")
+		Expect(err).To(MatchError(ContainSubstring("404")))
+	})
+
+	It("manages labels and standardises them across projects", func() {
+		group := server.AddGroup("Group", "group", nil)
+		template := server.AddProject("template", "template", group, server.Users()[0])
+		project := server.AddProject("project", "project", group, server.Users()[0])
+		server.AddLabel(project, "Bug", "#ff0000", "")
+		server.AddLabel(project, "defect", "#00ff00", "Something is broken")
+		server.AddLabel(project, "obsolete", "#cccccc", "")
+
+		_, err := golab("labels", "create", "-i", "group/template", "-n", "bug", "-c", "#d9534f", "-d", "Something isn't working")
+		Expect(err).To(BeNil())
+		_, err = golab("labels", "create", "-i", "group/template", "-n", "bug", "-c", "#d9534f")
+		Expect(err).To(MatchError(ContainSubstring("409")))
+		_, err = golab("labels", "update", "-i", "group/template", "-n", "bug", "-c", "#aa0000")
+		Expect(err).To(BeNil())
+		out, err := golab("labels", "ls", "-i", "group/template", "-o", "ids")
+		Expect(err).To(BeNil())
+		Expect(strings.Fields(out)).To(Equal([]string{"bug"}))
+
+		out, err = golab("labels", "sync", "--from", "group/template", "--to", "group")
+		Expect(err).To(BeNil(), out)
+		Expect(out).To(ContainSubstring("name: Bug => bug"))
+		Expect(server.Labels(project)).To(Equal([]*gitlab.Label{
+			{Name: "bug", Color: "#aa0000", Description: "Something isn't working"},
+			{Name: "defect", Color: "#00ff00", Description: "Something is broken"},
+			{Name: "obsolete", Color: "#cccccc"},
+		}))
+		Expect(server.Labels(template)).To(HaveLen(1))
+		out, err = golab("--dry-run", "labels", "sync", "--from", "group/template", "--to", "group/project", "--prune")
+		Expect(err).To(BeNil(), out)
+		Expect(out).To(ContainSubstring("- delete label defect of project group/project"))
+		Expect(server.Labels(project)).To(HaveLen(3))
+
+		manifest := filepath.Join(tempDir, "labels.yml")
+		Expect(ioutil.WriteFile(manifest, []byte(`projects:
+  - group/project
+labels:
+  - name: bug
+    color: "#aa0000"
+    description: Something isn't working
+  - name: regression
+    color: "#ff0000"
+    description: Something is broken
+    aliases: [defect]
+`), 0600)).To(Succeed())
+		out, err = golab("labels", "apply", "-f", manifest, "--prune")
+		Expect(err).To(BeNil(), out)
+		Expect(server.Labels(project)).To(Equal([]*gitlab.Label{
+			{Name: "bug", Color: "#aa0000", Description: "Something isn't working"},
+			{Name: "regression", Color: "#ff0000", Description: "Something is broken"},
+		}))
+
+		out, err = golab("labels", "apply", "-f", manifest, "--prune")
+		Expect(err).To(BeNil())
+		Expect(out).NotTo(ContainSubstring("update"))
+		_, err = golab("labels", "delete", "-i", "group/project", "-n", "regression")
+		Expect(err).To(BeNil())
+		Expect(server.Labels(project)).To(HaveLen(1))
+	})
+
+	It("manages milestones", func() {
+		group := server.AddGroup("Group", "group", nil)
+		server.AddProject("project", "project", group, server.Users()[0])
+
+		out, err := golab("milestones", "create", "-i", "group/project", "-t", "1.0", "--due_date", "2018-06-30")
+		Expect(err).To(BeNil(), out)
+		milestone := &gitlab.Milestone{}
+		Expect(json.Unmarshal([]byte(out), milestone)).To(Succeed())
+		Expect(milestone.State).To(Equal("active"))
+		Expect(milestone.DueDate).To(Equal("2018-06-30"))
+		_, err = golab("milestones", "create", "-i", "group/project", "-t", "2.0")
+		Expect(err).To(BeNil())
+
+		id := strconv.Itoa(milestone.ID)
+		_, err = golab("milestones", "update", "-i", "group/project", "-m", id, "--state_event", "close")
+		Expect(err).To(BeNil())
+		out, err = golab("milestones", "ls", "-i", "group/project", "-s", "active", "-o", "json")
+		Expect(err).To(BeNil())
+		Expect(out).To(ContainSubstring(`"title": "2.0"`))
+		Expect(out).NotTo(ContainSubstring(`"title": "1.0"`))
+		out, err = golab("milestones", "ls", "-i", "group/project", "--iids", "1", "-o", "ids")
+		Expect(err).To(BeNil())
+		Expect(strings.Fields(out)).To(Equal([]string{id}))
+		out, err = golab("milestones", "issues", "-i", "group/project", "-m", id, "-o", "json")
+		Expect(err).To(BeNil())
+		Expect(strings.TrimSpace(out)).To(Equal("[]"))
+
+		_, err = golab("milestones", "delete", "-i", "group/project", "-m", id)
+		Expect(err).To(BeNil())
+		_, err = golab("milestones", "get", "-i", "group/project", "-m", id)
+		Expect(err).To(MatchError(ContainSubstring("404")))
+	})
+
+	It("manages wiki pages and exports them to and imports them from a directory", func() {
+		group := server.AddGroup("Group", "group", nil)
+		project := server.AddProject("project", "project", group, server.Users()[0])
+		server.AddWikiPage(project, "guides/Setup", "= Setup\n", "asciidoc")
+		server.AddWikiPage(project, "obsolete", "old\n", "markdown")
+
+		out, err := golabWithStdin([]byte("# Home\n"), "wiki", "create", "-i", "group/project", "-t", "home", "-c", "-")
+		Expect(err).To(BeNil(), out)
+		_, err = golab("wiki", "update", "-i", "group/project", "-s", "guides/Setup", "-c", "= Setup\n\nRun make.\n")
+		Expect(err).To(BeNil())
+		out, err = golab("wiki", "get", "-i", "group/project", "-s", "guides/Setup")
+		Expect(err).To(BeNil())
+		page := &gitlab.Wiki{}
+		Expect(json.Unmarshal([]byte(out), page)).To(Succeed())
+		Expect(*page).To(Equal(gitlab.Wiki{Slug: "guides/Setup", Title: "Setup", Format: "asciidoc", Content: "= Setup\n\nRun make.\n"}))
+		out, err = golab("wiki", "ls", "-i", "group/project", "-o", "ids")
+		Expect(err).To(BeNil())
+		Expect(strings.Fields(out)).To(Equal([]string{"guides/Setup", "home", "obsolete"}))
+
+		// export and import take the project from the git remote of the clone in tempDir
+		Expect(os.MkdirAll(filepath.Join(tempDir, ".git"), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(tempDir, ".git", "config"), []byte(fmt.Sprintf("[remote \"origin\"]\n\turl = %s/group/project.git\n", server.URL)), 0600)).To(Succeed())
+		dir := filepath.Join(tempDir, "wiki")
+		out, err = golab("wiki", "export", "-d", dir)
+		Expect(err).To(BeNil(), out)
+		Expect(out).To(ContainSubstring("3 wiki pages written to"))
+		content, err := ioutil.ReadFile(filepath.Join(dir, "guides", "Setup.adoc"))
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(Equal("---\ntitle: Setup\nformat: asciidoc\n---\n= Setup\n\nRun make.\n"))
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "home.md"), []byte("---\ntitle: home\nformat: markdown\n---\n# Welcome\n"), 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "guides", "release-process.md"), []byte("# Releases\n"), 0600)).To(Succeed())
+		Expect(os.Remove(filepath.Join(dir, "obsolete.md"))).To(Succeed())
+
+		out, err = golab("wiki", "import", "-d", dir, "--prune")
+		Expect(err).To(BeNil(), out)
+		Expect(out).To(ContainSubstring("Plan: 1 to create, 1 to update, 1 to delete."))
+		Expect(server.WikiPages(project)).To(Equal([]*gitlab.Wiki{
+			{Slug: "guides/Setup", Title: "Setup", Format: "asciidoc", Content: "= Setup\n\nRun make.\n"},
+			{Slug: "guides/release-process", Title: "release process", Format: "markdown", Content: "# Releases\n"},
+			{Slug: "home", Title: "home", Format: "markdown", Content: "# Welcome\n"},
+		}))
+
+		out, err = golab("wiki", "import", "-i", "group/project", "-d", dir, "--prune")
+		Expect(err).To(BeNil())
+		Expect(out).To(ContainSubstring("No changes"))
+		_, err = golab("wiki", "delete", "-i", "group/project", "-s", "home")
+		Expect(err).To(BeNil())
+		_, err = golab("wiki", "get", "-i", "group/project", "-s", "home")
+		Expect(err).To(MatchError(ContainSubstring("404")))
+	})
+
+	It("does not send changing requests with --dry-run", func() {
+		user := server.AddUser("jdoe", "jdoe@example.com", "John Doe", "")
+
+		_, err := golab("--dry-run", "user", "delete", "-i", strconv.Itoa(user.ID))
+
+		Expect(err).To(BeNil())
+		Expect(server.Users()).To(HaveLen(2))
+	})
+
+	It("retries requests that failed with a server error", func() {
+		server.Fail("GET", "/users", http.StatusBadGateway, 2)
+
+		out, err := golab("user", "ls", "-o", "ids")
+
+		Expect(err).To(BeNil())
+		Expect(strings.Fields(out)).To(Equal([]string{"1"}))
+	})
+
+	It("exits with an error if the server responds with an error status", func() {
+		server.Fail("GET", "/users", http.StatusInternalServerError, 0)
+
+		_, err := golab("user", "ls")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("500"))
+
+		_, err = golab("project", "get", "-i", "does/not-exist")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("404"))
+	})
+
+	It("rejects unknown output formats before sending any request", func() {
+		group := server.AddGroup("Group", "group", nil)
+		server.AddProject("Project", "project", group, server.Users()[0])
+
+		_, err := golab("project", "delete", "-i", "group/project", "-o", "tabel")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("unknown output format 'tabel'"))
+
+		_, err = golab("project", "get", "-i", "group/project")
+		Expect(err).To(BeNil())
+	})
+
+})
@@ -0,0 +1,353 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func (s *Server) registerGroupRoutes() {
+	s.handle("GET", "/groups", s.listGroups)
+	s.handle("POST", "/groups", s.createGroup)
+	s.handle("GET", "/groups/:group", s.getGroup)
+	s.handle("PUT", "/groups/:group", s.updateGroup)
+	s.handle("DELETE", "/groups/:group", s.deleteGroup)
+	s.handle("GET", "/groups/:group/projects", s.listGroupProjects)
+	s.handle("POST", "/groups/:group/projects/:project", s.transferProject)
+	s.handle("GET", "/groups/:group/members", s.listGroupMembers)
+	s.handle("POST", "/groups/:group/members", s.addGroupMember)
+	s.handle("GET", "/groups/:group/members/:user", s.getGroupMember)
+	s.handle("PUT", "/groups/:group/members/:user", s.editGroupMember)
+	s.handle("DELETE", "/groups/:group/members/:user", s.deleteGroupMember)
+}
+
+// AddGroup creates a group, parent is nil for top-level groups
+func (s *Server) AddGroup(name string, path string, parent *gitlab.Group) *gitlab.Group {
+	visibility := gitlab.PrivateVisibility
+	group := &gitlab.Group{
+		ID:         s.nextId(),
+		Name:       name,
+		Path:       path,
+		FullName:   name,
+		FullPath:   path,
+		Visibility: &visibility,
+	}
+	if parent != nil {
+		group.ParentID = parent.ID
+		group.FullName = parent.FullName + " / " + name
+		group.FullPath = parent.FullPath + "/" + path
+	}
+	group.WebURL = s.URL + "/groups/" + group.FullPath
+	s.groups[group.ID] = group
+	return group
+}
+
+// Groups returns all groups ordered by ID
+func (s *Server) Groups() []*gitlab.Group {
+	var ids []int
+	for id := range s.groups {
+		ids = append(ids, id)
+	}
+	groups := []*gitlab.Group{}
+	for _, id := range sortedIds(ids) {
+		groups = append(groups, s.groups[id])
+	}
+	return groups
+}
+
+// findGroup returns the group with the given ID or full path
+func (s *Server) findGroup(idOrPath string) *gitlab.Group {
+	if id, err := strconv.Atoi(idOrPath); err == nil {
+		return s.groups[id]
+	}
+	for _, group := range s.groups {
+		if group.FullPath == idOrPath {
+			return group
+		}
+	}
+	return nil
+}
+
+func (s *Server) listGroups(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	search := r.URL.Query().Get("search")
+	groups := []*gitlab.Group{}
+	for _, group := range s.Groups() {
+		if search == "" || strings.Contains(group.FullPath+group.FullName, search) {
+			groups = append(groups, group)
+		}
+	}
+	writePage(w, r, groups)
+}
+
+func (s *Server) getGroup(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	group := s.findGroup(params["group"])
+	if group == nil {
+		writeError(w, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+	writeJson(w, http.StatusOK, group)
+}
+
+func (s *Server) createGroup(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	body := readBody(r)
+	if missing(w, body, "name", "path") {
+		return
+	}
+	var parent *gitlab.Group
+	if parentId, ok := intValue(body, "parent_id"); ok && parentId != 0 {
+		if parent = s.groups[parentId]; parent == nil {
+			writeError(w, http.StatusNotFound, "404 Parent Group Not Found")
+			return
+		}
+	}
+	name, _ := stringValue(body, "name")
+	path, _ := stringValue(body, "path")
+	fullPath := path
+	if parent != nil {
+		fullPath = parent.FullPath + "/" + path
+	}
+	if s.findGroup(fullPath) != nil {
+		writeError(w, http.StatusBadRequest, "Failed to save group {:path=>[\"has already been taken\"]}")
+		return
+	}
+	group := s.AddGroup(name, path, parent)
+	applyGroupAttributes(group, body)
+	s.groupMembers[group.ID] = []*gitlab.GroupMember{newGroupMember(s.currentUser(r), gitlab.OwnerPermission)}
+	writeJson(w, http.StatusCreated, group)
+}
+
+func (s *Server) updateGroup(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	group := s.findGroup(params["group"])
+	if group == nil {
+		writeError(w, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+	body := readBody(r)
+	if name, ok := stringValue(body, "name"); ok {
+		group.Name = name
+	}
+	if path, ok := stringValue(body, "path"); ok {
+		group.FullPath = strings.TrimSuffix(group.FullPath, group.Path) + path
+		group.Path = path
+	}
+	applyGroupAttributes(group, body)
+	writeJson(w, http.StatusOK, group)
+}
+
+func applyGroupAttributes(group *gitlab.Group, body map[string]interface{}) {
+	if value, ok := stringValue(body, "description"); ok {
+		group.Description = value
+	}
+	if value, ok := stringValue(body, "visibility"); ok {
+		visibility := gitlab.VisibilityValue(value)
+		group.Visibility = &visibility
+	}
+	if value, ok := boolValue(body, "lfs_enabled"); ok {
+		group.LFSEnabled = value
+	}
+	if value, ok := boolValue(body, "request_access_enabled"); ok {
+		group.RequestAccessEnabled = value
+	}
+}
+
+func (s *Server) deleteGroup(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	group := s.findGroup(params["group"])
+	if group == nil {
+		writeError(w, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+	for _, project := range s.projects {
+		if project.Namespace != nil && project.Namespace.ID == group.ID {
+			s.removeProject(project)
+		}
+	}
+	delete(s.groups, group.ID)
+	delete(s.groupMembers, group.ID)
+	writeJson(w, http.StatusAccepted, map[string]string{"message": "202 Accepted"})
+}
+
+func (s *Server) listGroupProjects(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	group := s.findGroup(params["group"])
+	if group == nil {
+		writeError(w, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+	projects := []*gitlab.Project{}
+	for _, project := range s.Projects() {
+		if project.Namespace != nil && project.Namespace.ID == group.ID {
+			projects = append(projects, project)
+		}
+	}
+	writePage(w, r, projects)
+}
+
+func (s *Server) transferProject(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	group := s.findGroup(params["group"])
+	project := s.findProject(params["project"])
+	if group == nil || project == nil {
+		writeError(w, http.StatusNotFound, "404 Not Found")
+		return
+	}
+	s.moveProject(project, group)
+	writeJson(w, http.StatusCreated, group)
+}
+
+func newGroupMember(user *gitlab.User, accessLevel gitlab.AccessLevelValue) *gitlab.GroupMember {
+	return &gitlab.GroupMember{
+		ID:          user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Name:        user.Name,
+		State:       user.State,
+		CreatedAt:   now(),
+		AccessLevel: accessLevel,
+	}
+}
+
+// expiresAtValue returns the expiry date of a member, ok is false if it was not given, null removes the expiry date
+func expiresAtValue(body map[string]interface{}) (*gitlab.ISOTime, bool) {
+	value, ok := body["expires_at"]
+	if !ok {
+		return nil, false
+	}
+	if date, isString := value.(string); isString {
+		return parseIsoTime(date), true
+	}
+	return nil, true
+}
+
+func findGroupMember(members []*gitlab.GroupMember, userId string) *gitlab.GroupMember {
+	for _, member := range members {
+		if strconv.Itoa(member.ID) == userId {
+			return member
+		}
+	}
+	return nil
+}
+
+func removeGroupMember(members []*gitlab.GroupMember, userId int) []*gitlab.GroupMember {
+	var result []*gitlab.GroupMember
+	for _, member := range members {
+		if member.ID != userId {
+			result = append(result, member)
+		}
+	}
+	return result
+}
+
+func (s *Server) listGroupMembers(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	group := s.findGroup(params["group"])
+	if group == nil {
+		writeError(w, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+	query := r.URL.Query().Get("query")
+	members := []*gitlab.GroupMember{}
+	for _, member := range s.groupMembers[group.ID] {
+		if query == "" || strings.Contains(member.Username+member.Name, query) {
+			members = append(members, member)
+		}
+	}
+	writePage(w, r, members)
+}
+
+func (s *Server) getGroupMember(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	group := s.findGroup(params["group"])
+	if group == nil {
+		writeError(w, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+	member := findGroupMember(s.groupMembers[group.ID], params["user"])
+	if member == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	writeJson(w, http.StatusOK, member)
+}
+
+func (s *Server) addGroupMember(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	group := s.findGroup(params["group"])
+	if group == nil {
+		writeError(w, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+	body := readBody(r)
+	if missing(w, body, "user_id", "access_level") {
+		return
+	}
+	userId, _ := stringValue(body, "user_id")
+	user := s.findUser(userId)
+	if user == nil {
+		writeError(w, http.StatusNotFound, "404 User Not Found")
+		return
+	}
+	if findGroupMember(s.groupMembers[group.ID], strconv.Itoa(user.ID)) != nil {
+		writeError(w, http.StatusConflict, "Member already exists")
+		return
+	}
+	accessLevel, _ := intValue(body, "access_level")
+	member := newGroupMember(user, gitlab.AccessLevelValue(accessLevel))
+	if expiresAt, ok := expiresAtValue(body); ok {
+		member.ExpiresAt = expiresAt
+	}
+	s.groupMembers[group.ID] = append(s.groupMembers[group.ID], member)
+	writeJson(w, http.StatusCreated, member)
+}
+
+func (s *Server) editGroupMember(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	group := s.findGroup(params["group"])
+	if group == nil {
+		writeError(w, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+	member := findGroupMember(s.groupMembers[group.ID], params["user"])
+	if member == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	body := readBody(r)
+	if accessLevel, ok := intValue(body, "access_level"); ok {
+		member.AccessLevel = gitlab.AccessLevelValue(accessLevel)
+	}
+	if expiresAt, ok := expiresAtValue(body); ok {
+		member.ExpiresAt = expiresAt
+	}
+	writeJson(w, http.StatusOK, member)
+}
+
+func (s *Server) deleteGroupMember(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	group := s.findGroup(params["group"])
+	if group == nil {
+		writeError(w, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+	member := findGroupMember(s.groupMembers[group.ID], params["user"])
+	if member == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	s.groupMembers[group.ID] = removeGroupMember(s.groupMembers[group.ID], member.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,242 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func (s *Server) registerLabelRoutes() {
+	s.handle("GET", "/projects/:project/labels", s.withProject(s.listLabels))
+	s.handle("POST", "/projects/:project/labels", s.withProject(s.createLabel))
+	s.handle("PUT", "/projects/:project/labels", s.withProject(s.updateLabel))
+	s.handle("DELETE", "/projects/:project/labels", s.withProject(s.deleteLabel))
+	s.handle("GET", "/projects/:project/milestones", s.withProject(s.listMilestones))
+	s.handle("POST", "/projects/:project/milestones", s.withProject(s.createMilestone))
+	s.handle("GET", "/projects/:project/milestones/:milestone", s.withMilestone(s.getMilestone))
+	s.handle("PUT", "/projects/:project/milestones/:milestone", s.withMilestone(s.updateMilestone))
+	s.handle("DELETE", "/projects/:project/milestones/:milestone", s.withMilestone(s.deleteMilestone))
+	s.handle("GET", "/projects/:project/milestones/:milestone/issues", s.withMilestone(s.listMilestoneIssues))
+}
+
+// AddLabel creates a label in the project
+func (s *Server) AddLabel(project *gitlab.Project, name string, color string, description string) *gitlab.Label {
+	label := &gitlab.Label{Name: name, Color: color, Description: description}
+	s.labels[project.ID] = append(s.labels[project.ID], label)
+	return label
+}
+
+// Labels returns the labels of the project ordered by name
+func (s *Server) Labels(project *gitlab.Project) []*gitlab.Label {
+	labels := append([]*gitlab.Label{}, s.labels[project.ID]...)
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return labels
+}
+
+func findLabel(labels []*gitlab.Label, name string) *gitlab.Label {
+	for _, label := range labels {
+		if label.Name == name {
+			return label
+		}
+	}
+	return nil
+}
+
+// colorPattern matches the hex colors Gitlab accepts, CSS color names are accepted as any word
+var colorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6}|[a-zA-Z]+)$`)
+
+func (s *Server) listLabels(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	writePage(w, r, s.Labels(project))
+}
+
+func (s *Server) createLabel(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	body := readBody(r)
+	if missing(w, body, "name", "color") {
+		return
+	}
+	name, _ := stringValue(body, "name")
+	color, _ := stringValue(body, "color")
+	if !colorPattern.MatchString(color) {
+		writeError(w, http.StatusBadRequest, "Color must be a valid color code")
+		return
+	}
+	if findLabel(s.labels[project.ID], name) != nil {
+		writeError(w, http.StatusConflict, "Label already exists")
+		return
+	}
+	description, _ := stringValue(body, "description")
+	writeJson(w, http.StatusCreated, s.AddLabel(project, name, color, description))
+}
+
+func (s *Server) updateLabel(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	body := readBody(r)
+	if missing(w, body, "name") {
+		return
+	}
+	name, _ := stringValue(body, "name")
+	label := findLabel(s.labels[project.ID], name)
+	if label == nil {
+		writeError(w, http.StatusNotFound, "404 Label Not Found")
+		return
+	}
+	newName, hasNewName := stringValue(body, "new_name")
+	color, hasColor := stringValue(body, "color")
+	description, hasDescription := stringValue(body, "description")
+	if !hasNewName && !hasColor && !hasDescription {
+		writeError(w, http.StatusBadRequest, "new_name, color, description are missing, at least one parameter must be provided")
+		return
+	}
+	if hasNewName && newName != name && findLabel(s.labels[project.ID], newName) != nil {
+		writeError(w, http.StatusConflict, "Label already exists")
+		return
+	}
+	if hasColor && !colorPattern.MatchString(color) {
+		writeError(w, http.StatusBadRequest, "Color must be a valid color code")
+		return
+	}
+	if hasNewName {
+		label.Name = newName
+	}
+	if hasColor {
+		label.Color = color
+	}
+	if hasDescription {
+		label.Description = description
+	}
+	writeJson(w, http.StatusOK, label)
+}
+
+func (s *Server) deleteLabel(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	body := readBody(r)
+	if missing(w, body, "name") {
+		return
+	}
+	name, _ := stringValue(body, "name")
+	if findLabel(s.labels[project.ID], name) == nil {
+		writeError(w, http.StatusNotFound, "404 Label Not Found")
+		return
+	}
+	var labels []*gitlab.Label
+	for _, label := range s.labels[project.ID] {
+		if label.Name != name {
+			labels = append(labels, label)
+		}
+	}
+	s.labels[project.ID] = labels
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type milestoneHandler func(w http.ResponseWriter, r *http.Request, project *gitlab.Project, milestone *gitlab.Milestone)
+
+func (s *Server) withMilestone(h milestoneHandler) handler {
+	return s.withProject(func(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+		for _, milestone := range s.milestones[project.ID] {
+			if strconv.Itoa(milestone.ID) == params["milestone"] {
+				h(w, r, project, milestone)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "404 Milestone Not Found")
+	})
+}
+
+// listMilestones lists the milestones of a project filtered by `iids`, `state` and `search`
+func (s *Server) listMilestones(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	query := r.URL.Query()
+	iids := map[string]bool{}
+	for _, iid := range append(query["iids"], query["iids[]"]...) {
+		iids[iid] = true
+	}
+	milestones := []*gitlab.Milestone{}
+	for _, milestone := range s.milestones[project.ID] {
+		if len(iids) > 0 && !iids[strconv.Itoa(milestone.IID)] {
+			continue
+		}
+		if state := query.Get("state"); state != "" && milestone.State != state {
+			continue
+		}
+		if search := query.Get("search"); search != "" && !strings.Contains(milestone.Title+" "+milestone.Description, search) {
+			continue
+		}
+		milestones = append(milestones, milestone)
+	}
+	writePage(w, r, milestones)
+}
+
+func (s *Server) getMilestone(w http.ResponseWriter, r *http.Request, project *gitlab.Project, milestone *gitlab.Milestone) {
+	writeJson(w, http.StatusOK, milestone)
+}
+
+func (s *Server) createMilestone(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	body := readBody(r)
+	if missing(w, body, "title") {
+		return
+	}
+	milestone := &gitlab.Milestone{ID: s.nextId(), IID: len(s.milestones[project.ID]) + 1, ProjectID: project.ID, State: "active", CreatedAt: now(), UpdatedAt: now()}
+	applyMilestoneAttributes(milestone, body)
+	s.milestones[project.ID] = append(s.milestones[project.ID], milestone)
+	writeJson(w, http.StatusCreated, milestone)
+}
+
+func (s *Server) updateMilestone(w http.ResponseWriter, r *http.Request, project *gitlab.Project, milestone *gitlab.Milestone) {
+	body := readBody(r)
+	applyMilestoneAttributes(milestone, body)
+	switch event, _ := stringValue(body, "state_event"); event {
+	case "close":
+		milestone.State = "closed"
+	case "activate":
+		milestone.State = "active"
+	}
+	milestone.UpdatedAt = now()
+	writeJson(w, http.StatusOK, milestone)
+}
+
+func applyMilestoneAttributes(milestone *gitlab.Milestone, body map[string]interface{}) {
+	for key, field := range map[string]*string{"title": &milestone.Title, "description": &milestone.Description, "start_date": &milestone.StartDate, "due_date": &milestone.DueDate} {
+		if value, ok := stringValue(body, key); ok {
+			*field = value
+		}
+	}
+}
+
+func (s *Server) deleteMilestone(w http.ResponseWriter, r *http.Request, project *gitlab.Project, milestone *gitlab.Milestone) {
+	var milestones []*gitlab.Milestone
+	for _, m := range s.milestones[project.ID] {
+		if m != milestone {
+			milestones = append(milestones, m)
+		}
+	}
+	s.milestones[project.ID] = milestones
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listMilestoneIssues returns no issues, as the fake does not keep issues
+func (s *Server) listMilestoneIssues(w http.ResponseWriter, r *http.Request, project *gitlab.Project, milestone *gitlab.Milestone) {
+	writePage(w, r, []*gitlab.Issue{})
+}
@@ -0,0 +1,239 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func (s *Server) registerMergeRequestRoutes() {
+	s.handle("GET", "/merge_requests", s.listMergeRequests)
+	s.handle("GET", "/projects/:project/merge_requests", s.withProject(s.listProjectMergeRequests))
+	s.handle("POST", "/projects/:project/merge_requests", s.withProject(s.createMergeRequest))
+	s.handle("GET", "/projects/:project/merge_requests/:iid", s.withProject(s.getMergeRequest))
+	s.handle("PUT", "/projects/:project/merge_requests/:iid", s.withProject(s.updateMergeRequest))
+	s.handle("DELETE", "/projects/:project/merge_requests/:iid", s.withProject(s.deleteMergeRequest))
+	s.handle("PUT", "/projects/:project/merge_requests/:iid/merge", s.withProject(s.acceptMergeRequest))
+}
+
+func findMergeRequest(mergeRequests []*gitlab.MergeRequest, iid string) *gitlab.MergeRequest {
+	for _, mergeRequest := range mergeRequests {
+		if strconv.Itoa(mergeRequest.IID) == iid {
+			return mergeRequest
+		}
+	}
+	return nil
+}
+
+// matchesMergeRequestFilters applies the `state`, `source_branch`, `target_branch` and `search` filters of list requests
+func matchesMergeRequestFilters(r *http.Request, mergeRequest *gitlab.MergeRequest) bool {
+	query := r.URL.Query()
+	if state := query.Get("state"); state != "" && state != "all" && state != mergeRequest.State {
+		return false
+	}
+	if branch := query.Get("source_branch"); branch != "" && branch != mergeRequest.SourceBranch {
+		return false
+	}
+	if branch := query.Get("target_branch"); branch != "" && branch != mergeRequest.TargetBranch {
+		return false
+	}
+	if search := query.Get("search"); search != "" && !strings.Contains(mergeRequest.Title+mergeRequest.Description, search) {
+		return false
+	}
+	return true
+}
+
+func (s *Server) listMergeRequests(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	mergeRequests := []*gitlab.MergeRequest{}
+	for _, project := range s.Projects() {
+		for _, mergeRequest := range s.mergeRequests[project.ID] {
+			if matchesMergeRequestFilters(r, mergeRequest) {
+				mergeRequests = append(mergeRequests, mergeRequest)
+			}
+		}
+	}
+	writePage(w, r, mergeRequests)
+}
+
+func (s *Server) listProjectMergeRequests(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	mergeRequests := []*gitlab.MergeRequest{}
+	for _, mergeRequest := range s.mergeRequests[project.ID] {
+		if matchesMergeRequestFilters(r, mergeRequest) {
+			mergeRequests = append(mergeRequests, mergeRequest)
+		}
+	}
+	writePage(w, r, mergeRequests)
+}
+
+func (s *Server) getMergeRequest(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	mergeRequest := findMergeRequest(s.mergeRequests[project.ID], params["iid"])
+	if mergeRequest == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	writeJson(w, http.StatusOK, mergeRequest)
+}
+
+func (s *Server) createMergeRequest(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	body := readBody(r)
+	if missing(w, body, "source_branch", "target_branch", "title") {
+		return
+	}
+	sourceBranch, _ := stringValue(body, "source_branch")
+	targetBranch, _ := stringValue(body, "target_branch")
+	source := findBranch(s.branches[project.ID], sourceBranch)
+	if source == nil || findBranch(s.branches[project.ID], targetBranch) == nil {
+		writeError(w, http.StatusNotFound, "404 Branch Not Found")
+		return
+	}
+	for _, mergeRequest := range s.mergeRequests[project.ID] {
+		if mergeRequest.State == "opened" && mergeRequest.SourceBranch == sourceBranch && mergeRequest.TargetBranch == targetBranch {
+			writeError(w, http.StatusConflict, "Another open merge request already exists for this source branch")
+			return
+		}
+	}
+	user := s.currentUser(r)
+	mergeRequest := &gitlab.MergeRequest{
+		ID:              s.nextId(),
+		IID:             len(s.mergeRequests[project.ID]) + 1,
+		ProjectID:       project.ID,
+		SourceProjectID: project.ID,
+		TargetProjectID: project.ID,
+		SourceBranch:    sourceBranch,
+		TargetBranch:    targetBranch,
+		State:           "opened",
+		MergeStatus:     "can_be_merged",
+		SHA:             source.Commit.ID,
+		CreatedAt:       now(),
+		UpdatedAt:       now(),
+		Labels:          []string{},
+	}
+	mergeRequest.Author.ID = user.ID
+	mergeRequest.Author.Username = user.Username
+	mergeRequest.Author.Name = user.Name
+	mergeRequest.Author.State = user.State
+	mergeRequest.WebURL = project.WebURL + "/merge_requests/" + strconv.Itoa(mergeRequest.IID)
+	s.applyMergeRequestAttributes(mergeRequest, body)
+	s.mergeRequests[project.ID] = append(s.mergeRequests[project.ID], mergeRequest)
+	writeJson(w, http.StatusCreated, mergeRequest)
+}
+
+func (s *Server) updateMergeRequest(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	mergeRequest := findMergeRequest(s.mergeRequests[project.ID], params["iid"])
+	if mergeRequest == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	body := readBody(r)
+	if stateEvent, ok := stringValue(body, "state_event"); ok && mergeRequest.State != "merged" {
+		switch stateEvent {
+		case "close":
+			mergeRequest.State = "closed"
+		case "reopen":
+			mergeRequest.State = "opened"
+		}
+	}
+	s.applyMergeRequestAttributes(mergeRequest, body)
+	mergeRequest.UpdatedAt = now()
+	writeJson(w, http.StatusOK, mergeRequest)
+}
+
+func (s *Server) applyMergeRequestAttributes(mergeRequest *gitlab.MergeRequest, body map[string]interface{}) {
+	if value, ok := stringValue(body, "title"); ok {
+		mergeRequest.Title = value
+		mergeRequest.WorkInProgress = strings.HasPrefix(value, "WIP")
+	}
+	if value, ok := stringValue(body, "description"); ok {
+		mergeRequest.Description = value
+	}
+	if value, ok := stringValue(body, "target_branch"); ok {
+		mergeRequest.TargetBranch = value
+	}
+	if value, ok := boolValue(body, "remove_source_branch"); ok {
+		mergeRequest.ForceRemoveSourceBranch = value
+	}
+	if value, ok := stringValue(body, "assignee_id"); ok {
+		if user := s.findUser(value); user != nil {
+			mergeRequest.Assignee.ID = user.ID
+			mergeRequest.Assignee.Username = user.Username
+			mergeRequest.Assignee.Name = user.Name
+			mergeRequest.Assignee.State = user.State
+		}
+	}
+	switch labels := body["labels"].(type) {
+	case string:
+		mergeRequest.Labels = strings.Split(labels, ",")
+	case []interface{}:
+		mergeRequest.Labels = []string{}
+		for _, label := range labels {
+			mergeRequest.Labels = append(mergeRequest.Labels, label.(string))
+		}
+	}
+}
+
+func (s *Server) deleteMergeRequest(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	mergeRequest := findMergeRequest(s.mergeRequests[project.ID], params["iid"])
+	if mergeRequest == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	var mergeRequests []*gitlab.MergeRequest
+	for _, m := range s.mergeRequests[project.ID] {
+		if m != mergeRequest {
+			mergeRequests = append(mergeRequests, m)
+		}
+	}
+	s.mergeRequests[project.ID] = mergeRequests
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// acceptMergeRequest marks the merge request and its source branch as merged
+func (s *Server) acceptMergeRequest(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	mergeRequest := findMergeRequest(s.mergeRequests[project.ID], params["iid"])
+	if mergeRequest == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	if mergeRequest.State != "opened" {
+		writeError(w, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+		return
+	}
+	body := readBody(r)
+	if sha, ok := stringValue(body, "sha"); ok && sha != mergeRequest.SHA {
+		writeError(w, http.StatusConflict, "SHA does not match HEAD of source branch")
+		return
+	}
+	mergeRequest.State = "merged"
+	mergeRequest.UpdatedAt = now()
+	if source := findBranch(s.branches[project.ID], mergeRequest.SourceBranch); source != nil {
+		source.Merged = true
+		removeSource, _ := boolValue(body, "should_remove_source_branch")
+		if removeSource || mergeRequest.ForceRemoveSourceBranch {
+			s.branches[project.ID] = removeBranch(s.branches[project.ID], source.Name)
+		}
+	}
+	mergeRequest.MergeCommitShaSHA = mergeRequest.SHA
+	writeJson(w, http.StatusOK, mergeRequest)
+}
@@ -0,0 +1,87 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the Gitlab version the server reports
+const Version = "10.1.0"
+
+// OAuthTokenLifetime is the lifetime of OAuth access tokens in seconds
+const OAuthTokenLifetime = 7200
+
+func (s *Server) registerOAuthRoutes() {
+	s.handle("POST", "/oauth/token", s.createOAuthToken)
+	s.handle("GET", "/version", s.getVersion)
+}
+
+// createOAuthToken implements the resource owner password credentials and the refresh token grant, refreshing
+// revokes the old access and refresh token like Gitlab does
+func (s *Server) createOAuthToken(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	body := readBody(r)
+	grantType, _ := stringValue(body, "grant_type")
+	userId := 0
+	switch grantType {
+	case "password":
+		username, _ := stringValue(body, "username")
+		password, _ := stringValue(body, "password")
+		if user := s.findUser(username); user != nil && s.passwords[user.ID] == password {
+			userId = user.ID
+		}
+	case "refresh_token":
+		refreshToken, _ := stringValue(body, "refresh_token")
+		userId = s.refreshTokens[refreshToken]
+		delete(s.refreshTokens, refreshToken)
+		for token, id := range s.tokens {
+			if id == userId && strings.HasPrefix(token, "oauth-") {
+				delete(s.tokens, token)
+			}
+		}
+	default:
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	if userId == 0 {
+		writeJson(w, http.StatusUnauthorized, map[string]string{"error": "invalid_grant", "error_description": "The provided authorization grant is invalid, expired or revoked."})
+		return
+	}
+
+	id := strconv.Itoa(s.nextId())
+	accessToken, refreshToken := "oauth-"+id, "refresh-"+id
+	s.tokens[accessToken] = userId
+	s.refreshTokens[refreshToken] = userId
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"access_token":  accessToken,
+		"token_type":    "bearer",
+		"refresh_token": refreshToken,
+		"expires_in":    OAuthTokenLifetime,
+		"created_at":    time.Now().Unix(),
+	})
+}
+
+func (s *Server) getVersion(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	writeJson(w, http.StatusOK, map[string]string{"version": Version, "revision": "fake"})
+}
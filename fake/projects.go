@@ -0,0 +1,457 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func (s *Server) registerProjectRoutes() {
+	s.handle("GET", "/projects", s.listProjects)
+	s.handle("POST", "/projects", s.createProject)
+	s.handle("GET", "/projects/:project", s.withProject(s.getProject))
+	s.handle("PUT", "/projects/:project", s.withProject(s.editProject))
+	s.handle("DELETE", "/projects/:project", s.withProject(s.deleteProject))
+	s.handle("POST", "/projects/:project/uploads", s.withProject(s.uploadFile))
+	s.handle("POST", "/projects/:project/archive", s.withProject(s.setArchived(true)))
+	s.handle("POST", "/projects/:project/unarchive", s.withProject(s.setArchived(false)))
+	s.handle("GET", "/projects/:project/members", s.withProject(s.listProjectMembers))
+	s.handle("POST", "/projects/:project/members", s.withProject(s.addProjectMember))
+	s.handle("GET", "/projects/:project/members/:user", s.withProject(s.getProjectMember))
+	s.handle("PUT", "/projects/:project/members/:user", s.withProject(s.editProjectMember))
+	s.handle("DELETE", "/projects/:project/members/:user", s.withProject(s.deleteProjectMember))
+	s.handle("GET", "/projects/:project/hooks", s.withProject(s.listProjectHooks))
+	s.handle("POST", "/projects/:project/hooks", s.withProject(s.addProjectHook))
+	s.handle("GET", "/projects/:project/hooks/:hook", s.withProject(s.getProjectHook))
+	s.handle("PUT", "/projects/:project/hooks/:hook", s.withProject(s.editProjectHook))
+	s.handle("DELETE", "/projects/:project/hooks/:hook", s.withProject(s.deleteProjectHook))
+}
+
+// AddProject creates a project with a master branch in the namespace of group or, if group is nil, of owner
+func (s *Server) AddProject(name string, path string, group *gitlab.Group, owner *gitlab.User) *gitlab.Project {
+	project := s.addProject(name, path, group, owner)
+	project.DefaultBranch = "master"
+	s.branches[project.ID] = []*gitlab.Branch{s.newBranch(project, "master", "Initial commit")}
+	return project
+}
+
+// addProject creates a project with an empty repository, as Gitlab does for projects created by the API
+func (s *Server) addProject(name string, path string, group *gitlab.Group, owner *gitlab.User) *gitlab.Project {
+	project := &gitlab.Project{
+		ID:                   s.nextId(),
+		Name:                 name,
+		Path:                 path,
+		Visibility:           gitlab.PrivateVisibility,
+		Owner:                owner,
+		CreatorID:            owner.ID,
+		CreatedAt:            now(),
+		LastActivityAt:       now(),
+		IssuesEnabled:        true,
+		MergeRequestsEnabled: true,
+		JobsEnabled:          true,
+		WikiEnabled:          true,
+		SnippetsEnabled:      true,
+		TagList:              []string{},
+	}
+	s.projects[project.ID] = project
+	if group != nil {
+		s.moveProject(project, group)
+	} else {
+		project.Namespace = &gitlab.ProjectNamespace{ID: owner.ID, Name: owner.Username, Path: owner.Username, OwnerID: owner.ID}
+		s.updateProjectPaths(project)
+	}
+	s.projectMembers[project.ID] = []*gitlab.GroupMember{newGroupMember(owner, gitlab.MasterPermissions)}
+	return project
+}
+
+// Projects returns all projects ordered by ID
+func (s *Server) Projects() []*gitlab.Project {
+	var ids []int
+	for id := range s.projects {
+		ids = append(ids, id)
+	}
+	projects := []*gitlab.Project{}
+	for _, id := range sortedIds(ids) {
+		projects = append(projects, s.projects[id])
+	}
+	return projects
+}
+
+// findProject returns the project with the given ID or `namespace/path`
+func (s *Server) findProject(idOrPath string) *gitlab.Project {
+	if id, err := strconv.Atoi(idOrPath); err == nil {
+		return s.projects[id]
+	}
+	for _, project := range s.projects {
+		if project.PathWithNamespace == idOrPath {
+			return project
+		}
+	}
+	return nil
+}
+
+func (s *Server) moveProject(project *gitlab.Project, group *gitlab.Group) {
+	project.Namespace = &gitlab.ProjectNamespace{ID: group.ID, Name: group.FullName, Path: group.FullPath, Description: group.Description}
+	s.updateProjectPaths(project)
+}
+
+func (s *Server) updateProjectPaths(project *gitlab.Project) {
+	project.PathWithNamespace = project.Namespace.Path + "/" + project.Path
+	project.NameWithNamespace = project.Namespace.Name + " / " + project.Name
+	project.WebURL = s.URL + "/" + project.PathWithNamespace
+	project.HTTPURLToRepo = project.WebURL + ".git"
+	project.SSHURLToRepo = "git@" + strings.TrimPrefix(strings.TrimPrefix(s.URL, "http://"), "https://") + ":" + project.PathWithNamespace + ".git"
+}
+
+func (s *Server) removeProject(project *gitlab.Project) {
+	delete(s.projects, project.ID)
+	delete(s.projectMembers, project.ID)
+	delete(s.branches, project.ID)
+	delete(s.hooks, project.ID)
+	delete(s.mergeRequests, project.ID)
+}
+
+// a projectHandler processes a request for an existing project
+type projectHandler func(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project)
+
+// withProject looks up the project of the request and writes a 404 if it does not exist
+func (s *Server) withProject(h projectHandler) handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		project := s.findProject(params["project"])
+		if project == nil {
+			writeError(w, http.StatusNotFound, "404 Project Not Found")
+			return
+		}
+		h(w, r, params, project)
+	}
+}
+
+func (s *Server) listProjects(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	query := r.URL.Query()
+	user := s.currentUser(r)
+	projects := []*gitlab.Project{}
+	for _, project := range s.Projects() {
+		if search := query.Get("search"); search != "" && !strings.Contains(project.PathWithNamespace+project.Name, search) {
+			continue
+		}
+		if visibility := query.Get("visibility"); visibility != "" && string(project.Visibility) != visibility {
+			continue
+		}
+		if query.Get("owned") == "true" && project.CreatorID != user.ID {
+			continue
+		}
+		if archived := query.Get("archived"); archived != "" && strconv.FormatBool(project.Archived) != archived {
+			continue
+		}
+		projects = append(projects, project)
+	}
+	writePage(w, r, projects)
+}
+
+func (s *Server) getProject(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	writeJson(w, http.StatusOK, project)
+}
+
+func (s *Server) createProject(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	body := readBody(r)
+	name, hasName := stringValue(body, "name")
+	path, hasPath := stringValue(body, "path")
+	if !hasName && !hasPath {
+		writeError(w, http.StatusBadRequest, "name is missing, path is missing, at least one parameter must be provided")
+		return
+	}
+	if !hasName {
+		name = path
+	}
+	if !hasPath {
+		path = strings.ToLower(strings.Replace(name, " ", "-", -1))
+	}
+	var group *gitlab.Group
+	if namespaceId, ok := stringValue(body, "namespace_id"); ok {
+		if group = s.findGroup(namespaceId); group == nil {
+			writeError(w, http.StatusNotFound, "404 Namespace Not Found")
+			return
+		}
+	}
+	user := s.currentUser(r)
+	namespacePath := user.Username
+	if group != nil {
+		namespacePath = group.FullPath
+	}
+	if s.findProject(namespacePath+"/"+path) != nil {
+		writeError(w, http.StatusBadRequest, "Failed to save project {:path=>[\"has already been taken\"]}")
+		return
+	}
+	project := s.addProject(name, path, group, user)
+	applyProjectAttributes(project, body)
+	writeJson(w, http.StatusCreated, project)
+}
+
+func (s *Server) editProject(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	body := readBody(r)
+	if name, ok := stringValue(body, "name"); ok {
+		project.Name = name
+	}
+	if path, ok := stringValue(body, "path"); ok {
+		project.Path = path
+	}
+	applyProjectAttributes(project, body)
+	s.updateProjectPaths(project)
+	if content, name, ok := readFile(r, "avatar"); ok {
+		project.AvatarURL = s.URL + "/uploads/project/avatar/" + strconv.Itoa(project.ID) + "/" + name
+		s.addUpload(project, project.AvatarURL, content)
+	}
+	writeJson(w, http.StatusOK, project)
+}
+
+func (s *Server) uploadFile(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	content, name, ok := readFile(r, "file")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "file is missing")
+		return
+	}
+	url := "/uploads/" + strconv.Itoa(s.nextId()) + "/" + name
+	s.addUpload(project, url, content)
+	writeJson(w, http.StatusCreated, &gitlab.ProjectFile{Alt: name, URL: url, Markdown: "[" + name + "](" + url + ")"})
+}
+
+func (s *Server) addUpload(project *gitlab.Project, url string, content []byte) {
+	if s.uploads[project.ID] == nil {
+		s.uploads[project.ID] = map[string][]byte{}
+	}
+	s.uploads[project.ID][url] = content
+}
+
+// Upload returns the content of a file uploaded to the project, url is the URL of the upload or the avatar
+func (s *Server) Upload(project *gitlab.Project, url string) []byte {
+	return s.uploads[project.ID][url]
+}
+
+func applyProjectAttributes(project *gitlab.Project, body map[string]interface{}) {
+	if value, ok := stringValue(body, "description"); ok {
+		project.Description = value
+	}
+	if value, ok := stringValue(body, "default_branch"); ok {
+		project.DefaultBranch = value
+	}
+	if value, ok := stringValue(body, "visibility"); ok {
+		project.Visibility = gitlab.VisibilityValue(value)
+		project.Public = value == string(gitlab.PublicVisibility)
+	}
+	if value, ok := boolValue(body, "issues_enabled"); ok {
+		project.IssuesEnabled = value
+	}
+	if value, ok := boolValue(body, "merge_requests_enabled"); ok {
+		project.MergeRequestsEnabled = value
+	}
+	if value, ok := boolValue(body, "jobs_enabled"); ok {
+		project.JobsEnabled = value
+	}
+	if value, ok := boolValue(body, "wiki_enabled"); ok {
+		project.WikiEnabled = value
+	}
+	if value, ok := boolValue(body, "snippets_enabled"); ok {
+		project.SnippetsEnabled = value
+	}
+	if value, ok := boolValue(body, "lfs_enabled"); ok {
+		project.LFSEnabled = value
+	}
+	if value, ok := boolValue(body, "request_access_enabled"); ok {
+		project.RequestAccessEnabled = value
+	}
+	if value, ok := boolValue(body, "only_allow_merge_if_pipeline_succeeds"); ok {
+		project.OnlyAllowMergeIfPipelineSucceeds = value
+	}
+	if value, ok := boolValue(body, "only_allow_merge_if_all_discussions_are_resolved"); ok {
+		project.OnlyAllowMergeIfAllDiscussionsAreResolved = value
+	}
+}
+
+func (s *Server) deleteProject(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	s.removeProject(project)
+	writeJson(w, http.StatusAccepted, map[string]string{"message": "202 Accepted"})
+}
+
+func (s *Server) setArchived(archived bool) projectHandler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+		project.Archived = archived
+		writeJson(w, http.StatusCreated, project)
+	}
+}
+
+func (s *Server) listProjectMembers(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	query := r.URL.Query().Get("query")
+	members := []*gitlab.GroupMember{}
+	for _, member := range s.projectMembers[project.ID] {
+		if query == "" || strings.Contains(member.Username+member.Name, query) {
+			members = append(members, member)
+		}
+	}
+	writePage(w, r, members)
+}
+
+func (s *Server) getProjectMember(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	member := findGroupMember(s.projectMembers[project.ID], params["user"])
+	if member == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	writeJson(w, http.StatusOK, member)
+}
+
+func (s *Server) addProjectMember(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	body := readBody(r)
+	if missing(w, body, "user_id", "access_level") {
+		return
+	}
+	userId, _ := stringValue(body, "user_id")
+	user := s.findUser(userId)
+	if user == nil {
+		writeError(w, http.StatusNotFound, "404 User Not Found")
+		return
+	}
+	if findGroupMember(s.projectMembers[project.ID], strconv.Itoa(user.ID)) != nil {
+		writeError(w, http.StatusConflict, "Member already exists")
+		return
+	}
+	accessLevel, _ := intValue(body, "access_level")
+	member := newGroupMember(user, gitlab.AccessLevelValue(accessLevel))
+	if expiresAt, ok := expiresAtValue(body); ok {
+		member.ExpiresAt = expiresAt
+	}
+	s.projectMembers[project.ID] = append(s.projectMembers[project.ID], member)
+	writeJson(w, http.StatusCreated, member)
+}
+
+func (s *Server) editProjectMember(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	member := findGroupMember(s.projectMembers[project.ID], params["user"])
+	if member == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	body := readBody(r)
+	if accessLevel, ok := intValue(body, "access_level"); ok {
+		member.AccessLevel = gitlab.AccessLevelValue(accessLevel)
+	}
+	if expiresAt, ok := expiresAtValue(body); ok {
+		member.ExpiresAt = expiresAt
+	}
+	writeJson(w, http.StatusOK, member)
+}
+
+func (s *Server) deleteProjectMember(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	member := findGroupMember(s.projectMembers[project.ID], params["user"])
+	if member == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	s.projectMembers[project.ID] = removeGroupMember(s.projectMembers[project.ID], member.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func findHook(hooks []*gitlab.ProjectHook, hookId string) *gitlab.ProjectHook {
+	for _, hook := range hooks {
+		if strconv.Itoa(hook.ID) == hookId {
+			return hook
+		}
+	}
+	return nil
+}
+
+func (s *Server) listProjectHooks(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	hooks := s.hooks[project.ID]
+	if hooks == nil {
+		hooks = []*gitlab.ProjectHook{}
+	}
+	writePage(w, r, hooks)
+}
+
+func (s *Server) getProjectHook(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	hook := findHook(s.hooks[project.ID], params["hook"])
+	if hook == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	writeJson(w, http.StatusOK, hook)
+}
+
+func (s *Server) addProjectHook(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	body := readBody(r)
+	if missing(w, body, "url") {
+		return
+	}
+	hook := &gitlab.ProjectHook{ID: s.nextId(), ProjectID: project.ID, PushEvents: true, EnableSSLVerification: true, CreatedAt: now()}
+	applyHookAttributes(hook, body)
+	s.hooks[project.ID] = append(s.hooks[project.ID], hook)
+	writeJson(w, http.StatusCreated, hook)
+}
+
+func (s *Server) editProjectHook(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	hook := findHook(s.hooks[project.ID], params["hook"])
+	if hook == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	applyHookAttributes(hook, readBody(r))
+	writeJson(w, http.StatusOK, hook)
+}
+
+func applyHookAttributes(hook *gitlab.ProjectHook, body map[string]interface{}) {
+	if value, ok := stringValue(body, "url"); ok {
+		hook.URL = value
+	}
+	events := map[string]*bool{
+		"push_events":             &hook.PushEvents,
+		"issues_events":           &hook.IssuesEvents,
+		"merge_requests_events":   &hook.MergeRequestsEvents,
+		"tag_push_events":         &hook.TagPushEvents,
+		"note_events":             &hook.NoteEvents,
+		"job_events":              &hook.JobEvents,
+		"pipeline_events":         &hook.PipelineEvents,
+		"wiki_page_events":        &hook.WikiPageEvents,
+		"enable_ssl_verification": &hook.EnableSSLVerification,
+	}
+	for key, field := range events {
+		if value, ok := boolValue(body, key); ok {
+			*field = value
+		}
+	}
+}
+
+func (s *Server) deleteProjectHook(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	hook := findHook(s.hooks[project.ID], params["hook"])
+	if hook == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	var hooks []*gitlab.ProjectHook
+	for _, h := range s.hooks[project.ID] {
+		if h != hook {
+			hooks = append(hooks, h)
+		}
+	}
+	s.hooks[project.ID] = hooks
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,376 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func (s *Server) registerRepositoryRoutes() {
+	s.handle("GET", "/projects/:project/repository/tree", s.withProject(s.listTree))
+	s.handle("GET", "/projects/:project/repository/files/:file", s.withProject(s.getFile))
+	s.handle("GET", "/projects/:project/repository/files/:file/raw", s.withProject(s.getRawFile))
+	s.handle("POST", "/projects/:project/repository/files/:file", s.withProject(s.createFile))
+	s.handle("PUT", "/projects/:project/repository/files/:file", s.withProject(s.updateFile))
+	s.handle("DELETE", "/projects/:project/repository/files/:file", s.withProject(s.deleteFile))
+	s.handle("POST", "/projects/:project/repository/commits", s.withProject(s.createCommit))
+}
+
+// AddFile commits a file to a branch of the project, an existing file is overwritten
+func (s *Server) AddFile(project *gitlab.Project, branch string, path string, content []byte) {
+	s.commit(project, s.commitBranch(project, branch), "Add "+path, project.Owner, func(files map[string][]byte) string {
+		files[path] = content
+		return ""
+	})
+}
+
+// File returns the content of a file on a branch of the project, ok is false if the file does not exist
+func (s *Server) File(project *gitlab.Project, branch string, path string) (content []byte, ok bool) {
+	b := findBranch(s.branches[project.ID], branch)
+	if b == nil {
+		return nil, false
+	}
+	content, ok = s.files[b.Commit.ID][path]
+	return content, ok
+}
+
+// Files returns the paths of all files on a branch of the project, sorted by path
+func (s *Server) Files(project *gitlab.Project, branch string) []string {
+	paths := []string{}
+	if b := findBranch(s.branches[project.ID], branch); b != nil {
+		for p := range s.files[b.Commit.ID] {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// blobId returns the SHA-1 git uses for a blob with the given content
+func blobId(content []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(append([]byte(fmt.Sprintf("blob %d\x00", len(content))), content...)))
+}
+
+// commit creates a commit on branch with the files modified by change, which returns an error message for invalid changes,
+// a branch of an empty repository is created by its first commit
+func (s *Server) commit(project *gitlab.Project, branch *gitlab.Branch, message string, author *gitlab.User, change func(files map[string][]byte) string) (*gitlab.Commit, string) {
+	files := map[string][]byte{}
+	parentIds := []string{}
+	if branch.Commit != nil {
+		for p, content := range s.files[branch.Commit.ID] {
+			files[p] = content
+		}
+		parentIds = append(parentIds, branch.Commit.ID)
+	}
+	if msg := change(files); msg != "" {
+		return nil, msg
+	}
+	sha := fmt.Sprintf("%x", sha1.Sum([]byte(fmt.Sprintf("%d/%s/%d", project.ID, branch.Name, s.nextId()))))
+	commit := &gitlab.Commit{
+		ID:             sha,
+		ShortID:        sha[:8],
+		Title:          strings.SplitN(message, "\n", 2)[0],
+		Message:        message,
+		AuthorName:     author.Name,
+		AuthorEmail:    author.Email,
+		AuthoredDate:   now(),
+		CommitterName:  author.Name,
+		CommitterEmail: author.Email,
+		CommittedDate:  now(),
+		CreatedAt:      now(),
+		ParentIDs:      parentIds,
+	}
+	s.files[commit.ID] = files
+	s.commits[project.ID] = append(s.commits[project.ID], commit)
+	branch.Commit = commit
+	if findBranch(s.branches[project.ID], branch.Name) == nil {
+		s.branches[project.ID] = append(s.branches[project.ID], branch)
+		project.DefaultBranch = branch.Name
+	}
+	return commit, ""
+}
+
+// commitBranch returns the branch to commit to, the first commit to an empty repository creates the branch
+func (s *Server) commitBranch(project *gitlab.Project, name string) *gitlab.Branch {
+	if branch := findBranch(s.branches[project.ID], name); branch != nil {
+		return branch
+	}
+	if len(s.branches[project.ID]) == 0 {
+		return &gitlab.Branch{Name: name}
+	}
+	return nil
+}
+
+// refFiles returns the files of a branch or commit, ref defaults to the default branch of the project
+func (s *Server) refFiles(project *gitlab.Project, ref string) (map[string][]byte, bool) {
+	commit := s.findCommit(project, ref)
+	if commit == nil {
+		return nil, false
+	}
+	return s.files[commit.ID], true
+}
+
+// listTree lists the files and directories below `path`, all levels for `recursive`, directories first
+func (s *Server) listTree(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	query := r.URL.Query()
+	files, ok := s.refFiles(project, query.Get("ref"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "404 Tree Not Found")
+		return
+	}
+	prefix := strings.Trim(query.Get("path"), "/")
+	recursive := query.Get("recursive") == "true"
+	nodes := map[string]*gitlab.TreeNode{}
+	for p, content := range files {
+		if prefix != "" && !strings.HasPrefix(p, prefix+"/") {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(p, prefix+"/"), "/")
+		if prefix == "" {
+			parts = strings.Split(p, "/")
+		}
+		dir := prefix
+		for i, part := range parts {
+			nodePath := path.Join(dir, part)
+			dir = nodePath
+			if i == len(parts)-1 {
+				nodes[nodePath] = &gitlab.TreeNode{ID: blobId(content), Name: part, Type: "blob", Path: nodePath, Mode: "100644"}
+			} else if _, exists := nodes[nodePath]; !exists {
+				nodes[nodePath] = &gitlab.TreeNode{ID: fmt.Sprintf("%x", sha1.Sum([]byte(nodePath))), Name: part, Type: "tree", Path: nodePath, Mode: "040000"}
+			}
+			if !recursive {
+				break
+			}
+		}
+	}
+	if prefix != "" && len(nodes) == 0 {
+		writeError(w, http.StatusNotFound, "404 Tree Not Found")
+		return
+	}
+	tree := []*gitlab.TreeNode{}
+	for _, node := range nodes {
+		tree = append(tree, node)
+	}
+	sort.Slice(tree, func(i, j int) bool {
+		if tree[i].Type != tree[j].Type {
+			return tree[i].Type == "tree"
+		}
+		return tree[i].Path < tree[j].Path
+	})
+	writePage(w, r, tree)
+}
+
+// fileAt returns the content of the file given by the route and the `ref` parameter, a 404 is written if there is none
+func (s *Server) fileAt(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) ([]byte, bool) {
+	ref := r.URL.Query().Get("ref")
+	if ref == "" {
+		writeError(w, http.StatusBadRequest, "ref is missing")
+		return nil, false
+	}
+	files, _ := s.refFiles(project, ref)
+	content, ok := files[params["file"]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "404 File Not Found")
+	}
+	return content, ok
+}
+
+func (s *Server) getFile(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	content, ok := s.fileAt(w, r, params, project)
+	if !ok {
+		return
+	}
+	ref := r.URL.Query().Get("ref")
+	writeJson(w, http.StatusOK, &gitlab.File{
+		FileName: path.Base(params["file"]),
+		FilePath: params["file"],
+		Size:     len(content),
+		Encoding: "base64",
+		Content:  base64.StdEncoding.EncodeToString(content),
+		Ref:      ref,
+		BlobID:   blobId(content),
+		CommitID: s.findCommit(project, ref).ID,
+	})
+}
+
+func (s *Server) getRawFile(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	content, ok := s.fileAt(w, r, params, project)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+// decodeContent returns the `content` of a file or commit action, which is base64 encoded for `encoding` base64
+func decodeContent(body map[string]interface{}) ([]byte, string) {
+	content, _ := stringValue(body, "content")
+	if encoding, _ := stringValue(body, "encoding"); encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, "content is not base64 encoded"
+		}
+		return decoded, ""
+	}
+	return []byte(content), ""
+}
+
+// commitAuthor returns the current user with the name and email overridden by `author_name` and `author_email`
+func (s *Server) commitAuthor(r *http.Request, body map[string]interface{}) *gitlab.User {
+	author := *s.currentUser(r)
+	if name, ok := stringValue(body, "author_name"); ok {
+		author.Name = name
+	}
+	if email, ok := stringValue(body, "author_email"); ok {
+		author.Email = email
+	}
+	return &author
+}
+
+// changeFile commits a change of a single file for the create, update and delete file routes
+func (s *Server) changeFile(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project, status int, change func(body map[string]interface{}, files map[string][]byte, path string) string) {
+	body := readBody(r)
+	if missing(w, body, "branch", "commit_message") {
+		return
+	}
+	name, _ := stringValue(body, "branch")
+	branch := s.commitBranch(project, name)
+	if branch == nil {
+		writeError(w, http.StatusBadRequest, "You can only create or edit files when you are on a branch")
+		return
+	}
+	message, _ := stringValue(body, "commit_message")
+	_, msg := s.commit(project, branch, message, s.commitAuthor(r, body), func(files map[string][]byte) string {
+		return change(body, files, params["file"])
+	})
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	writeJson(w, status, &gitlab.FileInfo{FilePath: params["file"], Branch: branch.Name})
+}
+
+func (s *Server) createFile(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	s.changeFile(w, r, params, project, http.StatusCreated, func(body map[string]interface{}, files map[string][]byte, path string) string {
+		return applyAction(files, "create", path, body)
+	})
+}
+
+func (s *Server) updateFile(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	s.changeFile(w, r, params, project, http.StatusOK, func(body map[string]interface{}, files map[string][]byte, path string) string {
+		// the fake compares the last commit with the head of the branch instead of the last commit of the file
+		if lastCommitId, ok := stringValue(body, "last_commit_id"); ok {
+			branch, _ := stringValue(body, "branch")
+			if b := findBranch(s.branches[project.ID], branch); b == nil || lastCommitId != b.Commit.ID {
+				return "You are attempting to update a file that has changed since you started editing it."
+			}
+		}
+		return applyAction(files, "update", path, body)
+	})
+}
+
+func (s *Server) deleteFile(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	s.changeFile(w, r, params, project, http.StatusNoContent, func(body map[string]interface{}, files map[string][]byte, path string) string {
+		return applyAction(files, "delete", path, body)
+	})
+}
+
+// applyAction creates, updates or deletes a file, it returns Gitlab's error message if the action is not possible
+func applyAction(files map[string][]byte, action string, path string, body map[string]interface{}) string {
+	_, exists := files[path]
+	switch action {
+	case "create", "update":
+		if action == "create" && exists {
+			return "A file with this name already exists"
+		}
+		if action == "update" && !exists {
+			return "A file with this name doesn't exist"
+		}
+		if _, ok := stringValue(body, "content"); !ok {
+			return "content is missing"
+		}
+		content, msg := decodeContent(body)
+		if msg != "" {
+			return msg
+		}
+		files[path] = content
+	case "delete":
+		if !exists {
+			return "A file with this name doesn't exist"
+		}
+		delete(files, path)
+	default:
+		return "action does not have a valid value"
+	}
+	return ""
+}
+
+// createCommit applies all actions in a single commit, nothing is committed if one of the actions fails
+func (s *Server) createCommit(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	body := readBody(r)
+	if missing(w, body, "branch", "commit_message") {
+		return
+	}
+	actions, ok := body["actions"].([]interface{})
+	if !ok || len(actions) == 0 {
+		writeError(w, http.StatusBadRequest, "actions is missing")
+		return
+	}
+	name, _ := stringValue(body, "branch")
+	branch := s.commitBranch(project, name)
+	if branch == nil {
+		writeError(w, http.StatusBadRequest, "You can only create or edit files when you are on a branch")
+		return
+	}
+	message, _ := stringValue(body, "commit_message")
+	commit, msg := s.commit(project, branch, message, s.commitAuthor(r, body), func(files map[string][]byte) string {
+		for _, a := range actions {
+			action, _ := a.(map[string]interface{})
+			actionName, _ := stringValue(action, "action")
+			filePath, ok := stringValue(action, "file_path")
+			if !ok {
+				return "file_path is missing"
+			}
+			if msg := applyAction(files, actionName, filePath, action); msg != "" {
+				return msg
+			}
+		}
+		return ""
+	})
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	writeJson(w, http.StatusCreated, commit)
+}
@@ -0,0 +1,433 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package fake provides an in-memory Gitlab v4 API server for testing golab without a Gitlab instance.
+//
+// The server keeps state for users, groups, group members, projects, project members, branches, commits with their
+// comments and statuses, tags, repository files, labels, milestones, wiki pages, hooks, merge requests and uploads,
+// supports Gitlab's pagination parameters and headers and can be told to fail requests with arbitrary status codes.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// Token is the private token of the root user that is created with every server
+const Token = "fake-root-token"
+
+// RootPassword is the password of the root user, e.g. for `golab login`
+const RootPassword = "12341234"
+
+// Server is a fake Gitlab server, its URL is the base URL of the Gitlab instance (without /api/v4)
+type Server struct {
+	URL string
+
+	server   *httptest.Server
+	routes   []route
+	mu       sync.Mutex
+	lastId   int
+	failures []*failure
+
+	users          map[int]*gitlab.User
+	passwords      map[int]string
+	tokens         map[string]int
+	refreshTokens  map[string]int
+	groups         map[int]*gitlab.Group
+	groupMembers   map[int][]*gitlab.GroupMember
+	projects       map[int]*gitlab.Project
+	projectMembers map[int][]*gitlab.GroupMember // the API returns the same members for groups and projects, including expires_at
+	branches       map[int][]*gitlab.Branch
+	commits        map[int][]*gitlab.Commit // all commits of a project in the order they were created
+	commitComments map[string][]*gitlab.CommitComment
+	commitStatuses map[string][]*gitlab.CommitStatus
+	tags           map[int][]*gitlab.Tag
+	labels         map[int][]*gitlab.Label
+	milestones     map[int][]*gitlab.Milestone
+	wikis          map[int][]*gitlab.Wiki
+	files          map[string]map[string][]byte // the repository files of a commit by commit ID and path
+	hooks          map[int][]*gitlab.ProjectHook
+	mergeRequests  map[int][]*gitlab.MergeRequest
+	uploads        map[int]map[string][]byte
+}
+
+// a handler processes a request, params holds the values of the `:name` segments of the route
+type handler func(w http.ResponseWriter, r *http.Request, params map[string]string)
+
+type route struct {
+	method   string
+	segments []string
+	handle   handler
+}
+
+type failure struct {
+	method string
+	path   string
+	status int
+	times  int
+}
+
+// NewServer starts a fake Gitlab server with a root user (an admin) that can be accessed with Token
+func NewServer() *Server {
+	s := &Server{
+		users:          map[int]*gitlab.User{},
+		passwords:      map[int]string{},
+		tokens:         map[string]int{},
+		refreshTokens:  map[string]int{},
+		groups:         map[int]*gitlab.Group{},
+		groupMembers:   map[int][]*gitlab.GroupMember{},
+		projects:       map[int]*gitlab.Project{},
+		projectMembers: map[int][]*gitlab.GroupMember{},
+		branches:       map[int][]*gitlab.Branch{},
+		commits:        map[int][]*gitlab.Commit{},
+		commitComments: map[string][]*gitlab.CommitComment{},
+		commitStatuses: map[string][]*gitlab.CommitStatus{},
+		tags:           map[int][]*gitlab.Tag{},
+		labels:         map[int][]*gitlab.Label{},
+		milestones:     map[int][]*gitlab.Milestone{},
+		wikis:          map[int][]*gitlab.Wiki{},
+		files:          map[string]map[string][]byte{},
+		hooks:          map[int][]*gitlab.ProjectHook{},
+		mergeRequests:  map[int][]*gitlab.MergeRequest{},
+		uploads:        map[int]map[string][]byte{},
+	}
+	s.registerRoutes()
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+
+	root := s.AddUser("root", "admin@example.com", "Administrator", RootPassword)
+	root.IsAdmin = true
+	s.tokens[Token] = root.ID
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Fail lets the next `times` requests with the given method and path fail with status, times < 1 fails all requests.
+// The path is relative to /api/v4, e.g. `/users`, an empty method matches all methods.
+func (s *Server) Fail(method string, path string, status int, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure{method: method, path: path, status: status, times: times})
+}
+
+func (s *Server) registerRoutes() {
+	s.registerUserRoutes()
+	s.registerGroupRoutes()
+	s.registerProjectRoutes()
+	s.registerBranchRoutes()
+	s.registerRepositoryRoutes()
+	s.registerCommitRoutes()
+	s.registerTagRoutes()
+	s.registerLabelRoutes()
+	s.registerWikiRoutes()
+	s.registerMergeRequestRoutes()
+	s.registerOAuthRoutes()
+}
+
+func (s *Server) handle(method string, pattern string, h handler) {
+	s.routes = append(s.routes, route{method: method, segments: strings.Split(strings.Trim(pattern, "/"), "/"), handle: h})
+}
+
+// ServeHTTP authenticates requests, applies failures and dispatches them to the matching route
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4")
+	if f := s.failureFor(r.Method, path); f != nil {
+		writeError(w, f.status, http.StatusText(f.status))
+		return
+	}
+	if !(r.Method == "POST" && (path == "/session" || path == "/oauth/token")) && s.currentUser(r) == nil {
+		writeError(w, http.StatusUnauthorized, "401 Unauthorized")
+		return
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segments[i] = unescaped
+		}
+	}
+	methodAllowed := true
+	for _, route := range s.routes {
+		params, ok := route.match(segments)
+		if !ok {
+			continue
+		}
+		if route.method != r.Method {
+			methodAllowed = false
+			continue
+		}
+		route.handle(w, r, params)
+		return
+	}
+	if !methodAllowed {
+		writeError(w, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+		return
+	}
+	writeError(w, http.StatusNotFound, "404 Not Found")
+}
+
+func (r route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, segment := range r.segments {
+		if strings.HasPrefix(segment, ":") {
+			params[segment[1:]] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (s *Server) failureFor(method string, path string) *failure {
+	for i, f := range s.failures {
+		if (f.method == "" || f.method == method) && f.path == path {
+			if f.times > 0 {
+				f.times--
+				if f.times == 0 {
+					s.failures = append(s.failures[:i], s.failures[i+1:]...)
+				}
+			}
+			return f
+		}
+	}
+	return nil
+}
+
+// currentUser returns the user of the token given in the PRIVATE-TOKEN header or as Bearer token
+func (s *Server) currentUser(r *http.Request) *gitlab.User {
+	token := r.Header.Get("PRIVATE-TOKEN")
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if token == "" {
+		token = r.URL.Query().Get("private_token")
+	}
+	if id, ok := s.tokens[token]; ok {
+		return s.users[id]
+	}
+	return nil
+}
+
+func (s *Server) nextId() int {
+	s.lastId++
+	return s.lastId
+}
+
+func now() *time.Time {
+	t := time.Now().UTC().Truncate(time.Second)
+	return &t
+}
+
+// writePage writes the requested page of a list (a slice) together with Gitlab's pagination headers
+func writePage(w http.ResponseWriter, r *http.Request, list interface{}) {
+	all := toInterfaces(list)
+	page, perPage := intParam(r, "page", 1), intParam(r, "per_page", 20)
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 20
+	}
+	if perPage > 100 {
+		// Gitlab returns at most 100 items per page
+		perPage = 100
+	}
+	totalPages := (len(all) + perPage - 1) / perPage
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	start, end := (page-1)*perPage, page*perPage
+	if start > len(all) {
+		start = len(all)
+	}
+	if end > len(all) {
+		end = len(all)
+	}
+
+	header := w.Header()
+	header.Set("X-Total", strconv.Itoa(len(all)))
+	header.Set("X-Total-Pages", strconv.Itoa(totalPages))
+	header.Set("X-Per-Page", strconv.Itoa(perPage))
+	header.Set("X-Page", strconv.Itoa(page))
+	var links []string
+	if page < totalPages {
+		header.Set("X-Next-Page", strconv.Itoa(page+1))
+		links = append(links, pageLink(r, page+1, perPage, "next"))
+	}
+	if page > 1 {
+		header.Set("X-Prev-Page", strconv.Itoa(page-1))
+		links = append(links, pageLink(r, page-1, perPage, "prev"))
+	}
+	links = append(links, pageLink(r, 1, perPage, "first"), pageLink(r, totalPages, perPage, "last"))
+	header.Set("Link", strings.Join(links, ", "))
+
+	writeJson(w, http.StatusOK, all[start:end])
+}
+
+func pageLink(r *http.Request, page int, perPage int, rel string) string {
+	query := r.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(perPage))
+	return fmt.Sprintf(`<http://%s%s?%s>; rel="%s"`, r.Host, r.URL.EscapedPath(), query.Encode(), rel)
+}
+
+func toInterfaces(list interface{}) []interface{} {
+	content, _ := json.Marshal(list)
+	result := []interface{}{}
+	json.Unmarshal(content, &result)
+	return result
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJson(w, status, map[string]string{"message": message})
+}
+
+// maxUploadSize is the size of multipart requests that is kept in memory
+const maxUploadSize = 10 << 20
+
+// readBody decodes the JSON body of a request, go-gitlab sends all parameters of POST and PUT requests as JSON,
+// OAuth token requests are form encoded and uploads are multipart forms
+func readBody(r *http.Request) map[string]interface{} {
+	body := map[string]interface{}{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		r.ParseForm()
+		for key, values := range r.PostForm {
+			body[key] = values[0]
+		}
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.ParseMultipartForm(maxUploadSize)
+		for key, values := range r.MultipartForm.Value {
+			body[key] = values[0]
+		}
+	} else {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	for key, values := range r.URL.Query() {
+		if _, ok := body[key]; !ok && len(values) > 0 {
+			body[key] = values[0]
+		}
+	}
+	return body
+}
+
+// readFile returns the content and the name of a file of a multipart request
+func readFile(r *http.Request, field string) ([]byte, string, bool) {
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		return nil, "", false
+	}
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, "", false
+	}
+	return content, header.Filename, true
+}
+
+func intParam(r *http.Request, name string, defaultValue int) int {
+	if value, err := strconv.Atoi(r.URL.Query().Get(name)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// stringValue returns a parameter of the body as string, ok is false if it was not given
+func stringValue(body map[string]interface{}, key string) (string, bool) {
+	switch value := body[key].(type) {
+	case string:
+		return value, true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value), true
+	}
+	return "", false
+}
+
+func intValue(body map[string]interface{}, key string) (int, bool) {
+	value, ok := stringValue(body, key)
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(value)
+	return i, err == nil
+}
+
+func boolValue(body map[string]interface{}, key string) (bool, bool) {
+	value, ok := stringValue(body, key)
+	if !ok {
+		return false, false
+	}
+	b, err := strconv.ParseBool(value)
+	return b, err == nil
+}
+
+// missing writes a 400 error, if one of the given parameters is missing in body
+func missing(w http.ResponseWriter, body map[string]interface{}, keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := stringValue(body, key); !ok {
+			writeError(w, http.StatusBadRequest, key+" is missing")
+			return true
+		}
+	}
+	return false
+}
+
+func sortedIds(ids []int) []int {
+	sort.Ints(ids)
+	return ids
+}
+
+func parseIsoTime(value string) *gitlab.ISOTime {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil
+	}
+	isoTime := gitlab.ISOTime(t)
+	return &isoTime
+}
@@ -0,0 +1,196 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("fake Gitlab server", func() {
+
+	var (
+		server *Server
+		client *gitlab.Client
+	)
+
+	BeforeEach(func() {
+		server = NewServer()
+		client = gitlab.NewClient(nil, Token)
+		client.SetBaseURL(server.URL + "/api/v4")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("rejects requests without a valid token", func() {
+		client = gitlab.NewClient(nil, "invalid")
+		client.SetBaseURL(server.URL + "/api/v4")
+		_, resp, err := client.Users.CurrentUser()
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("returns a token for the root user on login", func() {
+		login, password := "root", RootPassword
+		session, _, err := client.Session.GetSession(&gitlab.GetSessionOptions{Login: &login, Password: &password})
+		Expect(err).To(BeNil())
+		client = gitlab.NewClient(nil, session.PrivateToken)
+		client.SetBaseURL(server.URL + "/api/v4")
+		user, _, err := client.Users.CurrentUser()
+		Expect(err).To(BeNil())
+		Expect(user.Username).To(Equal("root"))
+		Expect(user.IsAdmin).To(BeTrue())
+	})
+
+	It("keeps state for users", func() {
+		user, _, err := client.Users.CreateUser(&gitlab.CreateUserOptions{
+			Username: gitlab.String("jdoe"), Email: gitlab.String("jdoe@example.com"), Name: gitlab.String("John Doe"),
+		})
+		Expect(err).To(BeNil())
+
+		_, resp, err := client.Users.CreateUser(&gitlab.CreateUserOptions{
+			Username: gitlab.String("jdoe"), Email: gitlab.String("other@example.com"), Name: gitlab.String("John Doe"),
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+		users, _, err := client.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.String("jdoe")})
+		Expect(err).To(BeNil())
+		Expect(users).To(HaveLen(1))
+		Expect(users[0].ID).To(Equal(user.ID))
+
+		_, err = client.Users.DeleteUser(user.ID)
+		Expect(err).To(BeNil())
+		_, resp, err = client.Users.GetUser(user.ID)
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("paginates lists and sets Gitlab's pagination headers", func() {
+		for i := 0; i < 4; i++ {
+			server.AddUser(string(rune('a'+i)), string(rune('a'+i))+"@example.com", "User", "")
+		}
+		users, resp, err := client.Users.ListUsers(&gitlab.ListUsersOptions{ListOptions: gitlab.ListOptions{Page: 2, PerPage: 2}})
+		Expect(err).To(BeNil())
+		Expect(users).To(HaveLen(2))
+		Expect(users[0].Username).To(Equal("b"))
+		Expect(resp.Header.Get("X-Total")).To(Equal("5"))
+		Expect(resp.Header.Get("X-Total-Pages")).To(Equal("3"))
+		Expect(resp.Header.Get("X-Page")).To(Equal("2"))
+		Expect(resp.NextPage).To(Equal(3))
+		Expect(resp.PrevPage).To(Equal(1))
+		Expect(resp.LastPage).To(Equal(3))
+	})
+
+	It("returns at most 100 items per page", func() {
+		for i := 0; i < 120; i++ {
+			server.AddUser(fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@example.com", i), "User", "")
+		}
+		users, resp, err := client.Users.ListUsers(&gitlab.ListUsersOptions{ListOptions: gitlab.ListOptions{PerPage: 500}})
+		Expect(err).To(BeNil())
+		Expect(users).To(HaveLen(100))
+		Expect(resp.Header.Get("X-Per-Page")).To(Equal("100"))
+		Expect(resp.NextPage).To(Equal(2))
+	})
+
+	It("keeps state for groups and group members", func() {
+		group, _, err := client.Groups.CreateGroup(&gitlab.CreateGroupOptions{Name: gitlab.String("Group"), Path: gitlab.String("group")})
+		Expect(err).To(BeNil())
+		user := server.AddUser("jdoe", "jdoe@example.com", "John Doe", "")
+
+		_, _, err = client.GroupMembers.AddGroupMember("group", &gitlab.AddGroupMemberOptions{
+			UserID: &user.ID, AccessLevel: gitlab.AccessLevel(gitlab.DeveloperPermissions), ExpiresAt: gitlab.String("2030-01-31"),
+		})
+		Expect(err).To(BeNil())
+		_, resp, err := client.GroupMembers.AddGroupMember(group.ID, &gitlab.AddGroupMemberOptions{
+			UserID: &user.ID, AccessLevel: gitlab.AccessLevel(gitlab.DeveloperPermissions),
+		})
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+		members, _, err := client.Groups.ListGroupMembers(group.ID, &gitlab.ListGroupMembersOptions{})
+		Expect(err).To(BeNil())
+		Expect(members).To(HaveLen(2))
+		Expect(members[1].Username).To(Equal("jdoe"))
+		Expect(members[1].AccessLevel).To(Equal(gitlab.DeveloperPermissions))
+		Expect(time.Time(*members[1].ExpiresAt).Format("2006-01-02")).To(Equal("2030-01-31"))
+	})
+
+	It("keeps state for projects, branches, hooks and merge requests", func() {
+		group := server.AddGroup("Group", "group", nil)
+		project, _, err := client.Projects.CreateProject(&gitlab.CreateProjectOptions{Name: gitlab.String("My Project"), NamespaceID: &group.ID})
+		Expect(err).To(BeNil())
+		Expect(project.PathWithNamespace).To(Equal("group/my-project"))
+
+		// projects are created with an empty repository, the first commit creates the default branch
+		branches, _, err := client.Branches.ListBranches(project.ID, &gitlab.ListBranchesOptions{})
+		Expect(err).To(BeNil())
+		Expect(branches).To(BeEmpty())
+		_, _, err = client.RepositoryFiles.CreateFile(project.ID, "README.md", &gitlab.CreateFileOptions{
+			Branch: gitlab.String("master"), Content: gitlab.String("# My Project"), CommitMessage: gitlab.String("Initial commit"),
+		})
+		Expect(err).To(BeNil())
+		project, _, err = client.Projects.GetProject(project.ID)
+		Expect(err).To(BeNil())
+		Expect(project.DefaultBranch).To(Equal("master"))
+
+		_, _, err = client.Branches.CreateBranch("group/my-project", &gitlab.CreateBranchOptions{Branch: gitlab.String("feature"), Ref: gitlab.String("master")})
+		Expect(err).To(BeNil())
+
+		hook, _, err := client.Projects.AddProjectHook(project.ID, &gitlab.AddProjectHookOptions{URL: gitlab.String("http://example.com/hook")})
+		Expect(err).To(BeNil())
+		Expect(hook.PushEvents).To(BeTrue())
+
+		mr, _, err := client.MergeRequests.CreateMergeRequest(project.ID, &gitlab.CreateMergeRequestOptions{
+			Title: gitlab.String("Feature"), SourceBranch: gitlab.String("feature"), TargetBranch: gitlab.String("master"),
+		})
+		Expect(err).To(BeNil())
+		Expect(mr.IID).To(Equal(1))
+
+		mr, _, err = client.MergeRequests.AcceptMergeRequest(project.ID, mr.IID, &gitlab.AcceptMergeRequestOptions{})
+		Expect(err).To(BeNil())
+		Expect(mr.State).To(Equal("merged"))
+
+		_, err = client.Branches.DeleteMergedBranches(project.ID)
+		Expect(err).To(BeNil())
+		branches, _, err = client.Branches.ListBranches(project.ID, &gitlab.ListBranchesOptions{})
+		Expect(err).To(BeNil())
+		Expect(branches).To(HaveLen(1))
+		Expect(branches[0].Name).To(Equal("master"))
+	})
+
+	It("fails requests as configured", func() {
+		server.Fail("GET", "/user", http.StatusInternalServerError, 1)
+
+		_, resp, err := client.Users.CurrentUser()
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+
+		_, _, err = client.Users.CurrentUser()
+		Expect(err).To(BeNil())
+	})
+
+})
@@ -0,0 +1,104 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func (s *Server) registerTagRoutes() {
+	s.handle("GET", "/projects/:project/repository/tags", s.withProject(s.listTags))
+	s.handle("POST", "/projects/:project/repository/tags", s.withProject(s.createTag))
+	s.handle("GET", "/projects/:project/repository/tags/:tag", s.withProject(s.getTag))
+	s.handle("DELETE", "/projects/:project/repository/tags/:tag", s.withProject(s.deleteTag))
+}
+
+func findTag(tags []*gitlab.Tag, name string) *gitlab.Tag {
+	for _, tag := range tags {
+		if tag.Name == name {
+			return tag
+		}
+	}
+	return nil
+}
+
+// listTags lists the tags of a project in reverse alphabetical order
+func (s *Server) listTags(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	tags := append([]*gitlab.Tag{}, s.tags[project.ID]...)
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name > tags[j].Name
+	})
+	writePage(w, r, tags)
+}
+
+func (s *Server) getTag(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	tag := findTag(s.tags[project.ID], params["tag"])
+	if tag == nil {
+		writeError(w, http.StatusNotFound, "404 Tag Not Found")
+		return
+	}
+	writeJson(w, http.StatusOK, tag)
+}
+
+// createTag tags the commit given by ref, which is a branch, a tag or a commit ID
+func (s *Server) createTag(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	body := readBody(r)
+	if missing(w, body, "tag_name", "ref") {
+		return
+	}
+	name, _ := stringValue(body, "tag_name")
+	ref, _ := stringValue(body, "ref")
+	if findTag(s.tags[project.ID], name) != nil {
+		writeError(w, http.StatusBadRequest, "Tag "+name+" already exists")
+		return
+	}
+	commit := s.findCommit(project, ref)
+	if commit == nil {
+		writeError(w, http.StatusBadRequest, "Target "+ref+" is invalid")
+		return
+	}
+	tag := &gitlab.Tag{Name: name, Commit: commit}
+	tag.Message, _ = stringValue(body, "message")
+	if description, ok := stringValue(body, "release_description"); ok {
+		tag.Release.TagName = name
+		tag.Release.Description = description
+	}
+	s.tags[project.ID] = append(s.tags[project.ID], tag)
+	writeJson(w, http.StatusCreated, tag)
+}
+
+func (s *Server) deleteTag(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	if findTag(s.tags[project.ID], params["tag"]) == nil {
+		writeError(w, http.StatusNotFound, "404 Tag Not Found")
+		return
+	}
+	var tags []*gitlab.Tag
+	for _, tag := range s.tags[project.ID] {
+		if tag.Name != params["tag"] {
+			tags = append(tags, tag)
+		}
+	}
+	s.tags[project.ID] = tags
+	w.WriteHeader(http.StatusNoContent)
+}
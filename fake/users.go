@@ -0,0 +1,223 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func (s *Server) registerUserRoutes() {
+	s.handle("POST", "/session", s.createSession)
+	s.handle("GET", "/user", s.getCurrentUser)
+	s.handle("GET", "/users", s.listUsers)
+	s.handle("POST", "/users", s.createUser)
+	s.handle("GET", "/users/:user", s.getUser)
+	s.handle("PUT", "/users/:user", s.modifyUser)
+	s.handle("DELETE", "/users/:user", s.deleteUser)
+	s.handle("POST", "/users/:user/block", s.setUserState("blocked"))
+	s.handle("POST", "/users/:user/unblock", s.setUserState("active"))
+}
+
+// AddUser creates a user, password is used for `golab login`
+func (s *Server) AddUser(username string, email string, name string, password string) *gitlab.User {
+	user := &gitlab.User{
+		ID:        s.nextId(),
+		Username:  username,
+		Email:     email,
+		Name:      name,
+		State:     "active",
+		CreatedAt: now(),
+	}
+	s.users[user.ID] = user
+	s.passwords[user.ID] = password
+	return user
+}
+
+// Users returns all users ordered by ID
+func (s *Server) Users() []*gitlab.User {
+	var ids []int
+	for id := range s.users {
+		ids = append(ids, id)
+	}
+	users := []*gitlab.User{}
+	for _, id := range sortedIds(ids) {
+		users = append(users, s.users[id])
+	}
+	return users
+}
+
+// findUser returns the user with the given ID or username
+func (s *Server) findUser(idOrUsername string) *gitlab.User {
+	if id, err := strconv.Atoi(idOrUsername); err == nil {
+		return s.users[id]
+	}
+	for _, user := range s.users {
+		if user.Username == idOrUsername {
+			return user
+		}
+	}
+	return nil
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	body := readBody(r)
+	login, _ := stringValue(body, "login")
+	password, _ := stringValue(body, "password")
+	for _, user := range s.users {
+		if (user.Username == login || user.Email == login) && s.passwords[user.ID] == password {
+			token := "token-" + strconv.Itoa(user.ID)
+			s.tokens[token] = user.ID
+			writeJson(w, http.StatusCreated, &gitlab.Session{ID: user.ID, Username: user.Username, Email: user.Email, Name: user.Name, PrivateToken: token})
+			return
+		}
+	}
+	writeError(w, http.StatusUnauthorized, "401 Unauthorized")
+}
+
+func (s *Server) getCurrentUser(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	writeJson(w, http.StatusOK, s.currentUser(r))
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	query := r.URL.Query()
+	users := []*gitlab.User{}
+	for _, user := range s.Users() {
+		if username := query.Get("username"); username != "" && user.Username != username {
+			continue
+		}
+		if search := query.Get("search"); search != "" && !strings.Contains(user.Username+user.Email+user.Name, search) {
+			continue
+		}
+		if query.Get("active") == "true" && user.State != "active" || query.Get("blocked") == "true" && user.State != "blocked" {
+			continue
+		}
+		users = append(users, user)
+	}
+	writePage(w, r, users)
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := s.findUser(params["user"])
+	if user == nil {
+		writeError(w, http.StatusNotFound, "404 User Not Found")
+		return
+	}
+	writeJson(w, http.StatusOK, user)
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	body := readBody(r)
+	if missing(w, body, "email", "username", "name") {
+		return
+	}
+	username, _ := stringValue(body, "username")
+	email, _ := stringValue(body, "email")
+	for _, user := range s.users {
+		if user.Username == username || user.Email == email {
+			writeError(w, http.StatusConflict, "Email or username has already been taken")
+			return
+		}
+	}
+	name, _ := stringValue(body, "name")
+	password, _ := stringValue(body, "password")
+	user := s.AddUser(username, email, name, password)
+	applyUserAttributes(user, body)
+	writeJson(w, http.StatusCreated, user)
+}
+
+func (s *Server) modifyUser(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := s.findUser(params["user"])
+	if user == nil {
+		writeError(w, http.StatusNotFound, "404 User Not Found")
+		return
+	}
+	body := readBody(r)
+	if password, ok := stringValue(body, "password"); ok {
+		s.passwords[user.ID] = password
+	}
+	applyUserAttributes(user, body)
+	writeJson(w, http.StatusOK, user)
+}
+
+func applyUserAttributes(user *gitlab.User, body map[string]interface{}) {
+	if value, ok := stringValue(body, "email"); ok {
+		user.Email = value
+	}
+	if value, ok := stringValue(body, "username"); ok {
+		user.Username = value
+	}
+	if value, ok := stringValue(body, "name"); ok {
+		user.Name = value
+	}
+	if value, ok := stringValue(body, "bio"); ok {
+		user.Bio = value
+	}
+	if value, ok := stringValue(body, "location"); ok {
+		user.Location = value
+	}
+	if value, ok := stringValue(body, "organization"); ok {
+		user.Organization = value
+	}
+	if value, ok := boolValue(body, "admin"); ok {
+		user.IsAdmin = value
+	}
+	if value, ok := boolValue(body, "can_create_group"); ok {
+		user.CanCreateGroup = value
+	}
+	if value, ok := boolValue(body, "external"); ok {
+		user.External = value
+	}
+	if value, ok := intValue(body, "projects_limit"); ok {
+		user.ProjectsLimit = value
+	}
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := s.findUser(params["user"])
+	if user == nil {
+		writeError(w, http.StatusNotFound, "404 User Not Found")
+		return
+	}
+	delete(s.users, user.ID)
+	for group, members := range s.groupMembers {
+		s.groupMembers[group] = removeGroupMember(members, user.ID)
+	}
+	for project, members := range s.projectMembers {
+		s.projectMembers[project] = removeGroupMember(members, user.ID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) setUserState(state string) handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		user := s.findUser(params["user"])
+		if user == nil {
+			writeError(w, http.StatusNotFound, "404 User Not Found")
+			return
+		}
+		user.State = state
+		w.WriteHeader(http.StatusCreated)
+	}
+}
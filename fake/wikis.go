@@ -0,0 +1,149 @@
+// Copyright © 2017 Michael Lihs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fake
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func (s *Server) registerWikiRoutes() {
+	s.handle("GET", "/projects/:project/wikis", s.withProject(s.listWikiPages))
+	s.handle("POST", "/projects/:project/wikis", s.withProject(s.createWikiPage))
+	s.handle("GET", "/projects/:project/wikis/:slug", s.withWikiPage(s.getWikiPage))
+	s.handle("PUT", "/projects/:project/wikis/:slug", s.withWikiPage(s.updateWikiPage))
+	s.handle("DELETE", "/projects/:project/wikis/:slug", s.withWikiPage(s.deleteWikiPage))
+}
+
+// AddWikiPage creates a wiki page in the project, a title like `dir/title` creates the page in a wiki directory
+func (s *Server) AddWikiPage(project *gitlab.Project, title string, content string, format string) *gitlab.Wiki {
+	page := &gitlab.Wiki{Content: content, Format: gitlab.WikiFormat(format)}
+	setWikiTitle(page, title)
+	s.wikis[project.ID] = append(s.wikis[project.ID], page)
+	return page
+}
+
+// WikiPages returns the wiki pages of the project ordered by slug
+func (s *Server) WikiPages(project *gitlab.Project) []*gitlab.Wiki {
+	pages := append([]*gitlab.Wiki{}, s.wikis[project.ID]...)
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].Slug < pages[j].Slug
+	})
+	return pages
+}
+
+// setWikiTitle sets the slug Gitlab derives from a title and the title without the wiki directory
+func setWikiTitle(page *gitlab.Wiki, title string) {
+	page.Slug = strings.Replace(title, " ", "-", -1)
+	page.Title = path.Base(title)
+}
+
+func (s *Server) findWikiPage(project *gitlab.Project, slug string) *gitlab.Wiki {
+	for _, page := range s.wikis[project.ID] {
+		if page.Slug == slug {
+			return page
+		}
+	}
+	return nil
+}
+
+type wikiPageHandler func(w http.ResponseWriter, r *http.Request, project *gitlab.Project, page *gitlab.Wiki)
+
+func (s *Server) withWikiPage(h wikiPageHandler) handler {
+	return s.withProject(func(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+		page := s.findWikiPage(project, params["slug"])
+		if page == nil {
+			writeError(w, http.StatusNotFound, "404 Wiki Page Not Found")
+			return
+		}
+		h(w, r, project, page)
+	})
+}
+
+// listWikiPages lists the wiki pages, their content is only included with `with_content`
+func (s *Server) listWikiPages(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	withContent := r.URL.Query().Get("with_content")
+	pages := []*gitlab.Wiki{}
+	for _, page := range s.WikiPages(project) {
+		listed := *page
+		if withContent != "true" && withContent != "1" {
+			listed.Content = ""
+		}
+		pages = append(pages, &listed)
+	}
+	writeJson(w, http.StatusOK, pages)
+}
+
+func (s *Server) getWikiPage(w http.ResponseWriter, r *http.Request, project *gitlab.Project, page *gitlab.Wiki) {
+	writeJson(w, http.StatusOK, page)
+}
+
+func (s *Server) createWikiPage(w http.ResponseWriter, r *http.Request, params map[string]string, project *gitlab.Project) {
+	body := readBody(r)
+	if missing(w, body, "title", "content") {
+		return
+	}
+	title, _ := stringValue(body, "title")
+	content, _ := stringValue(body, "content")
+	format, ok := stringValue(body, "format")
+	if !ok {
+		format = "markdown"
+	}
+	if s.findWikiPage(project, strings.Replace(title, " ", "-", -1)) != nil {
+		writeError(w, http.StatusBadRequest, "Duplicate page: A page with that title at that path already exists")
+		return
+	}
+	writeJson(w, http.StatusCreated, s.AddWikiPage(project, title, content, format))
+}
+
+// updateWikiPage updates the given attributes, a new title moves the page to the slug of the title
+func (s *Server) updateWikiPage(w http.ResponseWriter, r *http.Request, project *gitlab.Project, page *gitlab.Wiki) {
+	body := readBody(r)
+	if title, ok := stringValue(body, "title"); ok {
+		if other := s.findWikiPage(project, strings.Replace(title, " ", "-", -1)); other != nil && other != page {
+			writeError(w, http.StatusBadRequest, "Duplicate page: A page with that title at that path already exists")
+			return
+		}
+		setWikiTitle(page, title)
+	}
+	if content, ok := stringValue(body, "content"); ok {
+		page.Content = content
+	}
+	if format, ok := stringValue(body, "format"); ok {
+		page.Format = gitlab.WikiFormat(format)
+	}
+	writeJson(w, http.StatusOK, page)
+}
+
+func (s *Server) deleteWikiPage(w http.ResponseWriter, r *http.Request, project *gitlab.Project, page *gitlab.Wiki) {
+	var pages []*gitlab.Wiki
+	for _, p := range s.wikis[project.ID] {
+		if p != page {
+			pages = append(pages, p)
+		}
+	}
+	s.wikis[project.ID] = pages
+	w.WriteHeader(http.StatusNoContent)
+}